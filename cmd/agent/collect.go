@@ -0,0 +1,567 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/collector"
+	"cyber-inspector/internal/protocol"
+)
+
+// collectError 采集失败及对应的 HTTP 状态码
+type collectError struct {
+	status int
+	msg    string
+}
+
+func (e *collectError) Error() string { return e.msg }
+
+// collectorScript 单个采集器的 shell 片段，结果保存在 shell 变量 field 中并以同名字段输出
+type collectorScript struct {
+	field  string
+	script string
+	number bool // 以 --argjson 传给 jq
+}
+
+// collectorScripts 各采集器的 shell 片段，%v 为磁盘上报阈值
+var collectorScripts = map[string]collectorScript{
+	protocol.CollectorCPU: {field: "cpu_used", script: `cpu_used=$(top -bn1 | awk '/Cpu/{print 100-$8"%"}')`},
+	protocol.CollectorLoad: {field: "cpu_load",
+		script: `cpu_load=$(uptime | awk -F'load average:' '{print $2}' | awk '{print $1}' | sed 's/,//')`},
+	protocol.CollectorMemory: {field: "mem_used", script: `mem_used=$(free -m | awk 'NR==2{printf "%.2f%%",$3/$2*100}')`},
+	protocol.CollectorDisk: {field: "disk_alert", script: `disk_alert=$(df -P -x tmpfs -x devtmpfs -x overlay -x shm | \
+             awk '$1 !~ /loop/ && $6 !~ /^\/var\/lib\/docker/ && $6 !~ /^\/kubelet/ && $5+0>%v{
+                    gsub(/%%/,"",$5); print $1":"$6":"$5"%%"
+                  }' | paste -sd';')`},
+	protocol.CollectorRAID: {field: "raid_state", script: `raid_state=$(/opt/MegaRAID/MegaCli/MegaCli64 -LDInfo -Lall -aALL 2>/dev/null |
+             grep -E "State\s*:" | awk '{print $3}' | paste -sd, -)
+[ -z "$raid_state" ] && raid_state="null"`},
+	protocol.CollectorJournal: {field: "journal_err_1h", number: true,
+		script: `journal_err_1h=$(journalctl -p err --since "1 hour ago" 2>/dev/null | wc -l)`},
+	protocol.CollectorPing: {field: "ping_loss", script: `gateway=$(ip route | awk '$1=="default"{print $3}')
+ping_loss=$(ping -c 10 -W 1 "$gateway" 2>/dev/null | grep -o '[0-9]*%' | tr -d '%')`},
+}
+
+// buildScript 按启用的采集器生成采集脚本
+func buildScript(conf *agent.Config) string {
+	var script, args, fields strings.Builder
+	script.WriteString("hostname=$(hostname); timestamp=$(date -Iseconds)\n")
+	args.WriteString(`jq -c -n --arg hostname "$hostname" --arg timestamp "$timestamp"`)
+	fields.WriteString("hostname:$hostname,timestamp:$timestamp")
+
+	for _, name := range conf.Collectors {
+		c, ok := collectorScripts[name]
+		if !ok {
+			continue
+		}
+		if name == protocol.CollectorDisk {
+			script.WriteString(fmt.Sprintf(c.script, conf.Thresholds.DiskReport))
+		} else {
+			script.WriteString(c.script)
+		}
+		script.WriteString("\n")
+
+		if c.number {
+			fmt.Fprintf(&args, ` --argjson %s "${%s:-0}"`, c.field, c.field)
+		} else {
+			fmt.Fprintf(&args, ` --arg %s "$%s"`, c.field, c.field)
+		}
+		fmt.Fprintf(&fields, ",%s:$%s", c.field, c.field)
+	}
+
+	script.WriteString(args.String())
+	script.WriteString(" '{" + fields.String() + "}'\n")
+	return script.String()
+}
+
+// logTailer 记录日志来源的读取位置，日志规则只匹配上次采集之后的新日志
+var logTailer = collector.NewLogTailer()
+
+// auditor 安全基线审计，按 audit.interval 复用上次结果
+var auditor = collector.NewAuditor()
+
+// packageCollector 软件包采集，按 packages.interval 复用上次结果
+var packageCollector = collector.NewPackageCollector()
+
+// collect 采集系统指标并分析，返回当前版本协议的数据，按对端版本降级见 Report.ForVersion
+func collect() (*protocol.Report, *collectError) {
+	conf := currentConfig()
+
+	jsonRaw, err := exec.Command("bash", "-c", buildScript(conf)).Output()
+	if err != nil {
+		return nil, &collectError{http.StatusInternalServerError, "数据采集失败: " + err.Error()}
+	}
+	metrics := protocol.LegacyMetrics(jsonRaw)
+	if conf.Enabled(protocol.CollectorDisk) {
+		// disk_alert 仅保留给 v1 Master，v2 按挂载点上报
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		mounts, err := collector.Mounts(ctx)
+		cancel()
+		if err != nil {
+			return nil, &collectError{http.StatusInternalServerError, "磁盘采集失败: " + err.Error()}
+		}
+		metrics.SetMounts(mounts)
+		jsonRaw = withField(jsonRaw, "mounts", mounts)
+	}
+	if conf.Enabled(protocol.CollectorStorage) {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		disks, arrays, err := collector.Storage(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("SMART 采集失败: %v", err)
+		}
+		metrics.Disks, metrics.MDArrays = disks, arrays
+		jsonRaw = withField(jsonRaw, "storage", map[string]interface{}{"disks": disks, "md_arrays": arrays})
+	}
+	if conf.Enabled(protocol.CollectorService) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		units, failed, err := collector.Services(ctx, conf.Services)
+		cancel()
+		if err != nil {
+			log.Printf("systemd 单元采集失败: %v", err)
+		}
+		metrics.Units, metrics.FailedUnits = units, failed
+		jsonRaw = withField(jsonRaw, "services", map[string]interface{}{"units": units, "failed_units": failed})
+	}
+	if conf.Enabled(protocol.CollectorProcess) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		stats, err := collector.Processes(ctx, time.Second, conf.Processes.Top, conf.Watches())
+		cancel()
+		if err != nil {
+			log.Printf("进程采集失败: %v", err)
+		} else {
+			metrics.ProcessCount, metrics.ThreadCount, metrics.ZombieCount = stats.Count, stats.Threads, stats.Zombies
+			metrics.TopCPU, metrics.TopMemory, metrics.Watches = stats.TopCPU, stats.TopMemory, stats.Watches
+			jsonRaw = withField(jsonRaw, "processes", map[string]interface{}{
+				"count": stats.Count, "threads": stats.Threads, "zombies": stats.Zombies,
+				"top_cpu": stats.TopCPU, "top_memory": stats.TopMemory, "watches": stats.Watches,
+			})
+		}
+	}
+	if conf.Enabled(protocol.CollectorNetwork) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		stats, err := collector.Network(ctx, time.Second)
+		cancel()
+		if err != nil {
+			log.Printf("网络采集失败: %v", err)
+		} else {
+			metrics.Interfaces, metrics.TCPStates, metrics.TCPConnections = stats.Interfaces, stats.TCPStates, stats.TCPConnections
+			metrics.ListenPorts, metrics.ConntrackCount, metrics.ConntrackMax = stats.ListenPorts, stats.ConntrackCount, stats.ConntrackMax
+			metrics.ConntrackUsed = stats.ConntrackUsed()
+			jsonRaw = withField(jsonRaw, "network", map[string]interface{}{
+				"interfaces": stats.Interfaces, "tcp_states": stats.TCPStates, "tcp_connections": stats.TCPConnections,
+				"listen_ports": stats.ListenPorts, "conntrack_count": stats.ConntrackCount, "conntrack_max": stats.ConntrackMax,
+			})
+		}
+	}
+
+	if conf.Enabled(protocol.CollectorProbe) && len(conf.Probes) > 0 {
+		// 探测并发执行，各自按配置超时
+		probes := collector.Probes(context.Background(), conf.ProbeSpecs())
+		metrics.Probes = probes
+		jsonRaw = withField(jsonRaw, "probes", probes)
+	}
+
+	if conf.Enabled(protocol.CollectorCert) && len(conf.Certificates.Files)+len(conf.Certificates.Endpoints) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		certs := collector.Certificates(ctx, conf.Certificates.Files, conf.Certificates.Endpoints, time.Now())
+		cancel()
+		metrics.Certificates = certs
+		jsonRaw = withField(jsonRaw, "certificates", certs)
+	}
+
+	if conf.Enabled(protocol.CollectorLogs) && len(conf.Logs.Sources) > 0 && len(conf.Logs.Rules) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		matches, err := logTailer.Collect(ctx, conf.Logs.StateFile, conf.LogSources(), conf.LogRules(), conf.Logs.Samples)
+		cancel()
+		if err != nil {
+			log.Printf("日志采集失败: %v", err)
+		}
+		metrics.LogMatches = matches
+		jsonRaw = withField(jsonRaw, "log_matches", matches)
+	}
+
+	if conf.Enabled(protocol.CollectorAudit) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		audit := auditor.Run(ctx, conf.AuditOptions(), conf.Audit.Interval)
+		cancel()
+		metrics.Audit = audit
+		jsonRaw = withField(jsonRaw, "audit", audit)
+	}
+
+	if conf.Enabled(protocol.CollectorPackage) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		pkgs, err := packageCollector.Run(ctx, conf.Packages.Interval)
+		cancel()
+		if err != nil {
+			log.Printf("软件包采集失败: %v", err)
+		}
+		if pkgs != nil {
+			metrics.Packages = pkgs
+			// 全部软件包列表较长，LLM 只需要数量与待更新的软件包
+			jsonRaw = withField(jsonRaw, "packages", map[string]interface{}{
+				"manager": pkgs.Manager, "installed": pkgs.Installed, "updates": pkgs.Updates,
+				"security_updates": pkgs.Security, "pending": pendingPackages(pkgs),
+			})
+		}
+	}
+
+	var analysis json.RawMessage
+	if conf.LLM.Enabled {
+		content, cerr := analyzeWithLLM(conf, jsonRaw)
+		if cerr != nil {
+			return nil, cerr
+		}
+		analysis = content
+	} else {
+		analysis, _ = json.Marshal(analyzeLocally(conf.Thresholds, metrics))
+	}
+
+	now := time.Now()
+	return &protocol.Report{
+		ProtocolVersion: protocol.Version,
+		AgentVersion:    agent.Version,
+		Hostname:        conf.ReportHostname(),
+		ConfigVersion:   conf.RemoteVersion,
+		CollectedAt:     &now,
+		Metrics:         &metrics,
+		RawData:         jsonRaw,
+		Analysis:        analysis,
+	}, nil
+}
+
+// withField 向采集脚本输出的 JSON 对象添加字段，供 LLM 分析与原始数据查看
+func withField(raw []byte, key string, value interface{}) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return raw
+	}
+	m[key], _ = json.Marshal(value)
+	out, err := json.Marshal(m)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// collectReport 供结果缓存调用的采集函数
+func collectReport() (*protocol.Report, error) {
+	report, err := collect()
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// llmPrompt 按配置阈值生成系统提示词
+func llmPrompt(t agent.ThresholdConfig) string {
+	return fmt.Sprintf(`你是一名 Linux 运维专家，只返回 JSON。阈值规则：
+1. CPU 使用率 > %v%% 或 1-min load > 物理核数×%v → CRITICAL
+2. 内存使用率 > %v%% → CRITICAL
+3. 任一磁盘使用率 > %v%% 或 inode 使用率 > %v%% → CRITICAL
+4. RAID 状态 != "Optimal"、软 RAID 阵列降级或磁盘 SMART 检测未通过 → CRITICAL
+5. 1 小时内 journal 错误 > %d 条 → WARNING
+6. ping 网关丢包率 > %v%% → WARNING
+7. 磁盘温度 > %v℃、固态盘寿命消耗 > %v%% 或存在重映射/待重映射扇区 → WARNING
+8. services.units 中的服务未运行 → CRITICAL（指明服务名）；自动重启次数 > %d 或存在其它 failed 单元 → WARNING
+9. processes.watches 中进程数不在 [min, max] 范围 → CRITICAL（指明进程名）；僵尸进程 > %d → WARNING
+10. 连接跟踪表使用率（network.conntrack_count / conntrack_max）> %v%% 或网卡存在收发错误 → WARNING
+11. probes 中 success 为 false → CRITICAL（指明探测名称、目标与 error）
+12. certificates 中 days_left <= %d → CRITICAL，<= %d 或存在 error → WARNING（指明证书来源与到期时间）
+13. log_matches 中 count > 0 → 按规则的 severity 告警，并引用 samples 中的日志作为证据
+14. audit.findings 中 severity 为 CRITICAL 的问题 → CRITICAL；audit.score < %v → WARNING（列出主要问题）
+15. packages.security_updates > %d → WARNING（列出 pending 中 security 为 true 的软件包）
+CPU 或内存告警时，根据 processes.top_cpu / top_memory 指出占用最高的进程
+输出格式：{"alert":true/false,"level":"CRITICAL|WARNING|OK","summary":"结论","details":["原因"]},"Plan":"方案"`,
+		t.CPU, t.LoadFactor, t.Memory, t.Disk, t.Inodes, t.JournalErrors, t.PingLoss, t.DiskTemperature, t.DiskWear, t.ServiceRestarts, t.Zombies, t.Conntrack, t.CertCritical, t.CertWarning, t.Compliance, t.SecurityUpdates)
+}
+
+// analyzeWithLLM 调用 LLM 分析采集数据
+func analyzeWithLLM(conf *agent.Config, jsonRaw []byte) (json.RawMessage, *collectError) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"model":       conf.LLM.Model,
+		"temperature": conf.LLM.Temperature,
+		"messages": []map[string]string{
+			{"role": "system", "content": llmPrompt(conf.Thresholds)},
+			{"role": "user", "content": string(jsonRaw)},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.LLM.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, conf.LLM.APIURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, &collectError{http.StatusInternalServerError, "LLM 请求失败: " + err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, &collectError{http.StatusBadGateway, "LLM 请求失败: " + err.Error()}
+	}
+	defer resp.Body.Close()
+
+	var llmResp map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&llmResp); err != nil {
+		return nil, &collectError{http.StatusInternalServerError, "LLM 响应解析失败: " + err.Error()}
+	}
+
+	// 安全取出 LLM 返回的 content 并转成 json.RawMessage
+	choices, ok := llmResp["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return nil, &collectError{http.StatusInternalServerError, "LLM 返回格式异常"}
+	}
+	msg, ok := choices[0].(map[string]interface{})["message"].(map[string]interface{})
+	if !ok {
+		return nil, &collectError{http.StatusInternalServerError, "LLM 返回格式异常"}
+	}
+	contentStr, ok := msg["content"].(string)
+	if !ok {
+		return nil, &collectError{http.StatusInternalServerError, "LLM 返回格式异常"}
+	}
+	return json.RawMessage(contentStr), nil
+}
+
+// analyzeStorage 分析软 RAID 与磁盘 SMART 状态
+func analyzeStorage(t agent.ThresholdConfig, m protocol.Metrics, critical, warning []string) ([]string, []string) {
+	for _, a := range m.MDArrays {
+		switch {
+		case a.State == "inactive":
+			critical = append(critical, fmt.Sprintf("软 RAID %s 未激活", a.Name))
+		case a.State == "degraded" || len(a.Failed) > 0:
+			msg := fmt.Sprintf("软 RAID %s 降级（%d/%d）", a.Name, a.Active, a.Devices)
+			if len(a.Failed) > 0 {
+				msg += "，故障成员: " + strings.Join(a.Failed, ",")
+			}
+			if a.Sync != "" {
+				msg += "，" + a.Sync
+			}
+			critical = append(critical, msg)
+		}
+	}
+
+	for _, d := range m.Disks {
+		if !d.Passed {
+			critical = append(critical, fmt.Sprintf("磁盘 %s（%s）SMART 检测未通过", d.Device, d.Model))
+		}
+		if d.ReallocatedSectors > 0 || d.PendingSectors > 0 || d.MediaErrors > 0 {
+			warning = append(warning, fmt.Sprintf("磁盘 %s 重映射扇区 %d、待重映射扇区 %d、介质错误 %d",
+				d.Device, d.ReallocatedSectors, d.PendingSectors, d.MediaErrors))
+		}
+		if float64(d.Temperature) > t.DiskTemperature {
+			warning = append(warning, fmt.Sprintf("磁盘 %s 温度 %d℃ 超过 %v℃", d.Device, d.Temperature, t.DiskTemperature))
+		}
+		if d.Wear > t.DiskWear {
+			warning = append(warning, fmt.Sprintf("磁盘 %s 寿命消耗 %.0f%% 超过 %v%%", d.Device, d.Wear, t.DiskWear))
+		}
+	}
+	return critical, warning
+}
+
+// analyzeServices 分析关注的 systemd 单元与 failed 单元
+func analyzeServices(t agent.ThresholdConfig, m protocol.Metrics, critical, warning []string) ([]string, []string) {
+	watched := make(map[string]bool, len(m.Units))
+	for _, u := range m.Units {
+		watched[u.Name] = true
+		switch {
+		case u.LoadState == "not-found":
+			critical = append(critical, fmt.Sprintf("服务 %s 不存在", u.Name))
+		case !u.Running():
+			critical = append(critical, fmt.Sprintf("服务 %s 未运行（%s/%s）", u.Name, u.ActiveState, u.SubState))
+		case u.Restarts > t.ServiceRestarts:
+			warning = append(warning, fmt.Sprintf("服务 %s 已自动重启 %d 次", u.Name, u.Restarts))
+		}
+	}
+
+	var others []string
+	for _, name := range m.FailedUnits {
+		if !watched[strings.TrimSuffix(name, ".service")] {
+			others = append(others, name)
+		}
+	}
+	if len(others) > 0 {
+		warning = append(warning, "存在 failed 单元: "+strings.Join(others, ", "))
+	}
+	return critical, warning
+}
+
+// analyzeCertificates 分析证书剩余天数
+func analyzeCertificates(t agent.ThresholdConfig, m protocol.Metrics, critical, warning []string) ([]string, []string) {
+	for _, c := range m.Certificates {
+		switch {
+		case c.Error != "":
+			warning = append(warning, fmt.Sprintf("证书 %s 检查失败: %s", c.Source, c.Error))
+		case c.DaysLeft < 0:
+			critical = append(critical, fmt.Sprintf("证书 %s 已于 %s 过期", c.Source, c.NotAfter.Format("2006-01-02")))
+		case t.CertCritical > 0 && c.DaysLeft <= t.CertCritical:
+			critical = append(critical, fmt.Sprintf("证书 %s 将于 %s 过期，剩余 %d 天", c.Source, c.NotAfter.Format("2006-01-02"), c.DaysLeft))
+		case t.CertWarning > 0 && c.DaysLeft <= t.CertWarning:
+			warning = append(warning, fmt.Sprintf("证书 %s 将于 %s 过期，剩余 %d 天", c.Source, c.NotAfter.Format("2006-01-02"), c.DaysLeft))
+		}
+	}
+	return critical, warning
+}
+
+// analyzeAudit 分析安全基线审计结果，严重问题逐条列出，其余问题计入合规得分
+func analyzeAudit(t agent.ThresholdConfig, m protocol.Metrics, critical, warning []string) ([]string, []string) {
+	if m.Audit == nil {
+		return critical, warning
+	}
+	for _, f := range m.Audit.Findings {
+		if f.Severity == "CRITICAL" {
+			critical = append(critical, "安全基线: "+f.Title)
+		}
+	}
+	if m.Audit.Checks > 0 && m.Audit.Score < t.Compliance {
+		warning = append(warning, fmt.Sprintf("安全基线合规得分 %.0f 低于 %v（%d/%d 项通过，%d 个问题）",
+			m.Audit.Score, t.Compliance, m.Audit.Passed, m.Audit.Checks, len(m.Audit.Findings)))
+	}
+	return critical, warning
+}
+
+// analyzePackages 有安全更新的软件包数超过阈值时告警，列出前 10 个软件包
+func analyzePackages(t agent.ThresholdConfig, m protocol.Metrics, warning []string) []string {
+	if m.Packages == nil || m.Packages.Security <= t.SecurityUpdates {
+		return warning
+	}
+	var names []string
+	for _, p := range pendingPackages(m.Packages) {
+		if p.Security && len(names) < 10 {
+			names = append(names, p.Name)
+		}
+	}
+	return append(warning, fmt.Sprintf("%d 个软件包有安全更新: %s", m.Packages.Security, strings.Join(names, ", ")))
+}
+
+// pendingPackages 有可用更新的软件包
+func pendingPackages(pkgs *protocol.Packages) []protocol.Package {
+	var pending []protocol.Package
+	for _, p := range pkgs.List {
+		if p.Update != "" {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// topProcess 占用最高的进程说明，没有进程数据时为空
+func topProcess(procs []protocol.Process, memory bool) string {
+	if len(procs) == 0 {
+		return ""
+	}
+	p := procs[0]
+	if memory {
+		return fmt.Sprintf("，占用最高: %s(%d) %.0fMB", p.Name, p.PID, float64(p.RSS)/1024/1024)
+	}
+	return fmt.Sprintf("，占用最高: %s(%d) %.1f%%", p.Name, p.PID, p.CPU)
+}
+
+// watchRange 进程守护期望的实例数范围
+func watchRange(w protocol.ProcessWatch) string {
+	switch {
+	case w.Max == 0:
+		return fmt.Sprintf("至少 %d 个", w.Min)
+	case w.Min == w.Max:
+		return fmt.Sprintf("%d 个", w.Min)
+	default:
+		return fmt.Sprintf("%d-%d 个", w.Min, w.Max)
+	}
+}
+
+// analyzeLocally 未启用 LLM 时按阈值在本地分析
+func analyzeLocally(t agent.ThresholdConfig, m protocol.Metrics) protocol.Analysis {
+	var critical, warning []string
+	if m.CPUUsed > t.CPU {
+		critical = append(critical, fmt.Sprintf("CPU 使用率 %.1f%% 超过 %v%%", m.CPUUsed, t.CPU)+topProcess(m.TopCPU, false))
+	}
+	if limit := float64(runtime.NumCPU()) * t.LoadFactor; m.LoadAvg > limit {
+		critical = append(critical, fmt.Sprintf("1 分钟负载 %.2f 超过 %.2f", m.LoadAvg, limit))
+	}
+	if m.MemoryUsed > t.Memory {
+		critical = append(critical, fmt.Sprintf("内存使用率 %.1f%% 超过 %v%%", m.MemoryUsed, t.Memory)+topProcess(m.TopMemory, true))
+	}
+	for _, mount := range m.Mounts {
+		if mount.UsedPercent > t.Disk {
+			critical = append(critical, fmt.Sprintf("磁盘 %s 使用率 %.1f%% 超过 %v%%", mount.Path, mount.UsedPercent, t.Disk))
+		}
+		if mount.InodesPercent > t.Inodes {
+			critical = append(critical, fmt.Sprintf("磁盘 %s inode 使用率 %.1f%% 超过 %v%%", mount.Path, mount.InodesPercent, t.Inodes))
+		}
+	}
+	if len(m.Mounts) == 0 && m.DiskUsed > t.Disk {
+		critical = append(critical, fmt.Sprintf("磁盘使用率 %.1f%% 超过 %v%%", m.DiskUsed, t.Disk))
+	}
+	for _, state := range strings.Split(m.RAIDState, ",") {
+		if state != "" && !strings.EqualFold(state, "Optimal") {
+			critical = append(critical, "RAID 状态异常: "+m.RAIDState)
+			break
+		}
+	}
+	critical, warning = analyzeStorage(t, m, critical, warning)
+	critical, warning = analyzeServices(t, m, critical, warning)
+	for _, w := range m.Watches {
+		if !w.OK() {
+			critical = append(critical, fmt.Sprintf("进程 %s 实例数 %d，期望 %s", w.Name, w.Count, watchRange(w)))
+		}
+	}
+	if m.ZombieCount > t.Zombies {
+		warning = append(warning, fmt.Sprintf("僵尸进程 %d 个", m.ZombieCount))
+	}
+	for _, p := range m.Probes {
+		if !p.Success {
+			critical = append(critical, fmt.Sprintf("探测 %s（%s %s）失败: %s", p.Name, p.Type, p.Target, p.Error))
+		}
+	}
+	critical, warning = analyzeCertificates(t, m, critical, warning)
+	for _, lm := range m.LogMatches {
+		if lm.Count == 0 {
+			continue
+		}
+		msg := fmt.Sprintf("日志规则 %s 匹配 %d 行", lm.Rule, lm.Count)
+		if len(lm.Samples) > 0 {
+			msg += ": " + lm.Samples[len(lm.Samples)-1]
+		}
+		if lm.Severity == "CRITICAL" {
+			critical = append(critical, msg)
+		} else {
+			warning = append(warning, msg)
+		}
+	}
+	critical, warning = analyzeAudit(t, m, critical, warning)
+	warning = analyzePackages(t, m, warning)
+	if m.ConntrackUsed > t.Conntrack {
+		warning = append(warning, fmt.Sprintf("连接跟踪表使用率 %.1f%% 超过 %v%%（%d/%d）", m.ConntrackUsed, t.Conntrack, m.ConntrackCount, m.ConntrackMax))
+	}
+	for _, i := range m.Interfaces {
+		if i.RxErrors+i.TxErrors > 0 {
+			warning = append(warning, fmt.Sprintf("网卡 %s 收发错误 %d/%d", i.Name, i.RxErrors, i.TxErrors))
+		}
+	}
+	if m.JournalErr1h > t.JournalErrors {
+		warning = append(warning, fmt.Sprintf("1 小时内错误日志 %d 条", m.JournalErr1h))
+	}
+	if m.PingLoss > t.PingLoss {
+		warning = append(warning, fmt.Sprintf("网关丢包率 %.1f%%", m.PingLoss))
+	}
+
+	a := protocol.Analysis{Level: "OK", Summary: "各项指标正常", Details: append(critical, warning...)}
+	switch {
+	case len(critical) > 0:
+		a.Alert, a.Level, a.Summary = true, "CRITICAL", critical[0]
+	case len(warning) > 0:
+		a.Alert, a.Level, a.Summary = true, "WARNING", warning[0]
+	}
+	if a.Details == nil {
+		a.Details = []string{}
+	}
+	return a
+}
@@ -1,114 +1,388 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
+	"log"
+	"net"
 	"net/http"
-	"os/exec"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/protocol"
 	"github.com/gin-gonic/gin"
 )
 
-type AgentResponse struct {
-	Hostname string          `json:"hostname"`
-	RawData  json.RawMessage `json:"raw_data"`
-	Analysis json.RawMessage `json:"analysis"`
+var (
+	configFile  = flag.String("config", "configs/agent.yaml", "配置文件路径，不存在时使用默认配置")
+	printConfig = flag.Bool("print-config", false, "打印最终生效的配置（隐藏令牌）后退出")
+)
+
+// flagKeys 命令行参数与配置键的对应关系，只有显式指定的参数会覆盖配置文件与环境变量
+var flagKeys = map[string]string{
+	"listen":        "listen",
+	"hostname":      "hostname",
+	"url":           "url",
+	"mode":          "mode",
+	"stream":        "stream",
+	"master":        "master.url",
+	"agent-id":      "master.agent_id",
+	"token":         "master.token",
+	"enroll-token":  "master.enroll_token",
+	"state":         "master.state",
+	"tls":           "tls.enabled",
+	"cert-dir":      "tls.cert_dir",
+	"push-interval": "push.interval",
+	"spool-dir":     "push.spool_dir",
+}
+
+func init() {
+	flag.String("listen", "", "监听地址（默认 :8083）")
+	flag.String("hostname", "", "上报的主机名，为空时使用系统主机名")
+	flag.String("url", "", "Agent 对外地址，自注册时上报，为空时由 Master 推导")
+	flag.String("mode", "", "巡检模式：pull 由 Master 拉取（默认），push 主动推送到 Master")
+	flag.Bool("stream", false, "与 Master 建立长连接，接收命令并通过长连接上报巡检结果")
+	flag.String("master", "", "Master 地址（环境变量 CYBER_MASTER_URL）")
+	flag.Uint64("agent-id", 0, "Master 中的节点ID")
+	flag.String("token", "", "Master 签发的 Agent 令牌（环境变量 CYBER_AGENT_TOKEN）")
+	flag.String("enroll-token", "", "注册令牌，未配置节点令牌时用于向 Master 自注册（环境变量 CYBER_ENROLL_TOKEN）")
+	flag.String("state", "", "自注册凭据保存路径（默认 data/agent.json）")
+	flag.Bool("tls", false, "启用双向 TLS，证书由 Master 内置 CA 签发")
+	flag.String("cert-dir", "", "证书存放目录（默认 data/certs）")
+	flag.Duration("push-interval", 0, "推送模式的采集间隔（默认 5m）")
+	flag.String("spool-dir", "", "推送失败时的本地缓存目录（默认 data/spool）")
+}
+
+// flagOverrides 收集显式指定的命令行参数
+func flagOverrides() map[string]interface{} {
+	overrides := make(map[string]interface{})
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			overrides[key] = f.Value.(flag.Getter).Get()
+		}
+	})
+	return overrides
+}
+
+// conf 当前生效的配置，reload_config 时整体替换
+var conf atomic.Pointer[agent.Config]
+
+// currentConfig 当前生效的配置
+func currentConfig() *agent.Config {
+	return conf.Load()
+}
+
+// remoteConfig 当前生效的远程配置，未启用或 Master 未分配配置时为 nil
+var remoteConfig atomic.Pointer[protocol.RemoteConfig]
+
+// remoteEnabled 是否从 Master 获取远程配置，需要节点ID与令牌签名
+func remoteEnabled(cfg *agent.Config) bool {
+	return cfg.Remote.Enabled && cfg.Master.URL != "" && cfg.Master.AgentID != 0 && cfg.Master.Token != ""
+}
+
+// fetchRemoteConfig 从 Master 获取远程配置
+func fetchRemoteConfig(cfg *agent.Config) (*protocol.RemoteConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return agent.FetchRemoteConfig(ctx, cfg.Master.URL, cfg.Master.AgentID, cfg.Master.Token)
+}
+
+// reloadConfig 重新加载配置文件，启用远程配置时先从 Master 获取最新配置
+func reloadConfig() error {
+	r := remoteConfig.Load()
+	if cfg := currentConfig(); remoteEnabled(cfg) {
+		fetched, err := fetchRemoteConfig(cfg)
+		if err != nil {
+			log.Printf("获取远程配置失败，沿用当前远程配置: %v", err)
+		} else {
+			r = fetched
+		}
+	}
+	return applyConfig(r)
+}
+
+// applyConfig 按配置文件与远程配置生成新配置并生效，校验失败时保留当前配置
+// 监听地址、巡检模式、Master 连接等需重启后生效，保持不变
+func applyConfig(r *protocol.RemoteConfig) error {
+	old := currentConfig()
+	next, _, err := agent.LoadConfig(*configFile, r, flagOverrides())
+	if err != nil {
+		return err
+	}
+
+	next.Listen, next.URL, next.Mode, next.Stream = old.Listen, old.URL, old.Mode, old.Stream
+	next.Master, next.TLS, next.Push, next.RateLimit, next.Remote = old.Master, old.TLS, old.Push, old.RateLimit, old.Remote
+	conf.Store(next)
+	remoteConfig.Store(r)
+	reports.SetTTL(next.Cache.TTL)
+
+	if r != nil {
+		if err := agent.SaveRemoteConfig(next.Remote.CacheFile, r); err != nil {
+			log.Printf("缓存远程配置失败: %v", err)
+		}
+	}
+	log.Printf("配置已重新加载: %s，远程配置版本: %s，采集器: %v", *configFile, next.RemoteVersion, next.Collectors)
+	return nil
 }
 
+// pollRemoteConfig 定期从 Master 获取远程配置，版本变化时生效
+func pollRemoteConfig(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cfg := currentConfig()
+		r, err := fetchRemoteConfig(cfg)
+		if err != nil {
+			log.Printf("获取远程配置失败: %v", err)
+			continue
+		}
+		if r.Version == cfg.RemoteVersion {
+			continue
+		}
+		if err := applyConfig(r); err != nil {
+			log.Printf("远程配置 %s 无效，保留当前配置: %v", r.Version, err)
+		}
+	}
+}
+
+// initRemoteConfig 启动时获取远程配置，Master 不可达时使用本地缓存
+func initRemoteConfig(cfg *agent.Config) {
+	r, err := fetchRemoteConfig(cfg)
+	if err != nil {
+		log.Printf("获取远程配置失败，尝试使用本地缓存: %v", err)
+		if r, err = agent.LoadRemoteConfig(cfg.Remote.CacheFile); err != nil {
+			return
+		}
+	}
+	if err := applyConfig(r); err != nil {
+		log.Printf("远程配置 %s 无效，使用本地配置: %v", r.Version, err)
+	}
+}
+
+// reports 采集结果缓存，/inspect、推送与长连接共用
+var reports = agent.NewReportCache(0, collectReport)
+
 func inspectHandler(c *gin.Context) {
-	cmd := exec.Command("bash", "-c", `
-hostname=$(hostname); timestamp=$(date -Iseconds)
-cpu_used=$(top -bn1 | awk '/Cpu/{print 100-$8"%"}')
-cpu_load=$(uptime | awk -F'load average:' '{print $2}' | awk '{print $1}' | sed 's/,//')
-mem_used=$(free -m | awk 'NR==2{printf "%.2f%%",$3/$2*100}')
-disk_alert=$(df -P -x tmpfs -x devtmpfs -x overlay -x shm | \
-             awk '$1 !~ /loop/ && $6 !~ /^\/var\/lib\/docker/ && $6 !~ /^\/kubelet/ && $5+0>80{
-                    gsub(/%/,"",$5); print $1":"$6":"$5"%"
-                  }' | paste -sd';')
-raid_state=$(/opt/MegaRAID/MegaCli/MegaCli64 -LDInfo -Lall -aALL 2>/dev/null |
-             grep -E "State\s*:" | awk '{print $3}' | paste -sd, -)
-[ -z "$raid_state" ] && raid_state="null"
-err_cnt=$(journalctl -p err --since "1 hour ago" 2>/dev/null | wc -l)
-gateway=$(ip route | awk '$1=="default"{print $3}')
-loss=$(ping -c 10 -W 1 "$gateway" 2>/dev/null | grep -o '[0-9]*%' | tr -d '%')
-jq -c -n \
-  --arg hn "$hostname" \
-  --arg ts "$timestamp" \
-  --arg cpu "$cpu_used" \
-  --arg load "$cpu_load" \
-  --arg mem "$mem_used" \
-  --arg disk "$disk_alert" \
-  --arg raid "$raid_state" \
-  --argjson err "$err_cnt" \
-  --arg loss "$loss" \
-  '{hostname:$hn,timestamp:$ts,cpu_used:$cpu,cpu_load:$load,mem_used:$mem,disk_alert:$disk,raid_state:$raid,journal_err_1h:$err,ping_loss:$loss}'
-`)
-
-	jsonRaw, err := cmd.Output()
+	// 调用方可通过 Cache-Control: no-cache 要求重新采集
+	fresh := c.GetHeader("Cache-Control") == "no-cache"
+	report, cached, err := reports.Get(fresh)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据采集失败: " + err.Error()})
+		status := http.StatusInternalServerError
+		if cerr, ok := err.(*collectError); ok {
+			status = cerr.status
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	reqBody, _ := json.Marshal(map[string]interface{}{
-		"model":       "sinollm",
-		"temperature": 0,
-		"messages": []map[string]string{
-			{"role": "system", "content": `你是一名 Linux 运维专家，只返回 JSON。阈值规则：
-1. CPU 使用率 > 85% 或 1-min load > 物理核数×1.5 → CRITICAL 
-2. 内存使用率 > 90% → CRITICAL 
-3. 任一磁盘使用率 > 90% → CRITICAL 
-4. RAID 状态 != "Optimal" → CRITICAL 
-5. 1 小时内 journal 错误 > 10 条 → WARNING 
-6. ping 网关丢包率 > 5% → WARNING 
-输出格式：{"alert":true/false,"level":"CRITICAL|WARNING|OK","summary":"结论","details":["原因"]},"Plan":"方案"`},
-			{"role": "user", "content": string(jsonRaw)},
-		},
+	c.Header("X-Cache", "MISS")
+	if cached {
+		c.Header("X-Cache", "HIT")
+	}
+	c.Header("Age", strconv.Itoa(int(time.Since(*report.CollectedAt).Seconds())))
+
+	// 旧版 Master 不带版本请求头，按 v1 应答
+	requested, _ := strconv.Atoi(c.GetHeader(protocol.HeaderVersion))
+	c.JSON(http.StatusOK, report.ForVersion(protocol.Negotiate(requested)))
+}
+
+// infoHandler 返回 Agent 支持的协议版本、采集器与能力
+func infoHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, agentInfo())
+}
+
+// agentInfo 当前 Agent 的信息
+func agentInfo() protocol.Info {
+	cfg := currentConfig()
+	collectors := append([]string{}, cfg.Collectors...)
+	if cfg.LLM.Enabled {
+		collectors = append(collectors, protocol.CollectorLLM)
+	}
+	return protocol.Info{
+		ProtocolVersion:    protocol.Version,
+		MinProtocolVersion: protocol.MinVersion,
+		AgentVersion:       agent.Version,
+		Hostname:           cfg.ReportHostname(),
+		Collectors:         collectors,
+		Capabilities:       capabilities(cfg),
+		ConfigVersion:      cfg.RemoteVersion,
+	}
+}
+
+// capabilities 按配置返回支持的能力
+func capabilities(cfg *agent.Config) []string {
+	caps := []string{protocol.CapabilityInspect}
+	if cfg.Mode == "push" {
+		caps = append(caps, protocol.CapabilityPush)
+	}
+	if cfg.Stream {
+		caps = append(caps, protocol.CapabilityStream)
+	}
+	if cfg.TLS.Enabled {
+		caps = append(caps, protocol.CapabilityMTLS)
+	}
+	return caps
+}
+
+// startStream 建立与 Master 的长连接
+func startStream(ctx context.Context, cfg *agent.Config) *agent.StreamClient {
+	stream := agent.NewStreamClient(cfg.Master.URL, cfg.Master.AgentID, cfg.Master.Token)
+	stream.SetInfo(agentInfo)
+	stream.Handle(protocol.CommandInspect, func() error {
+		report, _, err := reports.Get(false)
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(report)
+		return stream.SendReport(data)
 	})
+	stream.Handle(protocol.CommandReloadConfig, reloadConfig)
+	go stream.Run(ctx)
+	return stream
+}
 
-	resp, err := http.Post("http://172.16.10.226:18000/v1/chat/completions",
-		"application/json", bytes.NewReader(reqBody))
+// enroll 自注册：优先使用本地保存的凭据，否则使用注册令牌向 Master 注册
+func enroll(cfg *agent.Config) (*agent.Credentials, error) {
+	if creds, err := agent.LoadCredentials(cfg.Master.State); err == nil {
+		return creds, nil
+	}
+
+	reg := agent.LocalRegistration(cfg.Master.EnrollToken, listenPort(cfg.Listen), capabilities(cfg))
+	reg.URL = cfg.URL
+	reg.Mode = cfg.Mode
+	if cfg.Hostname != "" {
+		reg.Hostname = cfg.Hostname
+	}
+
+	creds, err := agent.Enroll(cfg.Master.URL, reg)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "LLM 请求失败: " + err.Error()})
-		return
+		return nil, err
+	}
+	if err := agent.SaveCredentials(cfg.Master.State, creds); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return creds, nil
+}
 
-	var llmResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&llmResp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "LLM 响应解析失败: " + err.Error()})
-		return
+// listenPort 监听地址中的端口
+func listenPort(listen string) int {
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return 0
 	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
 
-	// 主机名写死
-	hostname := "本地机器-226"
+func main() {
+	flag.Parse()
 
-	// 安全取出 LLM 返回的 content 并转成 json.RawMessage
-	choices, ok := llmResp["choices"].([]interface{})
-	if !ok || len(choices) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "LLM 返回格式异常"})
-		return
+	cfg, v, err := agent.LoadConfig(*configFile, nil, flagOverrides())
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
 	}
-	msg, ok := choices[0].(map[string]interface{})["message"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "LLM 返回格式异常"})
+	if *printConfig {
+		// 包含本地缓存的远程配置
+		if r, err := agent.LoadRemoteConfig(cfg.Remote.CacheFile); err == nil {
+			if _, rv, err := agent.LoadConfig(*configFile, r, flagOverrides()); err == nil {
+				v = rv
+			}
+		}
+		out, err := agent.PrintConfig(v)
+		if err != nil {
+			log.Fatalf("输出配置失败: %v", err)
+		}
+		_, _ = os.Stdout.Write(out)
 		return
 	}
-	contentStr, ok := msg["content"].(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "LLM 返回格式异常"})
+
+	if cfg.Master.Token == "" && cfg.Master.EnrollToken != "" {
+		creds, err := enroll(cfg)
+		if err != nil {
+			log.Fatalf("自注册失败: %v", err)
+		}
+		cfg.Master.Token, cfg.Master.AgentID = creds.Token, creds.AgentID
+		log.Printf("已注册为节点 %d，状态: %s", creds.AgentID, creds.Status)
+	}
+	conf.Store(cfg)
+	reports.SetTTL(cfg.Cache.TTL)
+	if remoteEnabled(cfg) {
+		initRemoteConfig(cfg)
+		go pollRemoteConfig(context.Background(), cfg.Remote.Interval)
+	}
+
+	if cfg.Mode == "push" {
+		if cfg.Master.AgentID == 0 || cfg.Master.Token == "" {
+			log.Fatal("推送模式需要配置节点ID和令牌（或注册令牌）")
+		}
+		log.Printf("推送模式：每 %v 向 %s 推送巡检结果", cfg.Push.Interval, cfg.Master.URL)
+		pusher := agent.NewPusher(cfg.Master.URL, cfg.Master.AgentID, cfg.Master.Token, cfg.Push.SpoolDir, func() (*protocol.Report, error) {
+			report, _, err := reports.Get(false)
+			return report, err
+		})
+		if cfg.Stream {
+			pusher.UseStream(startStream(context.Background(), cfg))
+		}
+		pusher.Run(context.Background(), cfg.Push.Interval)
 		return
 	}
 
-	c.JSON(http.StatusOK, AgentResponse{
-		Hostname: hostname,
-		RawData:  jsonRaw,
-		Analysis: json.RawMessage(contentStr),
-	})
-}
+	if cfg.Stream {
+		if cfg.Master.AgentID == 0 || cfg.Master.Token == "" {
+			log.Fatal("长连接需要配置节点ID和令牌（或注册令牌）")
+		}
+		startStream(context.Background(), cfg)
+	}
 
-func main() {
 	r := gin.Default()
-	r.GET("/inspect", inspectHandler)
-	_ = r.Run(":8083") // 监听 0.0.0.0:8080
+	inspect := r.Group("")
+	if cfg.Master.Token != "" {
+		inspect.Use(agent.NewVerifier(cfg.Master.Token, agent.DefaultClockSkew).Middleware())
+	} else {
+		log.Println("【警告】未配置 Agent 令牌，/inspect 接口未启用认证")
+	}
+	// 限流放在签名校验之后，未通过认证的请求不消耗调用方的配额
+	if cfg.RateLimit.PerMinute > 0 {
+		inspect.Use(agent.NewRateLimiter(cfg.RateLimit.PerMinute, cfg.RateLimit.Burst).Middleware())
+	}
+	inspect.GET("/inspect", inspectHandler)
+	inspect.GET("/info", infoHandler)
+
+	if !cfg.TLS.Enabled {
+		_ = r.Run(cfg.Listen)
+		return
+	}
+
+	certs := agent.NewCertManager(cfg.TLS.CertDir, cfg.Master.URL, cfg.Master.AgentID, cfg.Master.Token)
+	// 自注册的节点在审批通过前无法申请证书，定期重试
+	for {
+		err := certs.Ensure()
+		if err == nil {
+			break
+		}
+		log.Printf("获取证书失败，30 秒后重试: %v", err)
+		time.Sleep(30 * time.Second)
+	}
+	go certs.Run(context.Background(), time.Hour)
+
+	srv := &http.Server{
+		Addr:      cfg.Listen,
+		Handler:   r,
+		TLSConfig: certs.ServerTLSConfig(),
+	}
+	log.Printf("启动 HTTPS 服务器（双向 TLS）: %s", srv.Addr)
+	if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("HTTPS 服务器启动失败: %v", err)
+	}
 }
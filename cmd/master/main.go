@@ -40,6 +40,14 @@ func main() {
 		log.Fatalf("加载配置文件失败: %v", err)
 	}
 
+	// 数据库迁移命令：master migrate up|down|status
+	if flag.Arg(0) == "migrate" {
+		if err := bootstrap.Migrate(flag.Args()[1:]); err != nil {
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+		return
+	}
+
 	// 2. 配置已就绪，再打印横幅
 	printBanner()
 
@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff 带随机抖动的指数退避
+type Backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	attempt int
+}
+
+// Next 返回下一次重试前的等待时间，在 [d/2, d] 区间内随机，d 每次翻倍且不超过 Max
+func (b *Backoff) Next() time.Duration {
+	d := b.Min << uint(b.attempt)
+	if d <= 0 || d > b.Max {
+		d = b.Max
+	} else {
+		b.attempt++
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Reset 重置退避状态
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
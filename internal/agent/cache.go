@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// ReportCache 缓存最近一次采集结果，TTL 内直接返回；并发请求合并为一次采集
+type ReportCache struct {
+	collect func() (*protocol.Report, error)
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	report   *protocol.Report
+	inflight *collectCall
+}
+
+// collectCall 进行中的采集，等待者共享结果
+type collectCall struct {
+	done   chan struct{}
+	report *protocol.Report
+	err    error
+}
+
+// NewReportCache 创建结果缓存，ttl 为 0 时不缓存，仅合并并发采集
+func NewReportCache(ttl time.Duration, collect func() (*protocol.Report, error)) *ReportCache {
+	return &ReportCache{ttl: ttl, collect: collect}
+}
+
+// SetTTL 修改缓存有效期
+func (c *ReportCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	c.ttl = ttl
+	c.mu.Unlock()
+}
+
+// Get 获取采集结果，fresh 为 true 时忽略缓存；cached 表示结果来自缓存或其它请求发起的采集
+// 返回的 Report 为共享数据，调用方不可修改
+func (c *ReportCache) Get(fresh bool) (report *protocol.Report, cached bool, err error) {
+	c.mu.Lock()
+	if !fresh && c.report != nil && time.Since(*c.report.CollectedAt) < c.ttl {
+		report = c.report
+		c.mu.Unlock()
+		return report, true, nil
+	}
+
+	// 已有采集在进行，等待其结果
+	if call := c.inflight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.report, true, call.err
+	}
+
+	call := &collectCall{done: make(chan struct{})}
+	c.inflight = call
+	c.mu.Unlock()
+
+	call.report, call.err = c.collect()
+	if call.err == nil && call.report.CollectedAt == nil {
+		now := time.Now()
+		call.report.CollectedAt = &now
+	}
+
+	c.mu.Lock()
+	c.inflight = nil
+	if call.err == nil {
+		c.report = call.report
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.report, false, call.err
+}
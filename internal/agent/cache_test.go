@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestReportCacheSingleFlight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	cache := NewReportCache(time.Minute, func() (*protocol.Report, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &protocol.Report{Hostname: "node-1", RawData: json.RawMessage(`{}`)}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, _, err := cache.Get(false); err != nil || r.CollectedAt == nil {
+				t.Errorf("采集失败: %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("并发请求应合并为一次采集，实际 %d 次", calls)
+	}
+
+	// TTL 内命中缓存，fresh 强制重新采集
+	if _, cached, _ := cache.Get(false); !cached || calls != 1 {
+		t.Fatalf("TTL 内应命中缓存: cached=%v calls=%d", cached, calls)
+	}
+	if _, cached, _ := cache.Get(true); cached || calls != 2 {
+		t.Fatalf("fresh 应重新采集: cached=%v calls=%d", cached, calls)
+	}
+
+	cache.SetTTL(0)
+	if _, cached, _ := cache.Get(false); cached || calls != 3 {
+		t.Fatalf("TTL 为 0 时不应缓存: cached=%v calls=%d", cached, calls)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(60, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("10.0.0.1", now); !ok {
+			t.Fatalf("突发范围内的第 %d 个请求应放行", i+1)
+		}
+	}
+	ok, wait := l.Allow("10.0.0.1", now)
+	if ok || wait <= 0 || wait > time.Second {
+		t.Fatalf("超出突发应限流并等待约 1 秒: ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := l.Allow("10.0.0.2", now); !ok {
+		t.Fatal("不同调用方应独立计数")
+	}
+	if ok, _ := l.Allow("10.0.0.1", now.Add(time.Second)); !ok {
+		t.Fatal("补充令牌后应放行")
+	}
+}
@@ -1,65 +1,188 @@
 package agent
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
 )
 
 // Client HTTP客户端
 type Client struct {
-	http *http.Client
+	http   *http.Client
+	secret string // 解密节点签名密钥的 Master 密钥
 }
 
-// NewClient 创建客户端
+// NewClient 创建客户端，请求超时由调用方的 context 控制
 func NewClient() *Client {
+	return &Client{
+		http: &http.Client{},
+	}
+}
+
+// NewClientWithTLS 创建使用双向 TLS 的客户端
+func NewClientWithTLS(tlsConfig *tls.Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
 	return &Client{
 		http: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: transport,
 		},
 	}
 }
 
-// Pull 拉取Agent数据
-func (c *Client) Pull(agent model.Agent) (*model.Inspection, error) {
+// SetKeySecret 设置解密节点签名密钥（Agent.APIKey）的 Master 密钥
+func (c *Client) SetKeySecret(secret string) {
+	c.secret = secret
+}
+
+// sign 已签发令牌的节点需要签名请求，旧版本遗留的未加密值不作为签名密钥，需管理员轮换令牌
+func (c *Client) sign(req *http.Request, agent model.Agent) error {
+	if !IsSealedKey(agent.APIKey) {
+		return nil
+	}
+	key, err := OpenKey(c.secret, agent.APIKey)
+	if err != nil {
+		return fmt.Errorf("节点 %d 签名密钥无效，请轮换令牌: %w", agent.ID, err)
+	}
+	return SignRequest(req, key, time.Now())
+}
+
+// Pull 拉取Agent数据，网络错误与 ctx 取消时返回错误，由调用方决定是否重试
+func (c *Client) Pull(ctx context.Context, agent model.Agent) (*model.Inspection, error) {
 	start := time.Now()
 
 	url := agent.URL + "/inspect"
-	resp, err := c.http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 声明 Master 支持的最高协议版本，旧版 Agent 忽略该请求头
+	req.Header.Set(protocol.HeaderVersion, strconv.Itoa(protocol.Version))
+
+	if err := c.sign(req, agent); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
 	if err != nil {
 		log.Printf("【Agent 网络不通】url=%s elapsed=%v err=%v", url, time.Since(start), err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("【Agent 原始回包】url=%s status=%d elapsed=%v len=%d",
+		url, resp.StatusCode, time.Since(start), len(body))
+
+	// Agent 限流属于临时状态，由调用方按 Retry-After 等待，不记录为故障
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		analysis, _ := json.Marshal(map[string]string{
+			"summary": fmt.Sprintf("Agent returned status %d: %s", resp.StatusCode, body),
+		})
 		return &model.Inspection{
 			AgentID:  agent.ID,
 			Hostname: agent.Name,
 			IP:       agent.IP,
 			Alert:    true,
 			Level:    model.LevelCritical,
-			Analysis: `{"summary":"Agent unreachable"}`,
+			Analysis: string(analysis),
 		}, nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return ParseInspection(agent, body), nil
+}
+
+// RateLimitError Agent 返回 429，RetryAfter 为要求的等待时间，未提供时为 0
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("agent rate limited, retry after %v", e.RetryAfter)
+}
+
+// parseRetryAfter 解析 Retry-After，支持秒数与 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// UnreachableInspection 节点无法访问时记录的 CRITICAL 巡检结果
+func UnreachableInspection(agent model.Agent, err error) *model.Inspection {
+	analysis, _ := json.Marshal(map[string]interface{}{
+		"summary": "Agent unreachable",
+		"details": []string{err.Error()},
+	})
+	return &model.Inspection{
+		AgentID:  agent.ID,
+		Hostname: agent.Name,
+		IP:       agent.IP,
+		Alert:    true,
+		Level:    model.LevelCritical,
+		Analysis: string(analysis),
+	}
+}
+
+// Info 获取 Agent 信息（协议版本、采集器与能力），v1 Agent 没有该接口
+func (c *Client) Info(ctx context.Context, agent model.Agent) (*protocol.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, agent.URL+"/info", nil)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.sign(req, agent); err != nil {
+		return nil, err
+	}
 
-	log.Printf("【Agent 原始回包】url=%s status=%d elapsed=%v len=%d",
-		url, resp.StatusCode, time.Since(start), len(body))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	// 解析响应
-	var response struct {
-		Hostname string          `json:"hostname"`
-		RawData  json.RawMessage `json:"raw_data"`
-		Analysis json.RawMessage `json:"analysis"`
+	if resp.StatusCode == http.StatusNotFound {
+		return &protocol.Info{ProtocolVersion: protocol.MinVersion, MinProtocolVersion: protocol.MinVersion}, nil
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("agent returned status %d", resp.StatusCode)
+	}
+
+	var info protocol.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
 
-	if err := json.Unmarshal(body, &response); err != nil {
+// ParseInspection 将 Agent 巡检数据解析为巡检记录，兼容 v1 与 v2 协议，格式错误时返回 CRITICAL 记录
+func ParseInspection(agent model.Agent, body []byte) *model.Inspection {
+	report, err := protocol.DecodeReport(body)
+	if err != nil {
 		return &model.Inspection{
 			AgentID:  agent.ID,
 			Hostname: agent.Name,
@@ -67,42 +190,193 @@ func (c *Client) Pull(agent model.Agent) (*model.Inspection, error) {
 			Alert:    true,
 			Level:    model.LevelCritical,
 			Analysis: `{"summary":"bad response format"}`,
-		}, nil
+		}
 	}
 
 	// 解析分析结果
-	var content struct {
-		Alert bool                  `json:"alert"`
-		Level model.InspectionLevel `json:"level"`
-	}
-	_ = json.Unmarshal(response.Analysis, &content)
+	var analysis protocol.Analysis
+	_ = json.Unmarshal(report.Analysis, &analysis)
 
 	// 创建巡检记录
+	m := report.Metrics
 	inspection := &model.Inspection{
-		AgentID:  agent.ID,
-		Hostname: response.Hostname,
-		IP:       agent.IP,
-		RawData:  string(response.RawData),
-		Analysis: string(response.Analysis),
-		Alert:    content.Alert,
-		Level:    content.Level,
+		AgentID:         agent.ID,
+		Hostname:        report.Hostname,
+		IP:              agent.IP,
+		RawData:         string(report.RawData),
+		Analysis:        string(report.Analysis),
+		Alert:           analysis.Alert,
+		Level:           model.InspectionLevel(analysis.Level),
+		CPUUsed:         m.CPUUsed,
+		MemoryUsed:      m.MemoryUsed,
+		DiskUsed:        m.DiskUsed,
+		LoadAvg:         m.LoadAvg,
+		PingLoss:        m.PingLoss,
+		JournalErr1h:    m.JournalErr1h,
+		ProtocolVersion: report.Version(),
+		ConfigVersion:   report.ConfigVersion,
+		InodesUsed:      m.InodesUsed,
+		FailedUnits:     len(m.FailedUnits),
+		ProcessCount:    m.ProcessCount,
+		ThreadCount:     m.ThreadCount,
+		ZombieCount:     m.ZombieCount,
+		TCPConnections:  m.TCPConnections,
+		TCPStates:       m.TCPStates,
+		ListenPorts:     m.ListenPorts,
+		ConntrackUsed:   m.ConntrackUsed,
 	}
-
-	// 解析系统指标
-	var sysInfo struct {
-		CPUUsed   string `json:"cpu_used"`
-		MemUsed   string `json:"mem_used"`
-		DiskAlert string `json:"disk_alert"`
-		LoadAvg   string `json:"cpu_load"`
-		PingLoss  string `json:"ping_loss"`
+	for _, mount := range m.Mounts {
+		inspection.Mounts = append(inspection.Mounts, model.InspectionMount{
+			AgentID:       agent.ID,
+			Device:        mount.Device,
+			Path:          mount.Path,
+			FSType:        mount.FSType,
+			Size:          mount.Size,
+			Used:          mount.Used,
+			Available:     mount.Available,
+			UsedPercent:   mount.UsedPercent,
+			Inodes:        mount.Inodes,
+			InodesUsed:    mount.InodesUsed,
+			InodesPercent: mount.InodesPercent,
+		})
+	}
+	for _, unit := range m.Units {
+		inspection.Services = append(inspection.Services, model.InspectionService{
+			AgentID:     agent.ID,
+			Name:        unit.Name,
+			LoadState:   unit.LoadState,
+			ActiveState: unit.ActiveState,
+			SubState:    unit.SubState,
+			Restarts:    unit.Restarts,
+			Uptime:      unit.Uptime,
+		})
+	}
+	for _, ranking := range []struct {
+		top   string
+		procs []protocol.Process
+	}{{model.ProcessTopCPU, m.TopCPU}, {model.ProcessTopMemory, m.TopMemory}} {
+		for i, p := range ranking.procs {
+			inspection.Processes = append(inspection.Processes, model.InspectionProcess{
+				AgentID: agent.ID,
+				Top:     ranking.top,
+				Rank:    i + 1,
+				PID:     p.PID,
+				Name:    p.Name,
+				User:    p.User,
+				CPU:     p.CPU,
+				Memory:  p.Memory,
+				RSS:     p.RSS,
+				Threads: p.Threads,
+				Command: p.Command,
+			})
+		}
+	}
+	for _, w := range m.Watches {
+		inspection.ProcessWatches = append(inspection.ProcessWatches, model.InspectionProcessWatch{
+			AgentID: agent.ID,
+			Name:    w.Name,
+			Pattern: w.Pattern,
+			Min:     w.Min,
+			Max:     w.Max,
+			Count:   w.Count,
+		})
+	}
+	for _, iface := range m.Interfaces {
+		inspection.Interfaces = append(inspection.Interfaces, model.InspectionInterface{
+			AgentID:   agent.ID,
+			Name:      iface.Name,
+			RxBytes:   iface.RxBytes,
+			TxBytes:   iface.TxBytes,
+			RxRate:    iface.RxRate,
+			TxRate:    iface.TxRate,
+			RxErrors:  iface.RxErrors,
+			TxErrors:  iface.TxErrors,
+			RxDropped: iface.RxDropped,
+			TxDropped: iface.TxDropped,
+		})
 	}
-	if err := json.Unmarshal(response.RawData, &sysInfo); err == nil {
-		// 解析数值（简化处理）
-		fmt.Sscanf(sysInfo.CPUUsed, "%f", &inspection.CPUUsed)
-		fmt.Sscanf(sysInfo.MemUsed, "%f", &inspection.MemoryUsed)
-		fmt.Sscanf(sysInfo.LoadAvg, "%f", &inspection.LoadAvg)
-		fmt.Sscanf(sysInfo.PingLoss, "%f", &inspection.PingLoss)
+	for _, p := range m.Probes {
+		inspection.Probes = append(inspection.Probes, model.InspectionProbe{
+			AgentID: agent.ID,
+			Name:    p.Name,
+			Type:    p.Type,
+			Target:  p.Target,
+			Success: p.Success,
+			Latency: p.Latency,
+			Loss:    p.Loss,
+			Error:   truncate(p.Error, 255),
+		})
+	}
+	for _, c := range m.Certificates {
+		inspection.Certificates = append(inspection.Certificates, model.InspectionCertificate{
+			AgentID:  agent.ID,
+			Source:   truncate(c.Source, 255),
+			Subject:  truncate(c.Subject, 255),
+			Issuer:   truncate(c.Issuer, 255),
+			SANs:     c.SANs,
+			NotAfter: c.NotAfter,
+			DaysLeft: c.DaysLeft,
+			Error:    truncate(c.Error, 255),
+		})
+	}
+	for _, lm := range m.LogMatches {
+		inspection.LogMatches = append(inspection.LogMatches, model.InspectionLogMatch{
+			AgentID:  agent.ID,
+			Rule:     lm.Rule,
+			Severity: model.InspectionLevel(lm.Severity),
+			Count:    lm.Count,
+			Samples:  lm.Samples,
+		})
+	}
+	if m.Audit != nil {
+		score := m.Audit.Score
+		inspection.ComplianceScore = &score
+		for _, f := range m.Audit.Findings {
+			inspection.Findings = append(inspection.Findings, model.InspectionFinding{
+				AgentID:   agent.ID,
+				FindingID: truncate(f.ID, 255),
+				Check:     truncate(f.Check, 64),
+				Severity:  model.InspectionLevel(f.Severity),
+				Title:     truncate(f.Title, 255),
+				Detail:    truncate(f.Detail, 255),
+			})
+		}
+	}
+	if p := m.Packages; p != nil {
+		inspection.PackageManager = truncate(p.Manager, 8)
+		inspection.Distro, inspection.DistroRelease = truncate(p.Distro, 32), truncate(p.Release, 32)
+		inspection.PackageCount, inspection.PackageUpdates, inspection.SecurityUpdates = p.Installed, p.Updates, p.Security
+		for _, pkg := range p.List {
+			ip := model.InspectionPackage{
+				AgentID:  agent.ID,
+				Name:     truncate(pkg.Name, 128),
+				Version:  truncate(pkg.Version, 64),
+				Arch:     truncate(pkg.Arch, 16),
+				Source:   truncate(pkg.Source, 128),
+				Update:   truncate(pkg.Update, 64),
+				Security: pkg.Security,
+			}
+			inspection.InstalledPackages = append(inspection.InstalledPackages, ip)
+			if ip.Update != "" {
+				inspection.Packages = append(inspection.Packages, ip)
+			}
+		}
+	}
+	// 采集时间用于区分缓存结果，Agent 时钟超前时以 Master 时间为准
+	if report.CollectedAt != nil {
+		inspection.CreatedAt = *report.CollectedAt
+		if now := time.Now(); inspection.CreatedAt.After(now) {
+			inspection.CreatedAt = now
+		}
 	}
 
-	return inspection, nil
+	return inspection
+}
+
+// truncate 按字符截断过长的字段，避免超出列长度
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
 }
@@ -0,0 +1,601 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/collector"
+	"cyber-inspector/internal/protocol"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix Agent 配置的环境变量前缀，如 CYBER_AGENT_LLM_API_URL 对应 llm.api_url
+const EnvPrefix = "CYBER_AGENT"
+
+// Config Agent 配置
+type Config struct {
+	Listen       string            `mapstructure:"listen"`   // /inspect 监听地址
+	Hostname     string            `mapstructure:"hostname"` // 上报的主机名，为空时使用系统主机名
+	URL          string            `mapstructure:"url"`      // 对外地址，自注册时上报，为空时由 Master 推导
+	Mode         string            `mapstructure:"mode"`     // pull / push
+	Stream       bool              `mapstructure:"stream"`   // 与 Master 建立长连接
+	Master       MasterConfig      `mapstructure:"master"`
+	TLS          AgentTLSConfig    `mapstructure:"tls"`
+	Push         PushConfig        `mapstructure:"push"`
+	Cache        CacheConfig       `mapstructure:"cache"`
+	RateLimit    RateLimitConfig   `mapstructure:"rate_limit"`
+	Collectors   []string          `mapstructure:"collectors"` // 启用的采集器
+	Services     []string          `mapstructure:"services"`   // 需要保持运行的 systemd 单元，如 nginx、sshd
+	Processes    ProcessConfig     `mapstructure:"processes"`
+	Probes       []ProbeConfig     `mapstructure:"probes"` // 连通性探测
+	Certificates CertificateConfig `mapstructure:"certificates"`
+	Logs         LogConfig         `mapstructure:"logs"`
+	Audit        AuditConfig       `mapstructure:"audit"`
+	Packages     PackageConfig     `mapstructure:"packages"`
+	Thresholds   ThresholdConfig   `mapstructure:"thresholds"`
+	LLM          AgentLLMConfig    `mapstructure:"llm"`
+	Remote       RemoteConfig      `mapstructure:"remote"`
+
+	RemoteVersion string `mapstructure:"-"` // 已应用的远程配置版本，未应用时为空
+}
+
+// MasterConfig Master 连接配置
+type MasterConfig struct {
+	URL         string `mapstructure:"url"`
+	AgentID     uint64 `mapstructure:"agent_id"`
+	Token       string `mapstructure:"token"`        // Master 签发的节点令牌
+	EnrollToken string `mapstructure:"enroll_token"` // 注册令牌，未配置节点令牌时用于自注册
+	State       string `mapstructure:"state"`        // 自注册凭据保存路径
+}
+
+// AgentTLSConfig 双向 TLS 配置
+type AgentTLSConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	CertDir string `mapstructure:"cert_dir"`
+}
+
+// PushConfig 推送模式配置
+type PushConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+	SpoolDir string        `mapstructure:"spool_dir"` // 推送失败时的本地缓存目录
+}
+
+// CacheConfig 采集结果缓存配置
+type CacheConfig struct {
+	TTL time.Duration `mapstructure:"ttl"` // 缓存有效期，0 表示每次请求都重新采集
+}
+
+// RateLimitConfig /inspect 按调用方 IP 限流配置
+type RateLimitConfig struct {
+	PerMinute int `mapstructure:"per_minute"` // 每分钟允许的请求数，0 表示不限流
+	Burst     int `mapstructure:"burst"`      // 允许的突发请求数
+}
+
+// ProcessConfig 进程采集配置
+type ProcessConfig struct {
+	Top   int                  `mapstructure:"top"`   // 上报 CPU 与内存占用最高的进程数
+	Watch []ProcessWatchConfig `mapstructure:"watch"` // 需要保持运行的进程
+}
+
+// ProcessWatchConfig 进程守护配置，按正则匹配完整命令行计数
+type ProcessWatchConfig struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+	Min     int    `mapstructure:"min"` // 最少实例数
+	Max     int    `mapstructure:"max"` // 最多实例数，0 表示不限
+}
+
+// ProbeConfig 连通性探测配置
+type ProbeConfig struct {
+	Name         string        `mapstructure:"name"`
+	Type         string        `mapstructure:"type"`          // icmp / tcp / http / dns
+	Target       string        `mapstructure:"target"`        // icmp、dns 为主机名，tcp 为 host:port，http 为 URL
+	Server       string        `mapstructure:"server"`        // dns 使用的服务器，为空时使用系统配置
+	ExpectStatus int           `mapstructure:"expect_status"` // http 期望的状态码，0 表示小于 400 即可
+	ExpectBody   string        `mapstructure:"expect_body"`   // http 响应需要包含的内容
+	Insecure     bool          `mapstructure:"insecure"`      // http 跳过证书校验
+	Timeout      time.Duration `mapstructure:"timeout"`       // 超时，0 时为 5s
+}
+
+// defaultProbeTimeout 未配置超时的探测使用的超时
+const defaultProbeTimeout = 5 * time.Second
+
+// CertificateConfig 证书到期检查配置
+type CertificateConfig struct {
+	Files     []string `mapstructure:"files"`     // PEM 证书文件，支持通配符，如 /etc/nginx/ssl/*.crt
+	Endpoints []string `mapstructure:"endpoints"` // TLS 端点，如 example.com:443
+}
+
+// LogConfig 日志规则配置
+type LogConfig struct {
+	Sources   []LogSourceConfig `mapstructure:"sources"`
+	Rules     []LogRuleConfig   `mapstructure:"rules"`
+	Samples   int               `mapstructure:"samples"`    // 每条规则上报的样例行数
+	StateFile string            `mapstructure:"state_file"` // 读取位置保存路径
+}
+
+// LogSourceConfig 日志来源，file 与 unit 二选一
+type LogSourceConfig struct {
+	Name string `mapstructure:"name"`
+	File string `mapstructure:"file"` // 日志文件路径
+	Unit string `mapstructure:"unit"` // journald 单元，kernel 表示内核日志
+}
+
+// LogRuleConfig 日志匹配规则
+type LogRuleConfig struct {
+	Name     string `mapstructure:"name"`
+	Pattern  string `mapstructure:"pattern"`  // 匹配日志行的正则表达式
+	Severity string `mapstructure:"severity"` // WARNING / CRITICAL，默认 WARNING
+}
+
+// AuditConfig 安全基线审计配置
+type AuditConfig struct {
+	Interval     time.Duration `mapstructure:"interval"`      // 审计间隔，两次审计之间的采集复用上次结果
+	SUIDAllow    []string      `mapstructure:"suid_allow"`    // 额外允许的 SUID 程序，文件名或完整路径
+	AllowedPorts []int         `mapstructure:"allowed_ports"` // 允许监听全部地址的 TCP 端口
+}
+
+// PackageConfig 软件包采集配置
+type PackageConfig struct {
+	Interval time.Duration `mapstructure:"interval"` // 采集间隔，两次采集之间复用上次结果
+}
+
+// ThresholdConfig 本地分析阈值，同时写入 LLM 提示词
+type ThresholdConfig struct {
+	CPU             float64 `mapstructure:"cpu"`                // CPU 使用率（%），超过为 CRITICAL
+	LoadFactor      float64 `mapstructure:"load_factor"`        // 1 分钟负载超过 核数×该值 为 CRITICAL
+	Memory          float64 `mapstructure:"memory"`             // 内存使用率（%），超过为 CRITICAL
+	Disk            float64 `mapstructure:"disk"`               // 磁盘使用率（%），超过为 CRITICAL
+	Inodes          float64 `mapstructure:"inodes"`             // inode 使用率（%），超过为 CRITICAL
+	DiskTemperature float64 `mapstructure:"disk_temperature"`   // 磁盘温度（℃），超过为 WARNING
+	DiskWear        float64 `mapstructure:"disk_wear"`          // 固态盘寿命消耗（%），超过为 WARNING
+	ServiceRestarts int     `mapstructure:"service_restarts"`   // 单元自动重启次数，超过为 WARNING
+	Zombies         int     `mapstructure:"zombies"`            // 僵尸进程数，超过为 WARNING
+	Conntrack       float64 `mapstructure:"conntrack"`          // 连接跟踪表使用率（%），超过为 WARNING
+	CertWarning     int     `mapstructure:"cert_warning_days"`  // 证书剩余天数，不超过为 WARNING
+	CertCritical    int     `mapstructure:"cert_critical_days"` // 证书剩余天数，不超过为 CRITICAL
+	Compliance      float64 `mapstructure:"compliance"`         // 安全基线合规得分，低于为 WARNING
+	SecurityUpdates int     `mapstructure:"security_updates"`   // 有安全更新的软件包数，超过为 WARNING
+	DiskReport      float64 `mapstructure:"disk_report"`        // 磁盘使用率超过该值时列入上报
+	JournalErrors   int     `mapstructure:"journal_errors"`     // 1 小时内错误日志数，超过为 WARNING
+	PingLoss        float64 `mapstructure:"ping_loss"`          // 网关丢包率（%），超过为 WARNING
+}
+
+// AgentLLMConfig LLM 分析配置，未启用时按阈值在本地分析
+type AgentLLMConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	APIURL      string        `mapstructure:"api_url"` // OpenAI 兼容的 chat/completions 地址
+	Model       string        `mapstructure:"model"`
+	Temperature float64       `mapstructure:"temperature"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+// RemoteConfig 从 Master 获取远程配置
+type RemoteConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`   // 轮询间隔，长连接节点还会在配置变更时收到通知
+	CacheFile string        `mapstructure:"cache_file"` // 最近一次远程配置的本地缓存，Master 不可达时使用
+}
+
+// DefaultCollectors 默认启用的采集器
+var DefaultCollectors = protocol.Collectors
+
+// LoadConfig 加载配置，优先级：overrides（命令行参数）> 环境变量 > remote（Master 下发）> 配置文件 > 默认值
+// configFile 不存在时使用默认值，remote 为 nil 时不使用远程配置；返回的 viper 实例用于打印最终配置
+func LoadConfig(configFile string, remote *protocol.RemoteConfig, overrides map[string]interface{}) (*Config, *viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	setAgentDefaults(v)
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// 兼容早期的环境变量
+	_ = v.BindEnv("master.token", EnvPrefix+"_MASTER_TOKEN", "CYBER_AGENT_TOKEN")
+	_ = v.BindEnv("master.url", EnvPrefix+"_MASTER_URL", "CYBER_MASTER_URL")
+	_ = v.BindEnv("master.enroll_token", EnvPrefix+"_MASTER_ENROLL_TOKEN", "CYBER_ENROLL_TOKEN")
+
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err == nil {
+			v.SetConfigFile(configFile)
+			if err := v.ReadInConfig(); err != nil {
+				return nil, nil, fmt.Errorf("读取配置文件失败: %w", err)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+	}
+
+	if remote != nil && len(remote.Settings) > 0 {
+		for key := range remote.Settings {
+			if !isRemoteKey(key) {
+				return nil, nil, fmt.Errorf("不支持远程下发的配置项: %s", key)
+			}
+		}
+		if err := v.MergeConfigMap(nestSettings(remote.Settings)); err != nil {
+			return nil, nil, fmt.Errorf("合并远程配置失败: %w", err)
+		}
+	}
+
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+
+	conf := &Config{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if remote != nil {
+		conf.RemoteVersion = remote.Version
+	}
+	return conf, v, nil
+}
+
+// setAgentDefaults 设置默认值，所有键都需要默认值，环境变量才能覆盖
+func setAgentDefaults(v *viper.Viper) {
+	v.SetDefault("listen", ":8083")
+	v.SetDefault("hostname", "")
+	v.SetDefault("url", "")
+	v.SetDefault("mode", "pull")
+	v.SetDefault("stream", false)
+
+	v.SetDefault("master.url", "")
+	v.SetDefault("master.agent_id", 0)
+	v.SetDefault("master.token", "")
+	v.SetDefault("master.enroll_token", "")
+	v.SetDefault("master.state", "data/agent.json")
+
+	v.SetDefault("tls.enabled", false)
+	v.SetDefault("tls.cert_dir", "data/certs")
+
+	v.SetDefault("push.interval", "5m")
+	v.SetDefault("push.spool_dir", "data/spool")
+
+	v.SetDefault("cache.ttl", "30s")
+	v.SetDefault("rate_limit.per_minute", 30)
+	v.SetDefault("rate_limit.burst", 10)
+
+	v.SetDefault("collectors", DefaultCollectors)
+	v.SetDefault("services", []string{})
+	v.SetDefault("processes.top", 5)
+	v.SetDefault("processes.watch", []interface{}{})
+	v.SetDefault("probes", []interface{}{})
+	v.SetDefault("certificates.files", []string{})
+	v.SetDefault("certificates.endpoints", []string{})
+	v.SetDefault("logs.sources", []interface{}{})
+	v.SetDefault("logs.rules", []interface{}{})
+	v.SetDefault("logs.samples", 3)
+	v.SetDefault("logs.state_file", "data/log-state.json")
+	v.SetDefault("audit.interval", "1h")
+	v.SetDefault("audit.suid_allow", []string{})
+	v.SetDefault("audit.allowed_ports", []int{22})
+	v.SetDefault("packages.interval", "1h")
+
+	v.SetDefault("thresholds.cpu", 85.0)
+	v.SetDefault("thresholds.load_factor", 1.5)
+	v.SetDefault("thresholds.memory", 90.0)
+	v.SetDefault("thresholds.disk", 90.0)
+	v.SetDefault("thresholds.inodes", 90.0)
+	v.SetDefault("thresholds.disk_temperature", 60.0)
+	v.SetDefault("thresholds.disk_wear", 90.0)
+	v.SetDefault("thresholds.service_restarts", 3)
+	v.SetDefault("thresholds.zombies", 10)
+	v.SetDefault("thresholds.conntrack", 80.0)
+	v.SetDefault("thresholds.cert_warning_days", 30)
+	v.SetDefault("thresholds.cert_critical_days", 7)
+	v.SetDefault("thresholds.compliance", 80.0)
+	v.SetDefault("thresholds.security_updates", 0)
+	v.SetDefault("thresholds.disk_report", 80.0)
+	v.SetDefault("thresholds.journal_errors", 10)
+	v.SetDefault("thresholds.ping_loss", 5.0)
+
+	v.SetDefault("llm.enabled", false)
+	v.SetDefault("llm.api_url", "")
+	v.SetDefault("llm.model", "sinollm")
+	v.SetDefault("llm.temperature", 0.0)
+	v.SetDefault("llm.timeout", "60s")
+
+	v.SetDefault("remote.enabled", true)
+	v.SetDefault("remote.interval", "5m")
+	v.SetDefault("remote.cache_file", "data/remote-config.json")
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return fmt.Errorf("listen 不能为空")
+	}
+	switch c.Mode {
+	case "pull", "push":
+	default:
+		return fmt.Errorf("不支持的巡检模式: %s", c.Mode)
+	}
+
+	needMaster := c.Mode == "push" || c.Stream || c.TLS.Enabled || c.Master.EnrollToken != ""
+	if needMaster && c.Master.URL == "" {
+		return fmt.Errorf("推送模式、长连接、双向 TLS 与自注册需要配置 master.url")
+	}
+	if c.Mode == "push" && c.Push.Interval <= 0 {
+		return fmt.Errorf("push.interval 必须大于 0")
+	}
+
+	if c.Cache.TTL < 0 || c.RateLimit.PerMinute < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("cache.ttl、rate_limit.per_minute 与 rate_limit.burst 不能为负数")
+	}
+
+	if len(c.Collectors) == 0 {
+		return fmt.Errorf("至少需要启用一个采集器")
+	}
+	for _, name := range c.Collectors {
+		if !isCollector(name) {
+			return fmt.Errorf("未知的采集器: %s", name)
+		}
+	}
+
+	t := c.Thresholds
+	for name, value := range map[string]float64{
+		"cpu": t.CPU, "memory": t.Memory, "disk": t.Disk, "inodes": t.Inodes, "disk_wear": t.DiskWear, "conntrack": t.Conntrack, "compliance": t.Compliance, "disk_report": t.DiskReport, "ping_loss": t.PingLoss,
+	} {
+		if value <= 0 || value > 100 {
+			return fmt.Errorf("thresholds.%s 必须在 (0, 100] 之间", name)
+		}
+	}
+	if t.DiskTemperature <= 0 {
+		return fmt.Errorf("thresholds.disk_temperature 必须大于 0")
+	}
+	if t.ServiceRestarts < 0 || t.Zombies < 0 || t.SecurityUpdates < 0 {
+		return fmt.Errorf("thresholds.service_restarts、thresholds.zombies 与 thresholds.security_updates 不能为负数")
+	}
+	if t.CertCritical < 0 || t.CertWarning < t.CertCritical {
+		return fmt.Errorf("thresholds.cert_critical_days 不能为负数，且不能大于 thresholds.cert_warning_days")
+	}
+	for _, endpoint := range c.Certificates.Endpoints {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			return fmt.Errorf("certificates.endpoints: %s 必须是 host:port", endpoint)
+		}
+	}
+	if err := c.Logs.Validate(); err != nil {
+		return err
+	}
+	if c.Audit.Interval < 0 || c.Packages.Interval < 0 {
+		return fmt.Errorf("audit.interval 与 packages.interval 不能为负数")
+	}
+	for _, port := range c.Audit.AllowedPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("audit.allowed_ports: %d 不是有效的端口", port)
+		}
+	}
+	if c.Processes.Top < 0 {
+		return fmt.Errorf("processes.top 不能为负数")
+	}
+	for _, w := range c.Processes.Watch {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("processes.watch: %w", err)
+		}
+	}
+	names := make(map[string]bool, len(c.Probes))
+	for _, p := range c.Probes {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("probes: %w", err)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("probes: 名称 %s 重复", p.Name)
+		}
+		names[p.Name] = true
+	}
+	for _, unit := range c.Services {
+		if strings.TrimSpace(unit) == "" {
+			return fmt.Errorf("services 不能包含空的单元名")
+		}
+	}
+	if t.LoadFactor <= 0 || t.JournalErrors < 0 {
+		return fmt.Errorf("thresholds.load_factor 必须大于 0，thresholds.journal_errors 不能为负数")
+	}
+
+	if c.Remote.Enabled && c.Remote.Interval <= 0 {
+		return fmt.Errorf("remote.interval 必须大于 0")
+	}
+
+	if c.LLM.Enabled {
+		if c.LLM.APIURL == "" {
+			return fmt.Errorf("启用 LLM 时 llm.api_url 不能为空")
+		}
+		if c.LLM.Timeout <= 0 {
+			return fmt.Errorf("llm.timeout 必须大于 0")
+		}
+	}
+	return nil
+}
+
+// Validate 校验进程守护配置
+func (w ProcessWatchConfig) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("name 不能为空")
+	}
+	if _, err := regexp.Compile(w.Pattern); err != nil || w.Pattern == "" {
+		return fmt.Errorf("%s 的 pattern 不是有效的正则表达式", w.Name)
+	}
+	if w.Min < 0 || w.Max < 0 || (w.Max > 0 && w.Max < w.Min) {
+		return fmt.Errorf("%s 的实例数范围错误: min=%d max=%d", w.Name, w.Min, w.Max)
+	}
+	return nil
+}
+
+// Validate 校验探测配置
+func (p ProbeConfig) Validate() error {
+	if p.Name == "" || p.Target == "" {
+		return fmt.Errorf("name 与 target 不能为空")
+	}
+	switch p.Type {
+	case protocol.ProbeICMP, protocol.ProbeDNS:
+	case protocol.ProbeTCP:
+		if _, _, err := net.SplitHostPort(p.Target); err != nil {
+			return fmt.Errorf("%s 的 target 必须是 host:port", p.Name)
+		}
+	case protocol.ProbeHTTP:
+		if u, err := url.Parse(p.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s 的 target 必须是 http(s) URL", p.Name)
+		}
+	default:
+		return fmt.Errorf("%s 的探测类型 %q 不支持，可选 %s", p.Name, p.Type, strings.Join(protocol.ProbeTypes, "/"))
+	}
+	if p.Timeout < 0 || p.ExpectStatus < 0 {
+		return fmt.Errorf("%s 的 timeout 与 expect_status 不能为负数", p.Name)
+	}
+	return nil
+}
+
+// ProbeSpecs 探测配置转换为采集参数
+func (c *Config) ProbeSpecs() []collector.ProbeSpec {
+	specs := make([]collector.ProbeSpec, 0, len(c.Probes))
+	for _, p := range c.Probes {
+		timeout := p.Timeout
+		if timeout == 0 {
+			timeout = defaultProbeTimeout
+		}
+		specs = append(specs, collector.ProbeSpec{
+			Name: p.Name, Type: p.Type, Target: p.Target, Server: p.Server,
+			ExpectStatus: p.ExpectStatus, ExpectBody: p.ExpectBody, Insecure: p.Insecure, Timeout: timeout,
+		})
+	}
+	return specs
+}
+
+// Validate 校验日志规则配置
+func (l LogConfig) Validate() error {
+	if l.Samples < 0 {
+		return fmt.Errorf("logs.samples 不能为负数")
+	}
+	names := make(map[string]bool, len(l.Sources))
+	for _, s := range l.Sources {
+		if s.Name == "" || (s.File == "") == (s.Unit == "") {
+			return fmt.Errorf("logs.sources: %q 需要 name，且 file 与 unit 二选一", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("logs.sources: 名称 %s 重复", s.Name)
+		}
+		names[s.Name] = true
+	}
+	names = make(map[string]bool, len(l.Rules))
+	for _, r := range l.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("logs.rules: name 不能为空")
+		}
+		if names[r.Name] {
+			return fmt.Errorf("logs.rules: 名称 %s 重复", r.Name)
+		}
+		names[r.Name] = true
+		if _, err := regexp.Compile(r.Pattern); err != nil || r.Pattern == "" {
+			return fmt.Errorf("logs.rules: %s 的 pattern 不是有效的正则表达式", r.Name)
+		}
+		switch strings.ToUpper(r.Severity) {
+		case "", "WARNING", "CRITICAL":
+		default:
+			return fmt.Errorf("logs.rules: %s 的 severity 必须是 WARNING 或 CRITICAL", r.Name)
+		}
+	}
+	return nil
+}
+
+// LogSources 日志来源转换为采集参数
+func (c *Config) LogSources() []collector.LogSource {
+	sources := make([]collector.LogSource, 0, len(c.Logs.Sources))
+	for _, s := range c.Logs.Sources {
+		sources = append(sources, collector.LogSource{Name: s.Name, File: s.File, Unit: s.Unit})
+	}
+	return sources
+}
+
+// LogRules 日志规则转换为采集参数，配置加载时已校验正则
+func (c *Config) LogRules() []collector.LogRule {
+	rules := make([]collector.LogRule, 0, len(c.Logs.Rules))
+	for _, r := range c.Logs.Rules {
+		severity := strings.ToUpper(r.Severity)
+		if severity == "" {
+			severity = "WARNING"
+		}
+		rules = append(rules, collector.LogRule{Name: r.Name, Pattern: regexp.MustCompile(r.Pattern), Severity: severity})
+	}
+	return rules
+}
+
+// AuditOptions 审计配置转换为采集参数
+func (c *Config) AuditOptions() collector.AuditOptions {
+	return collector.AuditOptions{SUIDAllow: c.Audit.SUIDAllow, AllowedPorts: c.Audit.AllowedPorts}
+}
+
+// Watches 进程守护配置转换为采集参数
+func (c *Config) Watches() []protocol.ProcessWatch {
+	watches := make([]protocol.ProcessWatch, 0, len(c.Processes.Watch))
+	for _, w := range c.Processes.Watch {
+		watches = append(watches, protocol.ProcessWatch{Name: w.Name, Pattern: w.Pattern, Min: w.Min, Max: w.Max})
+	}
+	return watches
+}
+
+// isCollector 是否为支持的采集器
+func isCollector(name string) bool {
+	for _, c := range DefaultCollectors {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isRemoteKey 是否为允许远程下发的配置键
+func isRemoteKey(key string) bool {
+	for _, k := range protocol.RemoteConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled 采集器是否启用
+func (c *Config) Enabled(collector string) bool {
+	for _, name := range c.Collectors {
+		if name == collector {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportHostname 上报的主机名
+func (c *Config) ReportHostname() string {
+	if c.Hostname != "" {
+		return c.Hostname
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// secretKeys 打印配置时需要隐藏的键
+var secretKeys = []string{"master.token", "master.enroll_token"}
+
+// PrintConfig 以 YAML 格式输出最终生效的配置，令牌只显示是否已配置
+func PrintConfig(v *viper.Viper) ([]byte, error) {
+	settings := v.AllSettings()
+	for _, key := range secretKeys {
+		parts := strings.Split(key, ".")
+		section, ok := settings[parts[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if s, _ := section[parts[1]].(string); s != "" {
+			section[parts[1]] = "******"
+		}
+	}
+	return yaml.Marshal(settings)
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "agent.yaml")
+	if err := os.WriteFile(file, []byte(`
+listen: ":9000"
+hostname: "db-01"
+collectors: [cpu, memory, disk]
+thresholds:
+  cpu: 70
+master:
+  url: "http://master:8080"
+  token: "file-token"
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CYBER_AGENT_THRESHOLDS_MEMORY", "80")
+	t.Setenv("CYBER_AGENT_TOKEN", "env-token")
+
+	conf, v, err := LoadConfig(file, nil, map[string]interface{}{"listen": ":9100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Listen != ":9100" {
+		t.Errorf("命令行参数应覆盖配置文件: %s", conf.Listen)
+	}
+	if conf.Master.Token != "env-token" || conf.Thresholds.Memory != 80 {
+		t.Errorf("环境变量应覆盖配置文件: token=%s memory=%v", conf.Master.Token, conf.Thresholds.Memory)
+	}
+	if conf.Thresholds.CPU != 70 || conf.Thresholds.Disk != 90 || conf.ReportHostname() != "db-01" {
+		t.Errorf("配置文件与默认值未生效: %+v", conf)
+	}
+	if !conf.Enabled("disk") || conf.Enabled("ping") {
+		t.Errorf("采集器配置错误: %v", conf.Collectors)
+	}
+
+	out, err := PrintConfig(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "env-token") {
+		t.Errorf("打印配置不应包含令牌:\n%s", out)
+	}
+}
+
+func TestLoadConfigRemote(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "agent.yaml")
+	if err := os.WriteFile(file, []byte("thresholds:\n  cpu: 70\n  disk: 85\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CYBER_AGENT_THRESHOLDS_MEMORY", "80")
+
+	remote := &protocol.RemoteConfig{Version: "abc", Settings: map[string]interface{}{
+		"thresholds.cpu":    60.0,
+		"thresholds.memory": 95.0,
+		"collectors":        []interface{}{"cpu", "memory"},
+	}}
+	conf, _, err := LoadConfig(file, remote, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Thresholds.CPU != 60 || conf.Thresholds.Disk != 85 || conf.RemoteVersion != "abc" {
+		t.Errorf("远程配置应覆盖配置文件中的同名配置: %+v", conf.Thresholds)
+	}
+	if conf.Thresholds.Memory != 80 {
+		t.Errorf("环境变量应覆盖远程配置: %v", conf.Thresholds.Memory)
+	}
+	if conf.Enabled("disk") {
+		t.Errorf("远程配置的采集器未生效: %v", conf.Collectors)
+	}
+
+	for _, settings := range []map[string]interface{}{
+		{"master.token": "x"},
+		{"thresholds.cpu": 150.0},
+	} {
+		if _, _, err := LoadConfig(file, &protocol.RemoteConfig{Version: "bad", Settings: settings}, nil); err == nil {
+			t.Errorf("非法远程配置应被拒绝: %v", settings)
+		}
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	for name, overrides := range map[string]map[string]interface{}{
+		"push 缺少 Master": {"mode": "push"},
+		"未知采集器":          {"collectors": []string{"gpu"}},
+		"阈值越界":           {"thresholds.cpu": 120},
+		"LLM 缺少地址":       {"llm.enabled": true},
+		"进程守护正则错误":       {"processes.watch": []map[string]interface{}{{"name": "nginx", "pattern": "nginx: (master"}}},
+		"探测类型错误":         {"probes": []map[string]interface{}{{"name": "db", "type": "udp", "target": "db:3306"}}},
+		"日志来源缺少路径":       {"logs.sources": []map[string]interface{}{{"name": "app"}}},
+		"日志规则级别错误":       {"logs.rules": []map[string]interface{}{{"name": "oom", "pattern": "Out of memory", "severity": "INFO"}}},
+		"TCP 探测缺少端口":     {"probes": []map[string]interface{}{{"name": "db", "type": "tcp", "target": "db"}}},
+	} {
+		if _, _, err := LoadConfig("", nil, overrides); err == nil {
+			t.Errorf("%s: 期望校验失败", name)
+		}
+	}
+
+	if _, _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"), nil, nil); err != nil {
+		t.Errorf("配置文件不存在时应使用默认配置: %v", err)
+	}
+
+	conf, _, err := LoadConfig("", nil, map[string]interface{}{"probes": []map[string]interface{}{
+		{"name": "db", "type": "tcp", "target": "10.0.0.5:3306", "timeout": "2s"},
+		{"name": "api", "type": "http", "target": "https://api.internal/health"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := conf.ProbeSpecs()
+	if len(specs) != 2 || specs[0].Timeout != 2*time.Second || specs[1].Timeout != defaultProbeTimeout {
+		t.Errorf("ProbeSpecs = %+v", specs)
+	}
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Version Agent 版本，自注册时上报
+const Version = "2.0.0"
+
+// Registration Agent 自注册请求
+type Registration struct {
+	EnrollmentToken string   `json:"enrollment_token" binding:"required"`
+	Hostname        string   `json:"hostname" binding:"required,max=64,hostname_rfc1123"`
+	IPs             []string `json:"ips"`
+	OS              string   `json:"os"`
+	Version         string   `json:"version"`
+	Capabilities    []string `json:"capabilities"`
+	URL             string   `json:"url"`                                      // Agent 对外地址，为空时由 Master 按 IP 与端口推导
+	Port            int      `json:"port"`                                     // Agent 监听端口
+	Mode            string   `json:"mode" binding:"omitempty,oneof=pull push"` // 巡检模式，默认 pull
+}
+
+// Credentials 注册成功后 Master 返回的凭据
+type Credentials struct {
+	AgentID uint64 `json:"agent_id"`
+	Token   string `json:"token"`
+	Status  string `json:"status"`
+}
+
+// Enroll 使用注册令牌向 Master 注册
+func Enroll(masterURL string, reg Registration) (*Credentials, error) {
+	body, err := json.Marshal(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(strings.TrimRight(masterURL, "/")+"/agent-api/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("Master 返回 %d: %s", resp.StatusCode, data)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// LoadCredentials 读取本地保存的凭据
+func LoadCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// SaveCredentials 保存凭据，文件只允许当前用户读写
+func SaveCredentials(path string, creds *Credentials) error {
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// LocalRegistration 采集本机信息生成注册请求
+func LocalRegistration(enrollmentToken string, port int, capabilities []string) Registration {
+	hostname, _ := os.Hostname()
+	return Registration{
+		EnrollmentToken: enrollmentToken,
+		Hostname:        hostname,
+		IPs:             localIPs(),
+		OS:              osName(),
+		Version:         Version,
+		Capabilities:    capabilities,
+		Port:            port,
+	}
+}
+
+// localIPs 本机非回环地址，IPv4 在前
+func localIPs() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+
+	var v4, v6 []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			v4 = append(v4, ipNet.IP.String())
+		} else {
+			v6 = append(v6, ipNet.IP.String())
+		}
+	}
+	return append(v4, v6...)
+}
+
+// osName 读取发行版名称，失败时返回 GOOS/GOARCH
+func osName() string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if v, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+				return strings.Trim(v, `"`)
+			}
+		}
+	}
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
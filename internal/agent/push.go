@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// DefaultSpoolLimit 本地缓存的最大巡检结果数，超出时丢弃最旧的结果
+const DefaultSpoolLimit = 1000
+
+// Pusher 推送模式：定时采集并推送到 Master，Master 不可达时缓存到磁盘，恢复后按采集顺序补发
+type Pusher struct {
+	masterURL string
+	agentID   uint64
+	token     string
+	spoolDir  string
+	limit     int
+	collect   func() (*protocol.Report, error)
+	http      *http.Client
+	stream    *StreamClient
+}
+
+// NewPusher 创建推送器，collect 负责采集一次巡检数据
+func NewPusher(masterURL string, agentID uint64, token, spoolDir string, collect func() (*protocol.Report, error)) *Pusher {
+	return &Pusher{
+		masterURL: strings.TrimRight(masterURL, "/"),
+		agentID:   agentID,
+		token:     token,
+		spoolDir:  spoolDir,
+		limit:     DefaultSpoolLimit,
+		collect:   collect,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// UseStream 长连接已建立时通过长连接推送，否则使用 HTTP
+func (p *Pusher) UseStream(stream *StreamClient) {
+	p.stream = stream
+}
+
+// Run 按间隔采集并推送，直到 ctx 结束
+func (p *Pusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.tick()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick 补发缓存后推送本次采集结果
+func (p *Pusher) tick() {
+	now := time.Now()
+	report, err := p.collect()
+	if err != nil {
+		log.Printf("【推送】采集失败: %v", err)
+		return
+	}
+	if report.CollectedAt == nil {
+		report.CollectedAt = &now
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("【推送】序列化失败: %v", err)
+		return
+	}
+
+	// 先补发缓存，保证按采集顺序送达；补发失败时本次结果直接缓存
+	err = p.Replay()
+	if err == nil {
+		err = p.send(data)
+		if err == nil {
+			return
+		}
+		if !retryable(err) {
+			log.Printf("【推送】Master 拒绝巡检结果，已丢弃: %v", err)
+			return
+		}
+	}
+
+	log.Printf("【推送】发送失败，缓存到磁盘: %v", err)
+	if err := p.spool(now, data); err != nil {
+		log.Printf("【推送】缓存失败: %v", err)
+	}
+}
+
+// Replay 按采集顺序补发磁盘缓存，遇到可重试的错误时停止
+func (p *Pusher) Replay() error {
+	files, err := p.spooled()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := p.send(data); err != nil {
+			if retryable(err) {
+				return err
+			}
+			log.Printf("【推送】Master 拒绝缓存的巡检结果，已丢弃 %s: %v", filepath.Base(f), err)
+		}
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	if len(files) > 0 {
+		log.Printf("【推送】已补发 %d 条缓存的巡检结果", len(files))
+	}
+	return nil
+}
+
+// statusError Master 返回的非成功状态
+type statusError struct {
+	code int
+	body []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("Master 返回 %d: %s", e.code, e.body)
+}
+
+// retryable 网络错误、5xx 以及认证失败（节点可能尚未审批）可重试，其他 4xx 说明数据本身无效
+func retryable(err error) bool {
+	se, ok := err.(*statusError)
+	if !ok {
+		return true
+	}
+	return se.code >= 500 || se.code == http.StatusUnauthorized || se.code == http.StatusForbidden || se.code == http.StatusTooManyRequests
+}
+
+// send 推送一条巡检结果
+func (p *Pusher) send(data []byte) error {
+	if p.stream != nil {
+		if err := p.stream.SendReport(data); err != ErrStreamDisconnected {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.masterURL+"/agent-api/inspections", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := SignAgentRequest(req, p.agentID, p.token, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, body: body}
+	}
+	return nil
+}
+
+// spool 将巡检结果写入缓存目录，文件名为采集时间以便排序
+func (p *Pusher) spool(at time.Time, data []byte) error {
+	if err := os.MkdirAll(p.spoolDir, 0o700); err != nil {
+		return err
+	}
+
+	name := filepath.Join(p.spoolDir, fmt.Sprintf("%020d.json", at.UnixNano()))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+
+	// 超出上限时丢弃最旧的结果
+	files, err := p.spooled()
+	if err != nil {
+		return err
+	}
+	for len(files) > p.limit {
+		log.Printf("【推送】缓存已满，丢弃 %s", filepath.Base(files[0]))
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// spooled 按采集时间升序返回缓存文件
+func (p *Pusher) spooled() ([]string, error) {
+	entries, err := os.ReadDir(p.spoolDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if _, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(p.spoolDir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestPusherSpoolAndReplay(t *testing.T) {
+	token := "push-token"
+	verifier := NewVerifier(token, time.Minute)
+
+	var (
+		mu       sync.Mutex
+		down     = true
+		received []string
+	)
+	master := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := verifier.Verify(r, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var report protocol.Report
+		_ = json.Unmarshal(body, &report)
+		received = append(received, report.Hostname)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer master.Close()
+
+	hosts := []string{"r1", "r2", "r3"}
+	n := 0
+	p := NewPusher(master.URL, 1, token, t.TempDir(), func() (*protocol.Report, error) {
+		n++
+		return &protocol.Report{Hostname: hosts[n-1], RawData: json.RawMessage(`{}`), Analysis: json.RawMessage(`{}`)}, nil
+	})
+
+	// Master 不可达时缓存到磁盘
+	p.tick()
+	p.tick()
+	if files, _ := p.spooled(); len(files) != 2 {
+		t.Fatalf("应缓存 2 条结果，实际 %d", len(files))
+	}
+
+	// 恢复后先补发缓存，再推送本次结果
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	p.tick()
+
+	if files, _ := p.spooled(); len(files) != 0 {
+		t.Fatalf("补发后缓存应为空，实际 %d", len(files))
+	}
+	if len(received) != 3 || received[0] != "r1" || received[1] != "r2" || received[2] != "r3" {
+		t.Fatalf("应按采集顺序送达，实际 %v", received)
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter 按调用方 IP 的令牌桶限流
+type RateLimiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter 创建限流器，perMinute 为每分钟允许的请求数，burst 为允许的突发请求数
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow 判断 key 的请求是否放行，拒绝时返回需要等待的时间
+func (l *RateLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Minute
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup 清理已补满的令牌桶，避免调用方过多时无限增长
+func (l *RateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware 超过限制时返回 429 与 Retry-After，按连接的对端地址限流，不信任 X-Forwarded-For
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	var requests int
+	var mu sync.Mutex
+
+	return func(c *gin.Context) {
+		now := time.Now()
+
+		mu.Lock()
+		requests++
+		if requests%1000 == 0 {
+			l.cleanup(now)
+		}
+		mu.Unlock()
+
+		ok, wait := l.Allow(c.RemoteIP(), now)
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "请求过于频繁"})
+			return
+		}
+		c.Next()
+	}
+}
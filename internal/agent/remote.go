@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// FetchRemoteConfig 从 Master 获取本节点的远程配置
+func FetchRemoteConfig(ctx context.Context, masterURL string, agentID uint64, token string) (*protocol.RemoteConfig, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(masterURL, "/")+protocol.ConfigPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := SignAgentRequest(req, agentID, token, time.Now()); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Master 返回 %d: %s", resp.StatusCode, data)
+	}
+
+	var remote protocol.RemoteConfig
+	if err := json.Unmarshal(data, &remote); err != nil {
+		return nil, fmt.Errorf("远程配置解析失败: %w", err)
+	}
+	return &remote, nil
+}
+
+// LoadRemoteConfig 读取本地缓存的远程配置，Master 不可达时使用
+func LoadRemoteConfig(path string) (*protocol.RemoteConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var remote protocol.RemoteConfig
+	if err := json.Unmarshal(data, &remote); err != nil {
+		return nil, err
+	}
+	return &remote, nil
+}
+
+// SaveRemoteConfig 缓存远程配置到本地
+func SaveRemoteConfig(path string, remote *protocol.RemoteConfig) error {
+	data, err := json.MarshalIndent(remote, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// nestSettings 将扁平键值（如 thresholds.cpu）转换为嵌套结构，供 viper 合并
+func nestSettings(settings map[string]interface{}) map[string]interface{} {
+	nested := make(map[string]interface{})
+	for key, value := range settings {
+		parts := strings.Split(key, ".")
+		m := nested
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = value
+	}
+	return nested
+}
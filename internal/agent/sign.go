@@ -0,0 +1,263 @@
+// file: internal/agent/sign.go
+package agent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求签名头
+const (
+	HeaderTimestamp = "X-Agent-Timestamp"
+	HeaderNonce     = "X-Agent-Nonce"
+	HeaderSignature = "X-Agent-Signature"
+	HeaderAgentID   = "X-Agent-ID" // Agent 请求 Master 时携带的节点ID
+)
+
+// DefaultClockSkew 默认允许的时钟偏差
+const DefaultClockSkew = 5 * time.Minute
+
+// GenerateToken 生成随机的 Agent 令牌
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken 计算令牌摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SigningKey 由明文令牌推导请求签名密钥，Agent 与 Master 使用同一密钥
+func SigningKey(token string) string {
+	return HashToken(token)
+}
+
+// TokenDigest 令牌的比对摘要，Master 只保存该摘要，无法由其推导出签名密钥
+func TokenDigest(token string) string {
+	return HashToken(SigningKey(token))
+}
+
+// sealedPrefix 加密后签名密钥的格式前缀
+const sealedPrefix = "v1:"
+
+// ErrSealedKey 签名密钥无法解密（Master 密钥已变更或数据损坏）
+var ErrSealedKey = errors.New("签名密钥无法解密")
+
+// sealCipher 由 Master 密钥（app.secret）派生 AES-256-GCM
+func sealCipher(secret string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealKey 使用 Master 密钥加密签名密钥，结果保存在节点记录中
+func SealKey(secret, key string) (string, error) {
+	aead, err := sealCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(key), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenKey 解密 SealKey 加密的签名密钥
+func OpenKey(secret, sealed string) (string, error) {
+	if !IsSealedKey(sealed) {
+		return "", ErrSealedKey
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		return "", ErrSealedKey
+	}
+	aead, err := sealCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", ErrSealedKey
+	}
+	key, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrSealedKey
+	}
+	return string(key), nil
+}
+
+// IsSealedKey 是否为加密后的签名密钥，旧版本直接保存的是未加密的令牌摘要
+func IsSealedKey(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+// signature 计算签名：HMAC-SHA256(key, 方法\n路径\n时间戳\nnonce\nbody摘要)
+func signature(key, method, uri, timestamp, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%x", method, uri, timestamp, nonce, bodySum)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest 为请求添加签名头，key 为签名密钥
+func SignRequest(req *http.Request, key string, now time.Time) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	nonceBuf := make([]byte, 16)
+	if _, err := rand.Read(nonceBuf); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(nonceBuf)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, signature(key, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	return nil
+}
+
+// SignAgentRequest Agent 请求 Master 时签名，token 为明文令牌
+func SignAgentRequest(req *http.Request, agentID uint64, token string, now time.Time) error {
+	req.Header.Set(HeaderAgentID, strconv.FormatUint(agentID, 10))
+	return SignRequest(req, SigningKey(token), now)
+}
+
+// readBody 读取请求体并恢复，便于后续继续读取
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// 签名校验错误
+var (
+	ErrMissingSignature = errors.New("缺少请求签名")
+	ErrClockSkew        = errors.New("请求时间超出允许偏差")
+	ErrReplay           = errors.New("重复的请求")
+	ErrBadSignature     = errors.New("签名校验失败")
+)
+
+// Verifier 请求签名校验器，在允许的时钟偏差内记录 nonce 以防止重放
+type Verifier struct {
+	key    string
+	skew   time.Duration
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewVerifier 创建签名校验器，token 为明文令牌
+func NewVerifier(token string, skew time.Duration) *Verifier {
+	v := NewKeyVerifier(skew)
+	v.key = SigningKey(token)
+	return v
+}
+
+// NewKeyVerifier 创建不绑定密钥的签名校验器，由调用方按节点提供密钥（Master 侧使用）
+func NewKeyVerifier(skew time.Duration) *Verifier {
+	if skew <= 0 {
+		skew = DefaultClockSkew
+	}
+	return &Verifier{
+		skew:   skew,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Verify 校验请求签名
+func (v *Verifier) Verify(req *http.Request, now time.Time) error {
+	return v.VerifyKey(req, v.key, now)
+}
+
+// VerifyKey 使用指定的签名密钥校验请求签名
+func (v *Verifier) VerifyKey(req *http.Request, key string, now time.Time) error {
+	if key == "" {
+		return ErrBadSignature
+	}
+
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	sig := req.Header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || sig == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	sent := time.Unix(ts, 0)
+	if sent.Before(now.Add(-v.skew)) || sent.After(now.Add(v.skew)) {
+		return ErrClockSkew
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	expected := signature(key, req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return ErrBadSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// 清理过期 nonce
+	for n, exp := range v.nonces {
+		if now.After(exp) {
+			delete(v.nonces, n)
+		}
+	}
+	if _, seen := v.nonces[nonce]; seen {
+		return ErrReplay
+	}
+	v.nonces[nonce] = sent.Add(2 * v.skew)
+	return nil
+}
+
+// Middleware 返回签名校验的 Gin 中间件
+func (v *Verifier) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := v.Verify(c.Request, time.Now()); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
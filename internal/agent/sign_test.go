@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	token, err := GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(token, time.Minute)
+	now := time.Now()
+
+	req := httptest.NewRequest(http.MethodPost, "/inspect?x=1", strings.NewReader(`{"a":1}`))
+	if err := SignRequest(req, HashToken(token), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(req, now); err != nil {
+		t.Fatalf("签名应校验通过: %v", err)
+	}
+
+	// 同一请求重放
+	if err := v.Verify(req, now.Add(time.Second)); err != ErrReplay {
+		t.Fatalf("重放请求应被拒绝，实际 %v", err)
+	}
+
+	// 篡改请求体
+	tampered := httptest.NewRequest(http.MethodPost, "/inspect?x=1", strings.NewReader(`{"a":2}`))
+	tampered.Header = req.Header.Clone()
+	if err := v.Verify(tampered, now); err != ErrBadSignature {
+		t.Fatalf("篡改的请求应被拒绝，实际 %v", err)
+	}
+
+	// 超出时钟偏差
+	late := httptest.NewRequest(http.MethodGet, "/inspect", nil)
+	_ = SignRequest(late, HashToken(token), now.Add(-2*time.Minute))
+	if err := v.Verify(late, now); err != ErrClockSkew {
+		t.Fatalf("过期请求应被拒绝，实际 %v", err)
+	}
+
+	// 错误的令牌
+	wrong := httptest.NewRequest(http.MethodGet, "/inspect", nil)
+	_ = SignRequest(wrong, HashToken("other-token"), now)
+	if err := v.Verify(wrong, now); err != ErrBadSignature {
+		t.Fatalf("错误令牌应被拒绝，实际 %v", err)
+	}
+
+	// 未签名
+	if err := v.Verify(httptest.NewRequest(http.MethodGet, "/inspect", nil), now); err != ErrMissingSignature {
+		t.Fatalf("未签名请求应被拒绝，实际 %v", err)
+	}
+}
+
+func TestSealKey(t *testing.T) {
+	token, _ := GenerateToken()
+	key := SigningKey(token)
+
+	sealed, err := SealKey("master-secret", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealedKey(sealed) || strings.Contains(sealed, key) {
+		t.Fatalf("签名密钥应加密保存: %s", sealed)
+	}
+	if TokenDigest(token) == key {
+		t.Fatal("比对摘要不应等于签名密钥")
+	}
+
+	if got, err := OpenKey("master-secret", sealed); err != nil || got != key {
+		t.Fatalf("解密结果 = %q, %v", got, err)
+	}
+	if _, err := OpenKey("other-secret", sealed); err != ErrSealedKey {
+		t.Fatalf("Master 密钥错误时应无法解密，实际 %v", err)
+	}
+	if _, err := OpenKey("master-secret", key); err != ErrSealedKey {
+		t.Fatalf("未加密的密钥不应被接受，实际 %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// ErrStreamDisconnected 长连接未建立
+var ErrStreamDisconnected = errors.New("长连接未建立")
+
+// StreamClient Agent 侧长连接：发送心跳与巡检结果，执行 Master 下发的命令，断开后按指数退避重连
+type StreamClient struct {
+	url      string
+	agentID  uint64
+	token    string
+	dialer   *websocket.Dialer
+	handlers map[string]func() error
+	info     func() protocol.Info // 心跳携带的 Agent 信息，每次心跳时获取
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	pending map[string]chan protocol.Message
+	seq     uint64
+	writeMu sync.Mutex // websocket 连接不支持并发写
+}
+
+// NewStreamClient 创建长连接客户端，masterURL 为 Master 的 HTTP 地址
+func NewStreamClient(masterURL string, agentID uint64, token string) *StreamClient {
+	url := strings.TrimRight(masterURL, "/")
+	url = strings.Replace(url, "https://", "wss://", 1)
+	url = strings.Replace(url, "http://", "ws://", 1)
+
+	return &StreamClient{
+		url:      url + protocol.StreamPath,
+		agentID:  agentID,
+		token:    token,
+		dialer:   &websocket.Dialer{HandshakeTimeout: 30 * time.Second, Proxy: http.ProxyFromEnvironment},
+		handlers: make(map[string]func() error),
+		pending:  make(map[string]chan protocol.Message),
+		info: func() protocol.Info {
+			return protocol.Info{ProtocolVersion: protocol.Version, MinProtocolVersion: protocol.MinVersion, AgentVersion: Version}
+		},
+	}
+}
+
+// SetInfo 设置心跳携带的 Agent 信息，需在 Run 之前调用
+func (s *StreamClient) SetInfo(info func() protocol.Info) {
+	s.info = info
+}
+
+// Handle 注册命令处理函数，需在 Run 之前调用
+func (s *StreamClient) Handle(command string, fn func() error) {
+	s.handlers[command] = fn
+}
+
+// Run 保持长连接，断开后按指数退避重连，直到 ctx 结束
+func (s *StreamClient) Run(ctx context.Context) {
+	backoff := Backoff{Min: time.Second, Max: time.Minute}
+	for {
+		connected, err := s.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff.Reset()
+		}
+
+		wait := backoff.Next()
+		log.Printf("【长连接】已断开，%v 后重连: %v", wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Connected 长连接是否已建立
+func (s *StreamClient) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn != nil
+}
+
+// SendReport 通过长连接发送巡检结果并等待 Master 确认
+func (s *StreamClient) SendReport(data []byte) error {
+	s.mu.Lock()
+	if s.conn == nil {
+		s.mu.Unlock()
+		return ErrStreamDisconnected
+	}
+	s.seq++
+	id := strconv.FormatUint(s.seq, 10)
+	ack := make(chan protocol.Message, 1)
+	s.pending[id] = ack
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	if err := s.write(protocol.Message{Type: protocol.MsgReport, ID: id, Payload: data}); err != nil {
+		return ErrStreamDisconnected
+	}
+
+	select {
+	case m, ok := <-ack:
+		if !ok {
+			return ErrStreamDisconnected
+		}
+		if m.Code != http.StatusOK {
+			return &statusError{code: m.Code, body: []byte(m.Error)}
+		}
+		return nil
+	case <-time.After(protocol.StreamPongWait):
+		return ErrStreamDisconnected
+	}
+}
+
+// connect 建立连接并处理消息，返回连接是否曾成功建立
+func (s *StreamClient) connect(ctx context.Context) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	if err := SignAgentRequest(req, s.agentID, s.token, time.Now()); err != nil {
+		return false, err
+	}
+
+	conn, resp, err := s.dialer.DialContext(ctx, s.url, req.Header)
+	if err != nil {
+		if resp != nil {
+			return false, fmt.Errorf("%w（HTTP %d）", err, resp.StatusCode)
+		}
+		return false, err
+	}
+	conn.SetReadLimit(protocol.StreamMaxMessage)
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(protocol.StreamPongWait))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(protocol.StreamWriteWait))
+	})
+	log.Printf("【长连接】已连接 %s", s.url)
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go s.heartbeat(conn, done)
+	defer func() {
+		close(done)
+		s.mu.Lock()
+		s.conn = nil
+		for id, ch := range s.pending {
+			close(ch)
+			delete(s.pending, id)
+		}
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	// ctx 结束时关闭连接以退出读循环
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(protocol.StreamPongWait))
+		var m protocol.Message
+		if err := conn.ReadJSON(&m); err != nil {
+			return true, err
+		}
+
+		switch m.Type {
+		case protocol.MsgAck:
+			s.mu.Lock()
+			if ch, ok := s.pending[m.ID]; ok {
+				select {
+				case ch <- m:
+				default:
+				}
+			}
+			s.mu.Unlock()
+		case protocol.MsgCommand:
+			go s.execute(m)
+		}
+	}
+}
+
+// execute 执行命令并回传结果
+func (s *StreamClient) execute(m protocol.Message) {
+	result := protocol.Message{Type: protocol.MsgResult, ID: m.ID, Command: m.Command}
+
+	fn, ok := s.handlers[m.Command]
+	if !ok {
+		result.Error = "不支持的命令: " + m.Command
+	} else if err := fn(); err != nil {
+		result.Error = err.Error()
+	}
+	log.Printf("【长连接】执行命令 %s: %s", m.Command, orOK(result.Error))
+
+	if err := s.write(result); err != nil {
+		log.Printf("【长连接】回传命令结果失败: %v", err)
+	}
+}
+
+// heartbeat 定时发送心跳
+func (s *StreamClient) heartbeat(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(protocol.HeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		payload, _ := json.Marshal(s.info())
+		if err := s.write(protocol.Message{Type: protocol.MsgHeartbeat, Payload: payload}); err != nil {
+			conn.Close()
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// write 发送消息
+func (s *StreamClient) write(m protocol.Message) error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn == nil {
+		return ErrStreamDisconnected
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	m.Time = time.Now()
+	conn.SetWriteDeadline(time.Now().Add(protocol.StreamWriteWait))
+	return conn.WriteJSON(m)
+}
+
+// orOK 错误为空时返回 ok
+func orOK(e string) string {
+	if e == "" {
+		return "ok"
+	}
+	return e
+}
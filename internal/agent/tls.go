@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/pki"
+)
+
+// 证书文件名
+const (
+	certFile = "agent.crt"
+	keyFile  = "agent.key"
+	caFile   = "ca.crt"
+)
+
+// CertManager Agent 侧证书管理：向 Master 申请证书、落盘并在临近过期时自动续期
+type CertManager struct {
+	dir       string
+	masterURL string
+	agentID   uint64
+	token     string
+	http      *http.Client
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// NewCertManager 创建证书管理器
+func NewCertManager(dir, masterURL string, agentID uint64, token string) *CertManager {
+	return &CertManager{
+		dir:       dir,
+		masterURL: strings.TrimRight(masterURL, "/"),
+		agentID:   agentID,
+		token:     token,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Ensure 加载本地证书，不存在或需要续期时向 Master 申请
+func (m *CertManager) Ensure() error {
+	if err := m.load(); err == nil && !m.needsRenewal(time.Now()) {
+		return nil
+	}
+	return m.Renew()
+}
+
+// Run 定期检查证书有效期，直到 ctx 结束
+func (m *CertManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !m.needsRenewal(time.Now()) {
+				continue
+			}
+			if err := m.Renew(); err != nil {
+				log.Printf("证书续期失败: %v", err)
+				continue
+			}
+			log.Printf("证书已续期")
+		}
+	}
+}
+
+// Renew 生成新私钥并向 Master 申请证书
+func (m *CertManager) Renew() error {
+	if m.masterURL == "" || m.agentID == 0 || m.token == "" {
+		return errors.New("申请证书需要配置 Master 地址、节点ID和令牌")
+	}
+
+	keyPEM, csrPEM, err := pki.NewKeyAndCSR(fmt.Sprintf("agent-%d", m.agentID))
+	if err != nil {
+		return err
+	}
+
+	body, _ := json.Marshal(map[string]string{"csr": string(csrPEM)})
+	req, err := http.NewRequest(http.MethodPost, m.masterURL+"/agent-api/certificate", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := SignAgentRequest(req, m.agentID, m.token, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := m.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Master 返回 %d: %s", resp.StatusCode, data)
+	}
+
+	var result struct {
+		Certificate string `json:"certificate"`
+		CA          string `json:"ca"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if err := m.install([]byte(result.Certificate), keyPEM, []byte(result.CA)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.dir, keyFile), keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.dir, certFile), []byte(result.Certificate), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, caFile), []byte(result.CA), 0o644)
+}
+
+// load 从目录加载证书
+func (m *CertManager) load() error {
+	certPEM, err := os.ReadFile(filepath.Join(m.dir, certFile))
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(m.dir, keyFile))
+	if err != nil {
+		return err
+	}
+	caPEM, err := os.ReadFile(filepath.Join(m.dir, caFile))
+	if err != nil {
+		return err
+	}
+	return m.install(certPEM, keyPEM, caPEM)
+}
+
+// install 解析并替换当前证书
+func (m *CertManager) install(certPEM, keyPEM, caPEM []byte) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("解析证书失败: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	pair.Leaf = leaf
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return errors.New("解析 CA 证书失败")
+	}
+
+	m.mu.Lock()
+	m.cert = &pair
+	m.pool = pool
+	m.mu.Unlock()
+	return nil
+}
+
+// needsRenewal 当前证书是否需要续期
+func (m *CertManager) needsRenewal(now time.Time) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.cert == nil {
+		return true
+	}
+	return pki.NeedsRenewal(m.cert.Leaf, now)
+}
+
+// ServerTLSConfig 返回 Agent 服务端 TLS 配置：要求 Master 出示由同一 CA 签发的客户端证书
+func (m *CertManager) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.mu.RLock()
+			cert, pool := m.cert, m.pool
+			m.mu.RUnlock()
+
+			if cert == nil {
+				return nil, errors.New("证书尚未就绪")
+			}
+			return &tls.Config{
+				MinVersion:            tls.VersionTLS12,
+				Certificates:          []tls.Certificate{*cert},
+				ClientAuth:            tls.RequireAndVerifyClientCert,
+				ClientCAs:             pool,
+				VerifyPeerCertificate: verifyMaster,
+			}, nil
+		},
+	}
+}
+
+// verifyMaster 只接受 Master 身份的客户端证书，防止其他节点的证书访问本节点
+func verifyMaster(_ [][]byte, chains [][]*x509.Certificate) error {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return errors.New("缺少客户端证书")
+	}
+	if cn := chains[0][0].Subject.CommonName; cn != pki.MasterCommonName {
+		return fmt.Errorf("拒绝非 Master 客户端证书: %s", cn)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/pki"
+)
+
+func TestMutualTLS(t *testing.T) {
+	ca, err := pki.LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyPEM, csrPEM, err := pki.NewKeyAndCSR("agent-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, _, err := ca.IssueAgent(csrPEM, "agent-1", []string{"127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewCertManager(t.TempDir(), "", 1, "")
+	if err := m.install(certPEM, keyPEM, ca.CertPEM); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = m.ServerTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(cn string) error {
+		cfg := &tls.Config{RootCAs: ca.Pool()}
+		if cn != "" {
+			cfg.GetClientCertificate = ca.ClientCertificate(cn, time.Hour)
+		}
+		client := NewClientWithTLS(cfg)
+		resp, err := client.http.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(pki.MasterCommonName); err != nil {
+		t.Fatalf("Master 证书应握手成功: %v", err)
+	}
+	if err := get("agent-2"); err == nil {
+		t.Fatal("非 Master 客户端证书应被拒绝")
+	}
+	if err := get(""); err == nil {
+		t.Fatal("未出示客户端证书应被拒绝")
+	}
+}
@@ -2,22 +2,25 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"cyber-inspector/internal/agent"
 	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/database"
 	"cyber-inspector/internal/handler"
-	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/migrate"
+	"cyber-inspector/internal/pki"
 	"cyber-inspector/internal/repository"
 	"cyber-inspector/internal/service"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -38,8 +41,22 @@ func Run() error {
 		log.Printf("初始化管理员账户失败: %v", err)
 	}
 
-	// 创建 HTTP 客户端
+	// 创建 HTTP 客户端，启用 TLS 时使用内置 CA 签发的客户端证书
+	var ca *pki.CA
 	httpClient := agent.NewClient()
+	if config.Conf.TLS.Enabled {
+		ca, err = pki.LoadOrCreateCA(config.Conf.TLS.CADir)
+		if err != nil {
+			return fmt.Errorf("加载 CA 失败: %w", err)
+		}
+		httpClient = agent.NewClientWithTLS(&tls.Config{
+			MinVersion:           tls.VersionTLS12,
+			RootCAs:              ca.Pool(),
+			GetClientCertificate: ca.ClientCertificate(pki.MasterCommonName, config.Conf.TLS.CertValidity),
+		})
+	}
+
+	httpClient.SetKeySecret(config.Conf.App.Secret)
 
 	// 创建巡检服务
 	checker := service.NewChecker(repo, httpClient)
@@ -56,7 +73,7 @@ func Run() error {
 	setupMiddleware(engine)
 
 	// 注册路由
-	setupRoutes(engine, repo, checker, db)
+	setupRoutes(engine, repo, checker, ca, db)
 
 	// 创建 HTTP 服务器
 	srv := &http.Server{
@@ -96,46 +113,90 @@ func Run() error {
 
 // initDatabase 初始化数据库
 func initDatabase() (*gorm.DB, error) {
-	// 配置 GORM
+	db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	// 执行未应用的迁移
+	if config.Conf.Database.AutoMigrate {
+		ran, err := migrate.Up(db)
+		if err != nil {
+			return nil, fmt.Errorf("数据库迁移失败: %w", err)
+		}
+		for _, m := range ran {
+			log.Printf("数据库迁移已执行: %04d_%s", m.Version, m.Name)
+		}
+	}
+
+	log.Printf("数据库连接成功: %s", config.Conf.Database.Driver)
+	return db, nil
+}
+
+// openDatabase 连接数据库
+func openDatabase() (*gorm.DB, error) {
 	gormConfig := &gorm.Config{
 		Logger: logger.Default.LogMode(getGormLogMode()),
 	}
+	return database.Open(config.Conf.Database, gormConfig)
+}
 
-	// 连接数据库
-	db, err := gorm.Open(mysql.Open(config.Conf.MySQL.DSN), gormConfig)
-	if err != nil {
-		return nil, err
+// Migrate 执行数据库迁移命令：up / down [步数] / status
+func Migrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: migrate up|down [步数]|status")
 	}
 
-	// 获取底层 SQL DB
-	sqlDB, err := db.DB()
+	db, err := openDatabase()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("连接数据库失败: %w", err)
 	}
 
-	// 配置连接池
-	sqlDB.SetMaxOpenConns(config.Conf.MySQL.MaxOpenConns)
-	sqlDB.SetMaxIdleConns(config.Conf.MySQL.MaxIdleConns)
-	sqlDB.SetConnMaxLifetime(config.Conf.MySQL.ConnMaxLifetime)
+	switch args[0] {
+	case "up":
+		ran, err := migrate.Up(db)
+		for _, m := range ran {
+			fmt.Printf("已执行  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
 
-	// 自动迁移
-	if err := autoMigrate(db); err != nil {
-		return nil, fmt.Errorf("数据库迁移失败: %w", err)
-	}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("无效的回滚步数: %s", args[1])
+			}
+		}
+		rolled, err := migrate.Down(db, steps)
+		for _, m := range rolled {
+			fmt.Printf("已回滚  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
 
-	log.Println("数据库连接成功")
-	return db, nil
-}
+	case "status":
+		list, err := migrate.List(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			state := "未执行"
+			if s.Applied {
+				state = "已执行 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-32s %s\n", s.Version, s.Name, state)
+		}
 
-// autoMigrate 自动迁移数据库
-func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&model.User{},
-		&model.Agent{},
-		&model.Inspection{},
-		&model.Alert{},
-		&model.LoginLog{},
-	)
+	default:
+		return fmt.Errorf("未知的迁移命令: %s", args[0])
+	}
+	return nil
 }
 
 // setupMiddleware 配置中间件
@@ -146,7 +207,7 @@ func setupMiddleware(engine *gin.Engine) {
 }
 
 // setupRoutes 注册路由
-func setupRoutes(engine *gin.Engine, repo *repository.Repository, checker *service.Checker, db *gorm.DB) {
+func setupRoutes(engine *gin.Engine, repo *repository.Repository, checker *service.Checker, ca *pki.CA, db *gorm.DB) {
 	// 健康检查
 	engine.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -174,15 +235,71 @@ func setupRoutes(engine *gin.Engine, repo *repository.Repository, checker *servi
 		auth.Use(handler.AuthMiddleware())
 		{
 			// Agent 管理
-			auth.GET("/agents", handler.ListAgents(repo))
+			auth.GET("/agents", handler.ListAgents(repo, repo, repo, checker.Stream()))
 			auth.POST("/agents", handler.CreateAgent(repo))
 			auth.PUT("/agents/:id", handler.UpdateAgent(repo))
 			auth.DELETE("/agents/:id", handler.DeleteAgent(repo))
 			auth.PUT("/agents/:id/interval", handler.UpdateInterval(repo))
+			auth.GET("/agents/:id/rules", handler.GetAgentRules(repo, repo))
+			auth.GET("/agents/:id/info", handler.GetAgentInfo(repo, checker))
+			auth.GET("/agents/:id/config", handler.GetAgentConfig(repo, repo))
+			auth.POST("/agents/:id/approve", handler.AdminMiddleware(), handler.ApproveAgent(repo))
+			auth.POST("/agents/:id/commands", handler.AdminMiddleware(), handler.SendAgentCommand(checker.Stream()))
+
+			// 自注册令牌
+			auth.GET("/enrollment-tokens", handler.ListEnrollmentTokens(repo))
+			auth.POST("/enrollment-tokens", handler.AdminMiddleware(), handler.CreateEnrollmentToken(repo))
+			auth.DELETE("/enrollment-tokens/:id", handler.AdminMiddleware(), handler.DeleteEnrollmentToken(repo))
+
+			// Agent 令牌
+			auth.GET("/agents/:id/token", handler.GetAgentToken(repo))
+			auth.POST("/agents/:id/token", handler.AdminMiddleware(), handler.RotateAgentToken(repo))
+			auth.DELETE("/agents/:id/token", handler.AdminMiddleware(), handler.RevokeAgentToken(repo))
+
+			// 阈值规则
+			auth.GET("/rules", handler.ListAlertRules(repo))
+			auth.POST("/rules", handler.AdminMiddleware(), handler.CreateAlertRule(repo))
+			auth.PUT("/rules/:id", handler.AdminMiddleware(), handler.UpdateAlertRule(repo))
+			auth.DELETE("/rules/:id", handler.AdminMiddleware(), handler.DeleteAlertRule(repo))
+
+			// 远程配置
+			auth.GET("/config-profiles", handler.ListConfigProfiles(repo))
+			auth.POST("/config-profiles", handler.AdminMiddleware(), handler.CreateConfigProfile(repo, checker.Stream()))
+			auth.PUT("/config-profiles/:id", handler.AdminMiddleware(), handler.UpdateConfigProfile(repo, checker.Stream()))
+			auth.DELETE("/config-profiles/:id", handler.AdminMiddleware(), handler.DeleteConfigProfile(repo, checker.Stream()))
 
 			// 巡检相关
 			auth.POST("/trigger", handler.TriggerCheck(checker))
 			auth.GET("/status", handler.GetStatus(checker))
+			auth.GET("/flapping", handler.ListFlapping(checker))
+			auth.GET("/breakers", handler.ListBreakers(checker))
+
+			// 证书到期
+			auth.GET("/certificates/expiring", handler.ListExpiringCertificates(repo, repo))
+			auth.GET("/compliance", handler.ListCompliance(repo, repo))
+
+			// 软件包与漏洞
+			auth.GET("/packages", handler.ListPackages(repo, repo))
+			auth.GET("/vulnerabilities", handler.ListVulnerabilities(repo, repo, checker.Vulnerabilities()))
+			auth.POST("/vulnerabilities/reload", handler.AdminMiddleware(), handler.ReloadVulnerabilities(checker.Vulnerabilities()))
+
+			// 内置 CA
+			if ca != nil {
+				auth.GET("/pki/ca", handler.GetCACertificate(ca))
+			}
+		}
+	}
+
+	// Agent 调用 Master 的接口：注册使用注册令牌，其余使用节点令牌签名认证
+	engine.POST("/agent-api/register", handler.RegisterAgent(repo))
+	agentAPI := engine.Group("/agent-api")
+	agentAPI.Use(handler.AgentAuthMiddleware(repo, agent.NewKeyVerifier(agent.DefaultClockSkew)))
+	{
+		agentAPI.POST("/inspections", handler.IngestInspection(checker))
+		agentAPI.GET("/stream", handler.AgentStream(checker.Stream()))
+		agentAPI.GET("/config", handler.AgentRemoteConfig(repo))
+		if ca != nil {
+			agentAPI.POST("/certificate", handler.IssueAgentCertificate(repo, ca, config.Conf.TLS.CertValidity))
 		}
 	}
 
@@ -0,0 +1,458 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// 审计检查项
+const (
+	AuditSSHRootLogin   = "ssh.permit_root_login"
+	AuditSSHPassword    = "ssh.password_authentication"
+	AuditEmptyPassword  = "account.empty_password"
+	AuditUID0           = "account.uid0"
+	AuditWorldWritable  = "file.world_writable"
+	AuditSUID           = "file.suid"
+	AuditFirewall       = "network.firewall"
+	AuditListenAny      = "network.listen_any"
+	maxFindingsPerCheck = 50 // 单个检查项最多上报的问题数
+	maxIncludeDepth     = 16 // sshd_config Include 最大嵌套层数，与 sshd 一致
+)
+
+// 审计扫描的目录，相对于根目录
+var (
+	sensitiveDirs = []string{"etc", "usr/bin", "usr/sbin", "usr/local/bin", "usr/local/sbin", "bin", "sbin"}
+	suidDirs      = []string{"usr/bin", "usr/sbin", "usr/local/bin", "usr/local/sbin", "bin", "sbin", "usr/lib", "usr/libexec"}
+)
+
+// DefaultSUIDAllow 发行版自带的常见 SUID 程序，按文件名匹配
+var DefaultSUIDAllow = []string{
+	"su", "sudo", "passwd", "chsh", "chfn", "newgrp", "gpasswd", "mount", "umount", "pkexec",
+	"fusermount", "fusermount3", "ping", "ping6", "crontab", "at", "unix_chkpwd", "pam_timestamp_check",
+	"ssh-keysign", "dbus-daemon-launch-helper", "polkit-agent-helper-1", "chrome-sandbox", "Xorg.wrap",
+	"newuidmap", "newgidmap", "userhelper", "staprun", "expiry", "chage", "sg",
+}
+
+// AuditOptions 审计参数
+type AuditOptions struct {
+	SUIDAllow    []string // 额外允许的 SUID 程序，文件名或完整路径
+	AllowedPorts []int    // 允许监听全部地址的端口
+}
+
+// Auditor 执行安全基线审计并缓存结果，审计涉及文件遍历，不必每次采集都执行
+type Auditor struct {
+	mu   sync.Mutex
+	last *protocol.Audit
+}
+
+// NewAuditor 创建审计器
+func NewAuditor() *Auditor {
+	return &Auditor{}
+}
+
+// Run 返回审计结果，上次审计距今不足 maxAge 时直接返回缓存
+func (a *Auditor) Run(ctx context.Context, opts AuditOptions, maxAge time.Duration) *protocol.Audit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.last != nil && time.Since(a.last.AuditedAt) < maxAge {
+		return a.last
+	}
+	a.last = RunAudit(ctx, os.DirFS("/"), opts)
+	return a.last
+}
+
+// auditReport 汇总各检查项的结果
+type auditReport struct {
+	checks   int
+	passed   int
+	findings []protocol.Finding
+}
+
+// add 记录一个已执行的检查项，没有问题即为通过
+func (r *auditReport) add(findings []protocol.Finding) {
+	r.checks++
+	if len(findings) == 0 {
+		r.passed++
+	}
+	if len(findings) > maxFindingsPerCheck {
+		findings = findings[:maxFindingsPerCheck]
+	}
+	r.findings = append(r.findings, findings...)
+}
+
+// result 计算合规得分
+func (r *auditReport) result() *protocol.Audit {
+	a := &protocol.Audit{Checks: r.checks, Passed: r.passed, Findings: r.findings, AuditedAt: time.Now()}
+	if r.checks > 0 {
+		a.Score = math.Round(float64(r.passed)/float64(r.checks)*10000) / 100
+	}
+	return a
+}
+
+// RunAudit 对 root 指向的文件系统执行全部检查，读取不到数据的检查项跳过
+func RunAudit(ctx context.Context, root fs.FS, opts AuditOptions) *protocol.Audit {
+	var r auditReport
+
+	// 优先使用 sshd -T 输出的生效配置
+	sshd, err := exec.CommandContext(ctx, "sshd", "-T").Output()
+	if err != nil {
+		sshd, err = ReadSSHDConfig(root, "etc/ssh/sshd_config")
+	}
+	if err == nil {
+		rootLogin, password := AuditSSHD(sshd)
+		r.add(rootLogin)
+		r.add(password)
+	}
+
+	if passwd, err := fs.ReadFile(root, "etc/passwd"); err == nil {
+		r.add(AuditUID0Accounts(passwd))
+	}
+	if shadow, err := fs.ReadFile(root, "etc/shadow"); err == nil {
+		r.add(AuditEmptyPasswords(shadow))
+	}
+
+	r.add(AuditWorldWritableFiles(root, sensitiveDirs))
+	r.add(AuditSUIDFiles(root, suidDirs, append(append([]string(nil), DefaultSUIDAllow...), opts.SUIDAllow...)))
+
+	if active, checked := firewallActive(ctx); checked {
+		var findings []protocol.Finding
+		if !active {
+			findings = append(findings, protocol.Finding{
+				ID: AuditFirewall, Check: AuditFirewall, Severity: "WARNING", Title: "未启用防火墙",
+				Detail: "firewalld、ufw、nftables 与 iptables 均没有生效的规则",
+			})
+		}
+		r.add(findings)
+	}
+
+	var tables [][]byte
+	for _, p := range TCPStatePaths {
+		if data, err := fs.ReadFile(root, strings.TrimPrefix(p, "/")); err == nil {
+			tables = append(tables, data)
+		}
+	}
+	if len(tables) > 0 {
+		r.add(AuditListeners(tables, opts.AllowedPorts))
+	}
+	return r.result()
+}
+
+// ReadSSHDConfig 读取 sshd_config，并将 Include 的文件按所在位置展开（如 Debian/Ubuntu 的 sshd_config.d/*.conf）。
+// 相对路径相对于 /etc/ssh，通配符匹配的文件按文件名顺序展开
+func ReadSSHDConfig(root fs.FS, name string) ([]byte, error) {
+	return readSSHDConfig(root, name, 0)
+}
+
+func readSSHDConfig(root fs.FS, name string, depth int) ([]byte, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("sshd_config Include 嵌套过深: %s", name)
+	}
+	data, err := fs.ReadFile(root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value := splitSSHDDirective(strings.TrimSpace(scanner.Text()))
+		if !strings.EqualFold(key, "include") {
+			buf.WriteString(scanner.Text())
+			buf.WriteByte('\n')
+			continue
+		}
+		for _, pattern := range strings.Fields(value) {
+			if path.IsAbs(pattern) {
+				pattern = strings.TrimPrefix(pattern, "/")
+			} else {
+				pattern = path.Join("etc/ssh", pattern)
+			}
+			// 没有匹配的文件时 sshd 同样忽略
+			matches, err := fs.Glob(root, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("sshd_config Include %s: %w", pattern, err)
+			}
+			for _, m := range matches {
+				included, err := readSSHDConfig(root, m, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				buf.Write(included)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// splitSSHDDirective 拆分配置行，键与值之间可以是空格、制表符或 "="（两侧可有空白）
+func splitSSHDDirective(line string) (key, value string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return line, ""
+	}
+	value = strings.TrimLeft(line[i:], " \t")
+	if strings.HasPrefix(value, "=") {
+		value = strings.TrimLeft(value[1:], " \t")
+	}
+	return line[:i], strings.TrimSpace(value)
+}
+
+// ParseSSHDConfig 解析 sshd_config 或 sshd -T 的输出，键转为小写；与 sshd 一致，同一配置项以第一次出现为准，忽略 Match 块。
+// Include 需先由 ReadSSHDConfig 展开
+func ParseSSHDConfig(data []byte) map[string]string {
+	conf := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := splitSSHDDirective(line)
+		key = strings.ToLower(key)
+		if key == "match" {
+			break
+		}
+		if _, ok := conf[key]; !ok {
+			conf[key] = strings.ToLower(value)
+		}
+	}
+	return conf
+}
+
+// AuditSSHD 检查是否允许 root 密码登录与密码认证，未配置时按 OpenSSH 默认值判断
+func AuditSSHD(data []byte) (rootLogin, password []protocol.Finding) {
+	conf := ParseSSHDConfig(data)
+	if v := conf["permitrootlogin"]; v == "yes" {
+		rootLogin = append(rootLogin, protocol.Finding{
+			ID: AuditSSHRootLogin, Check: AuditSSHRootLogin, Severity: "CRITICAL",
+			Title: "SSH 允许 root 使用密码登录", Detail: "PermitRootLogin " + v,
+		})
+	}
+	if v, ok := conf["passwordauthentication"]; !ok || v == "yes" {
+		password = append(password, protocol.Finding{
+			ID: AuditSSHPassword, Check: AuditSSHPassword, Severity: "WARNING",
+			Title: "SSH 允许密码认证", Detail: "PasswordAuthentication yes",
+		})
+	}
+	return rootLogin, password
+}
+
+// AuditUID0Accounts 检查 /etc/passwd 中除 root 外 UID 为 0 的账号
+func AuditUID0Accounts(passwd []byte) []protocol.Finding {
+	var findings []protocol.Finding
+	scanner := bufio.NewScanner(bytes.NewReader(passwd))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || fields[0] == "root" || fields[2] != "0" {
+			continue
+		}
+		findings = append(findings, protocol.Finding{
+			ID: AuditUID0 + ":" + fields[0], Check: AuditUID0, Severity: "CRITICAL",
+			Title: fmt.Sprintf("账号 %s 的 UID 为 0", fields[0]),
+		})
+	}
+	return findings
+}
+
+// AuditEmptyPasswords 检查 /etc/shadow 中密码为空的账号，已锁定（! 或 *）的账号不计
+func AuditEmptyPasswords(shadow []byte) []protocol.Finding {
+	var findings []protocol.Finding
+	scanner := bufio.NewScanner(bytes.NewReader(shadow))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 2 || fields[0] == "" || fields[1] != "" {
+			continue
+		}
+		findings = append(findings, protocol.Finding{
+			ID: AuditEmptyPassword + ":" + fields[0], Check: AuditEmptyPassword, Severity: "CRITICAL",
+			Title: fmt.Sprintf("账号 %s 的密码为空", fields[0]),
+		})
+	}
+	return findings
+}
+
+// AuditWorldWritableFiles 检查敏感目录下所有人可写的文件与目录，带粘滞位的目录与符号链接除外
+func AuditWorldWritableFiles(root fs.FS, dirs []string) []protocol.Finding {
+	var findings []protocol.Finding
+	walkAudit(root, dirs, func(p string, mode fs.FileMode) {
+		if mode&0o002 == 0 || (mode.IsDir() && mode&fs.ModeSticky != 0) {
+			return
+		}
+		findings = append(findings, protocol.Finding{
+			ID: AuditWorldWritable + ":/" + p, Check: AuditWorldWritable, Severity: "WARNING",
+			Title: "/" + p + " 所有人可写", Detail: mode.String(),
+		})
+	})
+	return findings
+}
+
+// AuditSUIDFiles 检查不在允许列表中的 SUID 程序，allow 可以是文件名或完整路径
+func AuditSUIDFiles(root fs.FS, dirs []string, allow []string) []protocol.Finding {
+	allowed := make(map[string]bool, len(allow))
+	for _, a := range allow {
+		allowed[a] = true
+	}
+	var findings []protocol.Finding
+	walkAudit(root, dirs, func(p string, mode fs.FileMode) {
+		if !mode.IsRegular() || mode&fs.ModeSetuid == 0 || allowed[path.Base(p)] || allowed["/"+p] {
+			return
+		}
+		findings = append(findings, protocol.Finding{
+			ID: AuditSUID + ":/" + p, Check: AuditSUID, Severity: "WARNING",
+			Title: "/" + p + " 设置了 SUID", Detail: mode.String(),
+		})
+	})
+	return findings
+}
+
+// walkAudit 遍历目录，不跟随符号链接；自身是符号链接的目录（如 /bin 指向 /usr/bin）跳过，避免重复上报
+func walkAudit(root fs.FS, dirs []string, visit func(p string, mode fs.FileMode)) {
+	for _, dir := range dirs {
+		if isSymlink(root, dir) {
+			continue
+		}
+		_ = fs.WalkDir(root, dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			visit(p, info.Mode())
+			return nil
+		})
+	}
+}
+
+// isSymlink 通过父目录的目录项判断 p 是否为符号链接，fs.FS 不提供 Lstat
+func isSymlink(root fs.FS, p string) bool {
+	entries, err := fs.ReadDir(root, path.Dir(p))
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.Name() == path.Base(p) {
+			return e.Type()&fs.ModeSymlink != 0
+		}
+	}
+	return false
+}
+
+// AuditListeners 检查监听全部地址（0.0.0.0 或 ::）且不在允许列表中的 TCP 端口
+func AuditListeners(tables [][]byte, allowedPorts []int) []protocol.Finding {
+	allowed := make(map[int]bool, len(allowedPorts))
+	for _, p := range allowedPorts {
+		allowed[p] = true
+	}
+	reported := make(map[int]bool)
+	var findings []protocol.Finding
+	for _, data := range tables {
+		for _, port := range WildcardListeners(data) {
+			if allowed[port] || reported[port] {
+				continue
+			}
+			reported[port] = true
+			findings = append(findings, protocol.Finding{
+				ID: fmt.Sprintf("%s:%d", AuditListenAny, port), Check: AuditListenAny, Severity: "WARNING",
+				Title: fmt.Sprintf("端口 %d 监听全部地址", port),
+			})
+		}
+	}
+	return findings
+}
+
+// WildcardListeners 解析 /proc/net/tcp 或 /proc/net/tcp6 中监听全部地址的端口
+func WildcardListeners(data []byte) []int {
+	var ports []int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for first := true; scanner.Scan(); first = false {
+		f := strings.Fields(scanner.Text())
+		if first || len(f) < 4 || strings.ToUpper(f[3]) != "0A" {
+			continue
+		}
+		addr, port, ok := strings.Cut(f[1], ":")
+		if !ok || strings.Trim(addr, "0") != "" {
+			continue
+		}
+		if p, err := strconv.ParseUint(port, 16, 16); err == nil {
+			ports = append(ports, int(p))
+		}
+	}
+	return ports
+}
+
+// firewallActive 依次检查 firewalld、ufw、nftables 与 iptables，checked 为 false 表示均无法查询
+func firewallActive(ctx context.Context) (active, checked bool) {
+	if out, err := exec.CommandContext(ctx, "firewall-cmd", "--state").Output(); err == nil {
+		checked = true
+		if strings.TrimSpace(string(out)) == "running" {
+			return true, true
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "ufw", "status").Output(); err == nil {
+		checked = true
+		if strings.Contains(string(out), "Status: active") {
+			return true, true
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "nft", "list", "ruleset").Output(); err == nil {
+		checked = true
+		if NftHasRules(out) {
+			return true, true
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "iptables", "-S").Output(); err == nil {
+		checked = true
+		if IptablesHasRules(out) {
+			return true, true
+		}
+	}
+	return false, checked
+}
+
+// NftHasRules nft list ruleset 的输出中是否有规则或非 accept 的默认策略
+func NftHasRules(out []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", line == "}", strings.HasPrefix(line, "table "), strings.HasPrefix(line, "chain "):
+		case strings.HasPrefix(line, "type "):
+			if strings.Contains(line, "policy drop") {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// IptablesHasRules iptables -S 的输出中是否有规则或非 ACCEPT 的默认策略
+func IptablesHasRules(out []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		switch {
+		case len(f) >= 3 && f[0] == "-P" && f[2] != "ACCEPT":
+			return true
+		case len(f) > 0 && f[0] == "-A":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func findingIDs(findings []protocol.Finding) []string {
+	ids := make([]string, 0, len(findings))
+	for _, f := range findings {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestAuditSSHD(t *testing.T) {
+	rootLogin, password := AuditSSHD([]byte(`# 注释
+PermitRootLogin yes
+PasswordAuthentication no
+PermitRootLogin no
+Match User backup
+    PasswordAuthentication yes
+`))
+	if len(rootLogin) != 1 || rootLogin[0].Severity != "CRITICAL" {
+		t.Errorf("应以第一次出现的 PermitRootLogin 为准: %+v", rootLogin)
+	}
+	if len(password) != 0 {
+		t.Errorf("应忽略 Match 块: %+v", password)
+	}
+
+	// sshd -T 的输出为小写键；未配置 PasswordAuthentication 时默认允许
+	rootLogin, password = AuditSSHD([]byte("permitrootlogin prohibit-password\nport 22\n"))
+	if len(rootLogin) != 0 || len(password) != 1 {
+		t.Errorf("默认值判断错误: %+v %+v", rootLogin, password)
+	}
+}
+
+func TestParseSSHDConfigSeparators(t *testing.T) {
+	conf := ParseSSHDConfig([]byte("PermitRootLogin\tyes\nPasswordAuthentication=no\nPort = 2222\nAuthorizedKeysFile  .ssh/authorized_keys .ssh/keys2\n"))
+	want := map[string]string{
+		"permitrootlogin":        "yes",
+		"passwordauthentication": "no",
+		"port":                   "2222",
+		"authorizedkeysfile":     ".ssh/authorized_keys .ssh/keys2",
+	}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("conf = %v", conf)
+	}
+}
+
+func TestReadSSHDConfigInclude(t *testing.T) {
+	root := fstest.MapFS{
+		// Debian/Ubuntu 默认在文件开头引入 sshd_config.d，其中的配置优先
+		"etc/ssh/sshd_config":                      {Data: []byte("Include /etc/ssh/sshd_config.d/*.conf\nPermitRootLogin no\nPasswordAuthentication yes\nInclude extra.conf missing/*.conf\n")},
+		"etc/ssh/sshd_config.d/50-cloud-init.conf": {Data: []byte("PasswordAuthentication no\n")},
+		"etc/ssh/sshd_config.d/10-root.conf":       {Data: []byte("PermitRootLogin=yes\n")},
+		"etc/ssh/sshd_config.d/readme.txt":         {Data: []byte("PermitRootLogin no\n")},
+		"etc/ssh/extra.conf":                       {Data: []byte("Port 2222\n")},
+	}
+	data, err := ReadSSHDConfig(root, "etc/ssh/sshd_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := ParseSSHDConfig(data)
+	if conf["permitrootlogin"] != "yes" || conf["passwordauthentication"] != "no" || conf["port"] != "2222" {
+		t.Errorf("Include 展开错误: %v", conf)
+	}
+
+	// 循环引用在达到最大嵌套层数后报错
+	loop := fstest.MapFS{"etc/ssh/sshd_config": {Data: []byte("Include sshd_config\n")}}
+	if _, err := ReadSSHDConfig(loop, "etc/ssh/sshd_config"); err == nil {
+		t.Error("循环 Include 应返回错误")
+	}
+}
+
+func TestAuditAccounts(t *testing.T) {
+	uid0 := AuditUID0Accounts([]byte("root:x:0:0:root:/root:/bin/bash\ntoor:x:0:0::/root:/bin/sh\nwww:x:33:33::/var/www:/usr/sbin/nologin\n"))
+	if !reflect.DeepEqual(findingIDs(uid0), []string{"account.uid0:toor"}) {
+		t.Errorf("uid0 = %v", findingIDs(uid0))
+	}
+	empty := AuditEmptyPasswords([]byte("root:$6$abc:19000:0:99999:7:::\nguest::19000:0:99999:7:::\ndaemon:*:19000:0:99999:7:::\nold:!:19000::::::\n"))
+	if !reflect.DeepEqual(findingIDs(empty), []string{"account.empty_password:guest"}) {
+		t.Errorf("empty = %v", findingIDs(empty))
+	}
+}
+
+func TestAuditFiles(t *testing.T) {
+	root := fstest.MapFS{
+		"etc/passwd":           {Mode: 0o644},
+		"etc/cron.d/job":       {Mode: 0o666},
+		"etc/shared":           {Mode: fs.ModeDir | fs.ModeSticky | 0o777},
+		"usr/bin/sudo":         {Mode: fs.ModeSetuid | 0o755},
+		"usr/bin/vim":          {Mode: fs.ModeSetuid | 0o755},
+		"usr/bin/agent-helper": {Mode: fs.ModeSetuid | 0o755},
+		"usr/bin/ls":           {Mode: 0o755},
+		"bin":                  {Mode: fs.ModeSymlink | 0o777, Data: []byte("usr/bin")},
+	}
+	dirs := []string{"etc", "usr/bin", "bin"}
+
+	ww := AuditWorldWritableFiles(root, dirs)
+	if !reflect.DeepEqual(findingIDs(ww), []string{"file.world_writable:/etc/cron.d/job"}) {
+		t.Errorf("world writable = %v", findingIDs(ww))
+	}
+	suid := AuditSUIDFiles(root, dirs, append(DefaultSUIDAllow, "/usr/bin/agent-helper"))
+	if !reflect.DeepEqual(findingIDs(suid), []string{"file.suid:/usr/bin/vim"}) {
+		t.Errorf("suid = %v", findingIDs(suid))
+	}
+}
+
+func TestAuditListeners(t *testing.T) {
+	tables := [][]byte{fixture(t, "proc-net-tcp.txt"), fixture(t, "proc-net-tcp6.txt")}
+	findings := AuditListeners(tables, []int{22})
+	if !reflect.DeepEqual(findingIDs(findings), []string{"network.listen_any:80"}) {
+		t.Errorf("listen any = %v", findingIDs(findings))
+	}
+}
+
+func TestFirewallRules(t *testing.T) {
+	if IptablesHasRules([]byte("-P INPUT ACCEPT\n-P FORWARD ACCEPT\n-P OUTPUT ACCEPT\n")) {
+		t.Error("只有 ACCEPT 策略不应视为启用")
+	}
+	if !IptablesHasRules([]byte("-P INPUT DROP\n-P OUTPUT ACCEPT\n")) {
+		t.Error("DROP 策略应视为启用")
+	}
+	empty := "table inet filter {\n\tchain input {\n\t\ttype filter hook input priority filter; policy accept;\n\t}\n}\n"
+	if NftHasRules([]byte(empty)) {
+		t.Error("空链不应视为启用")
+	}
+	rules := "table inet filter {\n\tchain input {\n\t\ttype filter hook input priority filter; policy accept;\n\t\ttcp dport 22 accept\n\t}\n}\n"
+	if !NftHasRules([]byte(rules)) {
+		t.Error("有规则时应视为启用")
+	}
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// certDialTimeout 连接单个 TLS 端点的超时
+const certDialTimeout = 10 * time.Second
+
+// Certificates 读取证书文件（支持通配符）与 TLS 端点的证书，now 用于计算剩余天数
+func Certificates(ctx context.Context, files, endpoints []string, now time.Time) []protocol.Certificate {
+	var certs []protocol.Certificate
+	for _, pattern := range files {
+		paths, err := filepath.Glob(pattern)
+		if err != nil || len(paths) == 0 {
+			certs = append(certs, protocol.Certificate{Source: pattern, Error: "没有匹配的证书文件"})
+			continue
+		}
+		for _, path := range paths {
+			certs = append(certs, fileCertificate(path, now))
+		}
+	}
+	for _, endpoint := range endpoints {
+		certs = append(certs, endpointCertificate(ctx, endpoint, now))
+	}
+	return certs
+}
+
+// fileCertificate 读取 PEM 文件中的第一张证书
+func fileCertificate(path string, now time.Time) protocol.Certificate {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return protocol.Certificate{Source: path, Error: err.Error()}
+	}
+	cert, err := ParsePEMCertificate(data)
+	if err != nil {
+		return protocol.Certificate{Source: path, Error: err.Error()}
+	}
+	return CertificateInfo(path, cert, now)
+}
+
+// endpointCertificate 握手后取服务端证书，不校验证书链以便读取已过期或自签名的证书
+func endpointCertificate(ctx context.Context, endpoint string, now time.Time) protocol.Certificate {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return protocol.Certificate{Source: endpoint, Error: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, certDialTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return protocol.Certificate{Source: endpoint, Error: err.Error()}
+	}
+	defer conn.Close()
+
+	peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return protocol.Certificate{Source: endpoint, Error: "服务端未返回证书"}
+	}
+	return CertificateInfo(endpoint, peers[0], now)
+}
+
+// ParsePEMCertificate 解析 PEM 数据中的第一张证书，跳过私钥等其它块
+func ParsePEMCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("未找到 PEM 格式的证书")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// CertificateInfo 提取证书的主题、签发者、SAN 与剩余天数
+func CertificateInfo(source string, cert *x509.Certificate, now time.Time) protocol.Certificate {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return protocol.Certificate{
+		Source:   source,
+		Subject:  cert.Subject.String(),
+		Issuer:   cert.Issuer.String(),
+		SANs:     sans,
+		NotAfter: cert.NotAfter.UTC(),
+		DaysLeft: int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+	}
+}
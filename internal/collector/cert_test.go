@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestCertificates(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	leaf := srv.Certificate()
+
+	dir := t.TempDir()
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})...)
+	if err := os.WriteFile(filepath.Join(dir, "server.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := leaf.NotAfter.Add(-10*24*time.Hour - time.Hour)
+	endpoint := strings.TrimPrefix(srv.URL, "https://")
+	certs := Certificates(context.Background(), []string{filepath.Join(dir, "*.pem"), filepath.Join(dir, "missing-*.crt")}, []string{endpoint}, now)
+	if len(certs) != 4 {
+		t.Fatalf("应返回 2 个文件、1 个无匹配的通配符与 1 个端点: %+v", certs)
+	}
+
+	broken, file, missing, remote := certs[0], certs[1], certs[2], certs[3]
+	if broken.Error == "" || missing.Error == "" {
+		t.Errorf("无效证书与无匹配文件应记录错误: %+v %+v", broken, missing)
+	}
+	for _, c := range []struct {
+		name string
+		cert protocol.Certificate
+	}{{"文件", file}, {"端点", remote}} {
+		if c.cert.Error != "" || c.cert.DaysLeft != 10 || !c.cert.NotAfter.Equal(leaf.NotAfter) {
+			t.Errorf("%s证书解析错误: %+v", c.name, c.cert)
+		}
+		if len(c.cert.SANs) == 0 || c.cert.Issuer == "" {
+			t.Errorf("%s证书缺少 SAN 或签发者: %+v", c.name, c.cert)
+		}
+	}
+}
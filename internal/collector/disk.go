@@ -0,0 +1,97 @@
+// Package collector Agent 端的结构化采集器，命令输出的解析与执行分开，便于用样例数据测试
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// virtualFS 不属于真实磁盘的文件系统类型
+var virtualFS = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "overlay": true, "squashfs": true, "shm": true,
+	"proc": true, "sysfs": true, "cgroup": true, "cgroup2": true, "devpts": true,
+	"nsfs": true, "tracefs": true, "debugfs": true, "fuse.lxcfs": true,
+}
+
+// realMount 是否为需要上报的真实文件系统，排除虚拟文件系统、loop 设备与容器运行时挂载点
+func realMount(device, fstype, path string) bool {
+	if virtualFS[fstype] || strings.Contains(device, "loop") {
+		return false
+	}
+	return !strings.HasPrefix(path, "/var/lib/docker") && !strings.Contains(path, "/kubelet")
+}
+
+// Mounts 通过 df 采集各文件系统的容量与 inode 使用情况
+func Mounts(ctx context.Context) ([]protocol.Mount, error) {
+	size, err := exec.CommandContext(ctx, "df", "-P", "-T", "-B1").Output()
+	if err != nil {
+		return nil, err
+	}
+	// 部分文件系统不支持 inode 统计，df -i 可能返回非零状态但仍有输出
+	inodes, _ := exec.CommandContext(ctx, "df", "-P", "-i").Output()
+	return ParseDF(size, inodes), nil
+}
+
+// ParseDF 解析 df -P -T -B1 与 df -P -i 的输出，按挂载点合并
+func ParseDF(size, inodes []byte) []protocol.Mount {
+	type inodeUsage struct{ total, used uint64 }
+	usage := make(map[string]inodeUsage)
+	for _, f := range dfRows(inodes, 6) {
+		total, _ := strconv.ParseUint(f[1], 10, 64)
+		used, _ := strconv.ParseUint(f[2], 10, 64)
+		usage[f[5]] = inodeUsage{total, used}
+	}
+
+	var mounts []protocol.Mount
+	seen := make(map[string]bool)
+	for _, f := range dfRows(size, 7) {
+		device, fstype, path := f[0], f[1], f[6]
+		if !realMount(device, fstype, path) || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		m := protocol.Mount{Device: device, Path: path, FSType: fstype}
+		m.Size, _ = strconv.ParseUint(f[2], 10, 64)
+		m.Used, _ = strconv.ParseUint(f[3], 10, 64)
+		m.Available, _ = strconv.ParseUint(f[4], 10, 64)
+		// 与 df 一致，按 已用/(已用+可用) 计算，不计入保留块
+		m.UsedPercent = percent(m.Used, m.Used+m.Available)
+		if u, ok := usage[path]; ok {
+			m.Inodes, m.InodesUsed = u.total, u.used
+			m.InodesPercent = percent(u.used, u.total)
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// dfRows 按列拆分 df 输出，跳过表头；挂载点可能包含空格，最后一列取剩余部分
+func dfRows(out []byte, columns int) [][]string {
+	var rows [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for first := true; scanner.Scan(); first = false {
+		fields := strings.Fields(scanner.Text())
+		if first || len(fields) < columns {
+			continue
+		}
+		last := strings.Join(fields[columns-1:], " ")
+		rows = append(rows, append(fields[:columns-1:columns-1], last))
+	}
+	return rows
+}
+
+// percent 计算百分比，保留两位小数
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// fixture 读取 testdata 中的命令输出样例
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseDF(t *testing.T) {
+	mounts := ParseDF(fixture(t, "df.txt"), fixture(t, "df-inodes.txt"))
+	if len(mounts) != 3 {
+		t.Fatalf("应只保留 3 个真实文件系统，实际 %+v", mounts)
+	}
+
+	root := mounts[0]
+	if root.Path != "/" || root.FSType != "ext4" || root.UsedPercent != 90.62 {
+		t.Errorf("根分区解析错误: %+v", root)
+	}
+	if root.Inodes != 6553600 || root.InodesUsed != 6225920 || root.InodesPercent != 95 {
+		t.Errorf("根分区 inode 解析错误: %+v", root)
+	}
+
+	backup := mounts[2]
+	if backup.Path != "/mnt/backup disk" || backup.Inodes != 0 || backup.InodesPercent != 0 {
+		t.Errorf("含空格的挂载点或无 inode 统计的文件系统解析错误: %+v", backup)
+	}
+
+	var m protocol.Metrics
+	m.SetMounts(mounts)
+	if m.DiskUsed != 90.62 || m.InodesUsed != 95 {
+		t.Errorf("DiskUsed = %v, InodesUsed = %v", m.DiskUsed, m.InodesUsed)
+	}
+}
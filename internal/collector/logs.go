@@ -0,0 +1,254 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// 日志读取限制
+const (
+	maxLogRead   = 16 * 1024 * 1024 // 单个文件每次最多读取的字节数，超出时跳过较早的部分
+	maxSampleLen = 512              // 样例行最大长度（字符）
+	logHeadLen   = 128              // 用于识别文件轮转的文件头长度
+)
+
+// journalKernel 表示读取内核日志（journalctl -k）的单元名
+const journalKernel = "kernel"
+
+// LogSource 日志来源，File 与 Unit 二选一
+type LogSource struct {
+	Name string
+	File string // 日志文件路径
+	Unit string // journald 单元，kernel 表示内核日志
+}
+
+// key 读取位置的存储键，与来源名称无关
+func (s LogSource) key() string {
+	if s.File != "" {
+		return "file:" + s.File
+	}
+	return "unit:" + s.Unit
+}
+
+// LogRule 日志匹配规则
+type LogRule struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Severity string
+}
+
+// logPosition 日志来源的读取位置
+type logPosition struct {
+	Offset int64  `json:"offset,omitempty"` // 文件已读取到的位置
+	Head   []byte `json:"head,omitempty"`   // 文件头，与当前文件不同说明已轮转
+	Cursor string `json:"cursor,omitempty"` // journald 游标
+}
+
+// LogTailer 记录各日志来源的读取位置，每次采集只匹配上次采集之后新增的日志
+type LogTailer struct {
+	mu        sync.Mutex
+	statePath string
+	positions map[string]logPosition
+}
+
+// NewLogTailer 创建日志读取器
+func NewLogTailer() *LogTailer {
+	return &LogTailer{}
+}
+
+// Collect 读取各来源新增的日志并按规则计数，samples 为每条规则保留的样例行数；
+// 首次读取某个来源时只记录当前位置。读取位置保存在 statePath，Agent 重启后继续
+func (t *LogTailer) Collect(ctx context.Context, statePath string, sources []LogSource, rules []LogRule, samples int) ([]protocol.LogMatch, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.positions == nil || t.statePath != statePath {
+		t.statePath, t.positions = statePath, loadLogPositions(statePath)
+	}
+
+	matches := make([]protocol.LogMatch, len(rules))
+	for i, r := range rules {
+		matches[i] = protocol.LogMatch{Rule: r.Name, Severity: r.Severity}
+	}
+
+	var errs []error
+	for _, src := range sources {
+		pos, seen := t.positions[src.key()]
+		var lines []string
+		var err error
+		if src.File != "" {
+			lines, pos, err = readLogFile(src.File, pos, seen)
+		} else {
+			lines, pos, err = readJournal(ctx, src.Unit, pos)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.positions[src.key()] = pos
+		if seen {
+			MatchLogLines(matches, rules, src.Name, lines, samples)
+		}
+	}
+
+	if err := t.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return matches, errors.Join(errs...)
+}
+
+// save 保存读取位置
+func (t *LogTailer) save() error {
+	data, err := json.Marshal(t.positions)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.statePath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(t.statePath, data, 0o600)
+}
+
+// loadLogPositions 读取保存的位置，文件不存在或损坏时从当前位置开始
+func loadLogPositions(path string) map[string]logPosition {
+	positions := make(map[string]logPosition)
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &positions)
+	}
+	return positions
+}
+
+// MatchLogLines 按规则匹配日志行，累加到 matches 并保留最近的 samples 条样例
+func MatchLogLines(matches []protocol.LogMatch, rules []LogRule, source string, lines []string, samples int) {
+	for _, line := range lines {
+		for i, r := range rules {
+			if !r.Pattern.MatchString(line) {
+				continue
+			}
+			m := &matches[i]
+			m.Count++
+			if samples <= 0 {
+				continue
+			}
+			sample := line
+			if runes := []rune(line); len(runes) > maxSampleLen {
+				sample = string(runes[:maxSampleLen])
+			}
+			m.Samples = append(m.Samples, "["+source+"] "+sample)
+			if len(m.Samples) > samples {
+				m.Samples = m.Samples[len(m.Samples)-samples:]
+			}
+		}
+	}
+}
+
+// readLogFile 读取文件自 pos 之后的完整行，末尾不完整的行留到下次；
+// 首次读取时从文件末尾开始，文件被截断或轮转（文件头变化）时从头读取
+func readLogFile(path string, pos logPosition, seen bool) ([]string, logPosition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, pos, err
+	}
+
+	size := info.Size()
+	head := make([]byte, logHeadLen)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	switch {
+	case !seen:
+		pos.Offset = size
+	case size < pos.Offset || !bytes.HasPrefix(head, pos.Head):
+		pos.Offset = 0
+	}
+	pos.Head = head
+	if size-pos.Offset > maxLogRead {
+		pos.Offset = size - maxLogRead
+	}
+
+	data := make([]byte, size-pos.Offset)
+	if _, err := f.ReadAt(data, pos.Offset); err != nil && err != io.EOF {
+		return nil, pos, err
+	}
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, pos, nil
+	}
+	pos.Offset += int64(end + 1)
+	return splitLines(data[:end+1]), pos, nil
+}
+
+// readJournal 读取 journald 单元在游标之后的日志；没有游标时只取得当前游标
+func readJournal(ctx context.Context, unit string, pos logPosition) ([]string, logPosition, error) {
+	args := []string{"--no-pager", "--quiet", "-o", "short-iso", "--show-cursor"}
+	if unit == journalKernel {
+		args = append(args, "-k")
+	} else {
+		args = append(args, "-u", unit)
+	}
+	if pos.Cursor == "" {
+		args = append(args, "-n", "1")
+	} else {
+		args = append(args, "--after-cursor", pos.Cursor)
+	}
+
+	out, err := exec.CommandContext(ctx, "journalctl", args...).Output()
+	if err != nil {
+		return nil, pos, err
+	}
+	lines, cursor := ParseJournal(out)
+	if pos.Cursor == "" {
+		lines = nil
+	}
+	if cursor != "" {
+		pos.Cursor = cursor
+	}
+	return lines, pos, nil
+}
+
+// ParseJournal 解析 journalctl --show-cursor 的输出，返回日志行与最后的游标
+func ParseJournal(out []byte) ([]string, string) {
+	var lines []string
+	var cursor string
+	for _, line := range splitLines(out) {
+		if c, ok := strings.CutPrefix(line, "-- cursor: "); ok {
+			cursor = c
+			continue
+		}
+		if strings.HasPrefix(line, "-- ") {
+			// "-- No entries --"、"-- Boot xxx --" 等提示
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, cursor
+}
+
+// splitLines 按行拆分，忽略空行
+func splitLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
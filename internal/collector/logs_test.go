@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cyber-inspector/internal/protocol"
+)
+
+var testLogRules = []LogRule{
+	{Name: "oom", Pattern: regexp.MustCompile(`Out of memory|oom-kill`), Severity: "CRITICAL"},
+	{Name: "io-error", Pattern: regexp.MustCompile(`I/O error`), Severity: "CRITICAL"},
+	{Name: "segfault", Pattern: regexp.MustCompile(`segfault`), Severity: "WARNING"},
+}
+
+func TestParseJournal(t *testing.T) {
+	lines, cursor := ParseJournal(fixture(t, "journalctl.txt"))
+	if len(lines) != 3 || !strings.HasPrefix(cursor, "s=2b1e6d9a") {
+		t.Fatalf("ParseJournal = %d 行, cursor %q", len(lines), cursor)
+	}
+
+	matches := make([]protocol.LogMatch, len(testLogRules))
+	MatchLogLines(matches, testLogRules, "kernel", lines, 2)
+	for i, m := range matches {
+		if m.Count != 1 || len(m.Samples) != 1 || !strings.HasPrefix(m.Samples[0], "[kernel] ") {
+			t.Errorf("%s: %+v", testLogRules[i].Name, m)
+		}
+	}
+}
+
+func TestLogTailerFile(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "state", "logs.json")
+	write := func(content string, flag int) {
+		t.Helper()
+		f, err := os.OpenFile(logFile, flag|os.O_WRONLY|os.O_CREATE, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sources := []LogSource{{Name: "app", File: logFile}}
+	collect := func(tailer *LogTailer) []protocol.LogMatch {
+		t.Helper()
+		matches, err := tailer.Collect(context.Background(), state, sources, testLogRules, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	write("old: Out of memory: Killed process 1\n", os.O_TRUNC)
+	tailer := NewLogTailer()
+	if m := collect(tailer); m[0].Count != 0 {
+		t.Fatalf("首次采集不应回溯已有日志: %+v", m)
+	}
+
+	write("a: segfault at 0\nb: Out of memory: Killed process 2\nc: segfault at 1\nd: segfault at 2\ne: partial I/O err", os.O_APPEND)
+	m := collect(tailer)
+	if m[0].Count != 1 || m[1].Count != 0 || m[2].Count != 3 {
+		t.Fatalf("计数错误: %+v", m)
+	}
+	if len(m[2].Samples) != 2 || m[2].Samples[1] != "[app] d: segfault at 2" {
+		t.Errorf("应保留最近的 2 条样例: %v", m[2].Samples)
+	}
+
+	// 不完整的行在补全后计入，重启后从保存的位置继续
+	write("or\n", os.O_APPEND)
+	if m := collect(NewLogTailer()); m[1].Count != 1 || m[0].Count != 0 {
+		t.Fatalf("应从保存的位置继续: %+v", m)
+	}
+
+	// 轮转后从新文件开头读取
+	write("new: oom-kill event\n", os.O_TRUNC)
+	if m := collect(tailer); m[0].Count != 1 {
+		t.Fatalf("轮转后应从头读取: %+v", m)
+	}
+}
@@ -0,0 +1,184 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// 网络统计来源
+const (
+	NetDevPath         = "/proc/net/dev"
+	ConntrackCountPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	ConntrackMaxPath   = "/proc/sys/net/netfilter/nf_conntrack_max"
+)
+
+// TCPStatePaths IPv4 与 IPv6 的 TCP 套接字表
+var TCPStatePaths = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// tcpStates /proc/net/tcp 中 st 列的取值
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED", "02": "SYN_SENT", "03": "SYN_RECV", "04": "FIN_WAIT1",
+	"05": "FIN_WAIT2", "06": "TIME_WAIT", "07": "CLOSE", "08": "CLOSE_WAIT",
+	"09": "LAST_ACK", "0A": "LISTEN", "0B": "CLOSING",
+}
+
+// NetworkStats 网络统计结果
+type NetworkStats struct {
+	Interfaces     []protocol.Interface
+	TCPStates      map[string]int
+	TCPConnections int
+	ListenPorts    []int
+	ConntrackCount int
+	ConntrackMax   int
+}
+
+// Network 采集网卡流量、TCP 连接状态与连接跟踪表，sample 为计算网卡速率的采样间隔
+func Network(ctx context.Context, sample time.Duration) (*NetworkStats, error) {
+	first, err := os.ReadFile(NetDevPath)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(sample):
+	}
+	second, err := os.ReadFile(NetDevPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &NetworkStats{
+		Interfaces: InterfaceRates(ParseNetDev(first), ParseNetDev(second), time.Since(start)),
+		TCPStates:  make(map[string]int),
+	}
+
+	ports := make(map[int]bool)
+	for _, path := range TCPStatePaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		states, listen := ParseProcNetTCP(data)
+		for state, n := range states {
+			stats.TCPStates[state] += n
+		}
+		for _, port := range listen {
+			ports[port] = true
+		}
+	}
+	for state, n := range stats.TCPStates {
+		if state != "LISTEN" {
+			stats.TCPConnections += n
+		}
+	}
+	for port := range ports {
+		stats.ListenPorts = append(stats.ListenPorts, port)
+	}
+	sort.Ints(stats.ListenPorts)
+
+	stats.ConntrackCount = readInt(ConntrackCountPath)
+	stats.ConntrackMax = readInt(ConntrackMaxPath)
+	return stats, nil
+}
+
+// ConntrackUsed 连接跟踪表使用率（%）
+func (s *NetworkStats) ConntrackUsed() float64 {
+	return percent(uint64(s.ConntrackCount), uint64(s.ConntrackMax))
+}
+
+// readInt 读取只包含一个整数的文件，失败时返回 0
+func readInt(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n
+}
+
+// ParseNetDev 解析 /proc/net/dev 的累计计数，忽略 lo
+func ParseNetDev(data []byte) []protocol.Interface {
+	var ifaces []protocol.Interface
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "lo" {
+			continue
+		}
+		// 接收：bytes packets errs drop fifo frame compressed multicast；发送：bytes packets errs drop ...
+		f := strings.Fields(counters)
+		if len(f) < 12 {
+			continue
+		}
+		n := make([]uint64, 12)
+		for i := range n {
+			n[i], _ = strconv.ParseUint(f[i], 10, 64)
+		}
+		ifaces = append(ifaces, protocol.Interface{
+			Name:    name,
+			RxBytes: n[0], RxErrors: n[2], RxDropped: n[3],
+			TxBytes: n[8], TxErrors: n[10], TxDropped: n[11],
+		})
+	}
+	return ifaces
+}
+
+// InterfaceRates 以两次采样计算收发速率，返回第二次采样的计数
+func InterfaceRates(before, after []protocol.Interface, elapsed time.Duration) []protocol.Interface {
+	prev := make(map[string]protocol.Interface, len(before))
+	for _, i := range before {
+		prev[i.Name] = i
+	}
+	seconds := elapsed.Seconds()
+	for idx, i := range after {
+		p, ok := prev[i.Name]
+		// 计数器回绕或网卡重置时不计算速率
+		if !ok || seconds <= 0 || i.RxBytes < p.RxBytes || i.TxBytes < p.TxBytes {
+			continue
+		}
+		after[idx].RxRate = math.Round(float64(i.RxBytes-p.RxBytes)/seconds*100) / 100
+		after[idx].TxRate = math.Round(float64(i.TxBytes-p.TxBytes)/seconds*100) / 100
+	}
+	return after
+}
+
+// ParseProcNetTCP 解析 /proc/net/tcp 或 /proc/net/tcp6，返回各状态的连接数与监听端口
+func ParseProcNetTCP(data []byte) (map[string]int, []int) {
+	states := make(map[string]int)
+	var listen []int
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for first := true; scanner.Scan(); first = false {
+		// sl local_address rem_address st ...
+		f := strings.Fields(scanner.Text())
+		if first || len(f) < 4 {
+			continue
+		}
+		state, ok := tcpStates[strings.ToUpper(f[3])]
+		if !ok {
+			continue
+		}
+		states[state]++
+
+		if state == "LISTEN" {
+			if i := strings.LastIndex(f[1], ":"); i >= 0 {
+				if port, err := strconv.ParseUint(f[1][i+1:], 16, 16); err == nil {
+					listen = append(listen, int(port))
+				}
+			}
+		}
+	}
+	return states, listen
+}
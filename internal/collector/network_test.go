@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNetDev(t *testing.T) {
+	ifaces := ParseNetDev(fixture(t, "net-dev.txt"))
+	if len(ifaces) != 2 || ifaces[0].Name != "eth0" {
+		t.Fatalf("应忽略 lo: %+v", ifaces)
+	}
+	eth0 := ifaces[0]
+	if eth0.RxBytes != 8823415210 || eth0.RxErrors != 12 || eth0.RxDropped != 340 || eth0.TxBytes != 1293847221 || eth0.TxDropped != 7 {
+		t.Errorf("eth0 计数解析错误: %+v", eth0)
+	}
+
+	later := ParseNetDev(fixture(t, "net-dev.txt"))
+	later[0].RxBytes += 2000
+	later[0].TxBytes += 500
+	rates := InterfaceRates(ifaces, later, 2*time.Second)
+	if rates[0].RxRate != 1000 || rates[0].TxRate != 250 || rates[1].RxRate != 0 {
+		t.Errorf("速率计算错误: %+v", rates)
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	states, listen := ParseProcNetTCP(fixture(t, "proc-net-tcp.txt"))
+	want := map[string]int{"LISTEN": 2, "ESTABLISHED": 2, "TIME_WAIT": 1, "CLOSE_WAIT": 1}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+	if !reflect.DeepEqual(listen, []int{22, 3306}) {
+		t.Errorf("listen = %v", listen)
+	}
+
+	states, listen = ParseProcNetTCP(fixture(t, "proc-net-tcp6.txt"))
+	if states["LISTEN"] != 2 || states["TIME_WAIT"] != 1 || !reflect.DeepEqual(listen, []int{80, 22}) {
+		t.Errorf("tcp6 解析错误: %v %v", states, listen)
+	}
+}
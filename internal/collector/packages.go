@@ -0,0 +1,264 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// dnfUpdatesAvailable dnf/yum check-update 有可用更新时的退出码
+const dnfUpdatesAvailable = 100
+
+// PackageCollector 采集软件包并缓存结果，查询软件包数据库较慢，不必每次采集都执行
+type PackageCollector struct {
+	mu   sync.Mutex
+	last *protocol.Packages
+	at   time.Time
+}
+
+// NewPackageCollector 创建软件包采集器
+func NewPackageCollector() *PackageCollector {
+	return &PackageCollector{}
+}
+
+// Run 返回软件包采集结果，上次采集距今不足 maxAge 时直接返回缓存；查询更新失败时仍返回已安装的软件包
+func (p *PackageCollector) Run(ctx context.Context, maxAge time.Duration) (*protocol.Packages, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last != nil && time.Since(p.at) < maxAge {
+		return p.last, nil
+	}
+	pkgs, err := CollectPackages(ctx)
+	if pkgs != nil && err == nil {
+		p.last, p.at = pkgs, time.Now()
+	}
+	return pkgs, err
+}
+
+// CollectPackages 查询 dpkg 或 rpm 数据库中已安装的软件包，并从本地软件源缓存查询可用更新
+func CollectPackages(ctx context.Context) (*protocol.Packages, error) {
+	pkgs := &protocol.Packages{}
+	if data, err := os.ReadFile("/etc/os-release"); err == nil {
+		pkgs.Distro, pkgs.Release = ParseOSRelease(data)
+	}
+
+	var updates map[string]protocol.Package
+	var errs []error
+	if _, err := exec.LookPath("dpkg-query"); err == nil {
+		pkgs.Manager = protocol.PackageDpkg
+		out, err := exec.CommandContext(ctx, "dpkg-query", "-W",
+			"-f", `${db:Status-Abbrev}\t${Package}\t${Version}\t${Architecture}\t${source:Package}\n`).Output()
+		if err != nil {
+			return nil, fmt.Errorf("dpkg-query 执行失败: %w", err)
+		}
+		pkgs.List = ParseDpkgQuery(out)
+		// apt list 只读取本地缓存，不会刷新软件源
+		if out, err := exec.CommandContext(ctx, "apt", "list", "--upgradable").Output(); err == nil {
+			updates = ParseAptUpgradable(out)
+		} else {
+			errs = append(errs, fmt.Errorf("apt list 执行失败: %w", err))
+		}
+	} else if _, err := exec.LookPath("rpm"); err == nil {
+		pkgs.Manager = protocol.PackageRPM
+		out, err := exec.CommandContext(ctx, "rpm", "-qa",
+			"--qf", `%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\n`).Output()
+		if err != nil {
+			return nil, fmt.Errorf("rpm 执行失败: %w", err)
+		}
+		pkgs.List = ParseRPMQuery(out)
+		updates, err = rpmUpdates(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		return nil, fmt.Errorf("未找到 dpkg 或 rpm")
+	}
+
+	ApplyUpdates(pkgs, updates)
+	return pkgs, errors.Join(errs...)
+}
+
+// rpmUpdates 使用 dnf（或 yum）的本地缓存（-C）查询可用更新与安全更新
+func rpmUpdates(ctx context.Context) (map[string]protocol.Package, error) {
+	tool := "dnf"
+	if _, err := exec.LookPath(tool); err != nil {
+		tool = "yum"
+	}
+	out, err := exec.CommandContext(ctx, tool, "-q", "-C", "check-update").Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == dnfUpdatesAvailable) {
+		return nil, fmt.Errorf("%s check-update 执行失败: %w", tool, err)
+	}
+	updates := ParseDnfCheckUpdate(out)
+
+	out, err = exec.CommandContext(ctx, tool, "-q", "-C", "updateinfo", "list", "--security").Output()
+	if err != nil {
+		return updates, fmt.Errorf("%s updateinfo 执行失败: %w", tool, err)
+	}
+	for name := range ParseDnfSecurity(out) {
+		if u, ok := updates[name]; ok {
+			u.Security = true
+			updates[name] = u
+		}
+	}
+	return updates, nil
+}
+
+// ApplyUpdates 将可用更新合并到已安装的软件包并统计数量
+func ApplyUpdates(pkgs *protocol.Packages, updates map[string]protocol.Package) {
+	pkgs.Installed, pkgs.Updates, pkgs.Security = len(pkgs.List), 0, 0
+	for i := range pkgs.List {
+		p := &pkgs.List[i]
+		u, ok := updates[p.Name]
+		if !ok {
+			continue
+		}
+		p.Update, p.Security = u.Update, u.Security
+		pkgs.Updates++
+		if p.Security {
+			pkgs.Security++
+		}
+	}
+}
+
+// ParseOSRelease 解析 /etc/os-release 的 ID 与 VERSION_ID
+func ParseOSRelease(data []byte) (id, version string) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			id = value
+		case "VERSION_ID":
+			version = value
+		}
+	}
+	return id, version
+}
+
+// ParseDpkgQuery 解析 dpkg-query -W 的输出，只保留已安装（ii）的软件包
+func ParseDpkgQuery(out []byte) []protocol.Package {
+	var pkgs []protocol.Package
+	for _, line := range splitLines(out) {
+		f := strings.Split(line, "\t")
+		if len(f) < 4 || strings.TrimSpace(f[0]) != "ii" {
+			continue
+		}
+		p := protocol.Package{Name: f[1], Version: f[2], Arch: f[3]}
+		if len(f) > 4 && f[4] != p.Name {
+			p.Source = f[4]
+		}
+		pkgs = append(pkgs, p)
+	}
+	sortPackages(pkgs)
+	return pkgs
+}
+
+// ParseAptUpgradable 解析 apt list --upgradable 的输出，如
+// openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]
+func ParseAptUpgradable(out []byte) map[string]protocol.Package {
+	updates := make(map[string]protocol.Package)
+	for _, line := range splitLines(out) {
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			// "Listing..." 等提示
+			continue
+		}
+		name, suites, ok := strings.Cut(f[0], "/")
+		if !ok {
+			continue
+		}
+		updates[name] = protocol.Package{Name: name, Update: f[1], Arch: f[2], Security: strings.Contains(suites, "-security")}
+	}
+	return updates
+}
+
+// ParseRPMQuery 解析 rpm -qa --qf 的输出，忽略 gpg-pubkey 等非软件包条目
+func ParseRPMQuery(out []byte) []protocol.Package {
+	var pkgs []protocol.Package
+	for _, line := range splitLines(out) {
+		f := strings.Split(line, "\t")
+		if len(f) < 3 || f[2] == "(none)" {
+			continue
+		}
+		pkgs = append(pkgs, protocol.Package{Name: f[0], Version: f[1], Arch: f[2]})
+	}
+	sortPackages(pkgs)
+	return pkgs
+}
+
+// ParseDnfCheckUpdate 解析 dnf/yum check-update 的输出，如 openssl.x86_64  1:3.0.7-25.el9_3  baseos
+func ParseDnfCheckUpdate(out []byte) map[string]protocol.Package {
+	updates := make(map[string]protocol.Package)
+	for _, line := range splitLines(out) {
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		f := strings.Fields(line)
+		if len(f) != 3 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		dot := strings.LastIndexByte(f[0], '.')
+		if dot <= 0 {
+			continue
+		}
+		name := f[0][:dot]
+		updates[name] = protocol.Package{Name: name, Arch: f[0][dot+1:], Update: f[1]}
+	}
+	return updates
+}
+
+// ParseDnfSecurity 解析 updateinfo list --security 的输出，返回有安全更新的软件包名，如
+// ALSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9_3.x86_64
+func ParseDnfSecurity(out []byte) map[string]bool {
+	names := make(map[string]bool)
+	for _, line := range splitLines(out) {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			continue
+		}
+		if name := nevraName(f[2]); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// nevraName 从 name-[epoch:]version-release.arch 中取出包名
+func nevraName(nevra string) string {
+	if dot := strings.LastIndexByte(nevra, '.'); dot > 0 {
+		nevra = nevra[:dot]
+	}
+	for i := 0; i < 2; i++ {
+		dash := strings.LastIndexByte(nevra, '-')
+		if dash <= 0 {
+			return ""
+		}
+		nevra = nevra[:dash]
+	}
+	return nevra
+}
+
+// sortPackages 按包名与架构排序
+func sortPackages(pkgs []protocol.Package) {
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Name != pkgs[j].Name {
+			return pkgs[i].Name < pkgs[j].Name
+		}
+		return pkgs[i].Arch < pkgs[j].Arch
+	})
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"testing"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestParseDpkgPackages(t *testing.T) {
+	pkgs := &protocol.Packages{List: ParseDpkgQuery(fixture(t, "dpkg-query.txt"))}
+	ApplyUpdates(pkgs, ParseAptUpgradable(fixture(t, "apt-upgradable.txt")))
+	if pkgs.Installed != 4 || pkgs.Updates != 3 || pkgs.Security != 2 {
+		t.Fatalf("计数错误: installed=%d updates=%d security=%d", pkgs.Installed, pkgs.Updates, pkgs.Security)
+	}
+	bash, libc, libssl := pkgs.List[0], pkgs.List[1], pkgs.List[2]
+	if bash.Name != "bash" || bash.Source != "" || bash.Update != "" {
+		t.Errorf("bash: %+v", bash)
+	}
+	if libc.Source != "glibc" || libc.Update != "2.36-9+deb12u4" || libc.Security {
+		t.Errorf("libc6: %+v", libc)
+	}
+	if libssl.Source != "openssl" || libssl.Version != "3.0.11-1~deb12u2" || !libssl.Security {
+		t.Errorf("libssl3: %+v", libssl)
+	}
+}
+
+func TestParseRPMUpdates(t *testing.T) {
+	pkgs := &protocol.Packages{List: ParseRPMQuery([]byte(
+		"openssl\t1:3.0.7-24.el9\tx86_64\nkernel\t5.14.0-362.8.1.el9_3\tx86_64\ngpg-pubkey\t350d275d-6279464b\t(none)\nbash\t5.1.8-6.el9_1\tx86_64\n"))}
+	updates := ParseDnfCheckUpdate(fixture(t, "dnf-check-update.txt"))
+	if len(updates) != 4 || updates["openssl"].Update != "1:3.0.7-25.el9_3" {
+		t.Fatalf("应忽略 Obsoleting 之后的内容: %+v", updates)
+	}
+	for name := range ParseDnfSecurity(fixture(t, "dnf-updateinfo.txt")) {
+		if u, ok := updates[name]; ok {
+			u.Security = true
+			updates[name] = u
+		}
+	}
+	ApplyUpdates(pkgs, updates)
+	if pkgs.Installed != 3 || pkgs.Updates != 2 || pkgs.Security != 2 {
+		t.Fatalf("计数错误: %+v", pkgs)
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	id, version := ParseOSRelease([]byte("NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n"))
+	if id != "rocky" || version != "9.3" {
+		t.Errorf("ParseOSRelease = %s %s", id, version)
+	}
+}
@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// maxProbeBody HTTP 探测读取的最大响应长度
+const maxProbeBody = 64 * 1024
+
+// ProbeSpec 探测配置
+type ProbeSpec struct {
+	Name         string
+	Type         string
+	Target       string        // icmp/dns 为主机名，tcp 为 host:port，http 为 URL
+	Server       string        // dns 使用的服务器，为空时使用系统配置
+	ExpectStatus int           // http 期望的状态码，0 表示 2xx 与 3xx
+	ExpectBody   string        // http 响应需要包含的内容
+	Insecure     bool          // http 跳过证书校验
+	Timeout      time.Duration // 单次探测超时
+}
+
+// Probes 并发执行探测，结果顺序与配置一致
+func Probes(ctx context.Context, specs []ProbeSpec) []protocol.Probe {
+	results := make([]protocol.Probe, len(specs))
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec ProbeSpec) {
+			defer wg.Done()
+			results[i] = RunProbe(ctx, spec)
+		}(i, spec)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunProbe 执行单个探测
+func RunProbe(ctx context.Context, spec ProbeSpec) protocol.Probe {
+	result := protocol.Probe{Name: spec.Name, Type: spec.Type, Target: spec.Target}
+	ctx, cancel := context.WithTimeout(ctx, spec.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	switch spec.Type {
+	case protocol.ProbeTCP:
+		err = probeTCP(ctx, spec)
+	case protocol.ProbeHTTP:
+		err = probeHTTP(ctx, spec)
+	case protocol.ProbeDNS:
+		err = probeDNS(ctx, spec)
+	case protocol.ProbeICMP:
+		result.Latency, result.Loss, err = probeICMP(ctx, spec)
+	default:
+		err = fmt.Errorf("不支持的探测类型: %s", spec.Type)
+	}
+	if spec.Type != protocol.ProbeICMP {
+		result.Latency = milliseconds(time.Since(start))
+	}
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// milliseconds 耗时换算为毫秒，保留两位小数
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+// probeTCP 建立 TCP 连接后立即关闭
+func probeTCP(ctx context.Context, spec ProbeSpec) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", spec.Target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeHTTP 发送 GET 请求并校验状态码与响应内容，不跟随重定向
+func probeHTTP(ctx context.Context, spec ProbeSpec) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.Target, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: spec.Insecure},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if spec.ExpectStatus != 0 && resp.StatusCode != spec.ExpectStatus {
+		return fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, spec.ExpectStatus)
+	}
+	if spec.ExpectStatus == 0 && resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	if spec.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(body), spec.ExpectBody) {
+			return fmt.Errorf("响应不包含 %q", spec.ExpectBody)
+		}
+	}
+	return nil
+}
+
+// probeDNS 解析域名，Server 不为空时只向该服务器查询
+func probeDNS(ctx context.Context, spec ProbeSpec) error {
+	resolver := net.DefaultResolver
+	if spec.Server != "" {
+		server := spec.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	addrs, err := resolver.LookupHost(ctx, spec.Target)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%s 没有解析结果", spec.Target)
+	}
+	return nil
+}
+
+// probeICMP 调用 ping 发送 3 个探测包，返回平均往返时间与丢包率
+func probeICMP(ctx context.Context, spec ProbeSpec) (float64, float64, error) {
+	wait := int(spec.Timeout / time.Second / 3)
+	if wait < 1 {
+		wait = 1
+	}
+	// ping 在全部丢包时以非零退出，输出中仍有统计信息
+	out, _ := exec.CommandContext(ctx, "ping", "-c", "3", "-i", "0.2", "-W", strconv.Itoa(wait), "-q", spec.Target).CombinedOutput()
+	return ParsePing(out)
+}
+
+var (
+	pingLoss = regexp.MustCompile(`([\d.]+)% packet loss`)
+	pingRTT  = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+)
+
+// ParsePing 解析 ping -q 的统计输出，返回平均往返时间（毫秒）与丢包率
+func ParsePing(out []byte) (float64, float64, error) {
+	m := pingLoss.FindSubmatch(out)
+	if m == nil {
+		return 0, 100, fmt.Errorf("ping 失败: %s", strings.TrimSpace(string(out)))
+	}
+	loss, _ := strconv.ParseFloat(string(m[1]), 64)
+	if loss >= 100 {
+		return 0, loss, fmt.Errorf("全部丢包")
+	}
+	var rtt float64
+	if m := pingRTT.FindSubmatch(out); m != nil {
+		rtt, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+	return rtt, loss, nil
+}
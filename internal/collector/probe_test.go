@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestParsePing(t *testing.T) {
+	rtt, loss, err := ParsePing(fixture(t, "ping.txt"))
+	if err != nil || rtt != 0.538 || loss != 33.3333 {
+		t.Errorf("ParsePing = %v, %v, %v", rtt, loss, err)
+	}
+	if _, loss, err := ParsePing(fixture(t, "ping-unreachable.txt")); err == nil || loss != 100 {
+		t.Errorf("全部丢包应返回错误: %v, %v", loss, err)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"status":"ok"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	specs := []ProbeSpec{
+		{Name: "api", Type: protocol.ProbeHTTP, Target: srv.URL + "/health", ExpectBody: `"ok"`},
+		{Name: "api-body", Type: protocol.ProbeHTTP, Target: srv.URL + "/health", ExpectBody: "healthy"},
+		{Name: "missing", Type: protocol.ProbeHTTP, Target: srv.URL + "/missing"},
+		{Name: "missing-404", Type: protocol.ProbeHTTP, Target: srv.URL + "/missing", ExpectStatus: 404},
+		{Name: "web", Type: protocol.ProbeTCP, Target: srv.Listener.Addr().String()},
+		{Name: "db", Type: protocol.ProbeTCP, Target: closed},
+	}
+	for i := range specs {
+		specs[i].Timeout = 2 * time.Second
+	}
+	want := []bool{true, false, false, true, true, false}
+
+	results := Probes(context.Background(), specs)
+	for i, r := range results {
+		if r.Name != specs[i].Name || r.Success != want[i] {
+			t.Errorf("%s: success = %v, want %v (%s)", specs[i].Name, r.Success, want[i], r.Error)
+		}
+		if !r.Success && r.Error == "" {
+			t.Errorf("%s: 失败时应记录原因", r.Name)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// 上报字段的最大长度（字符），与 Master 的列长度一致
+const (
+	maxCommandLen = 256
+	maxNameLen    = 128
+)
+
+// ProcPath 进程信息目录，用于两次采样进程 CPU 时间
+var ProcPath = "/proc"
+
+// clockTicks /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ），Linux 上固定为 100
+const clockTicks = 100
+
+// psFormat ps 输出列，命令行可能包含空格，放在最后
+const psFormat = "pid=,stat=,nlwp=,user:32=,pcpu=,pmem=,rss=,args="
+
+// ProcessStats 进程统计结果
+type ProcessStats struct {
+	Count     int
+	Threads   int
+	Zombies   int
+	TopCPU    []protocol.Process
+	TopMemory []protocol.Process
+	Watches   []protocol.ProcessWatch
+}
+
+// Processes 通过 ps 采集进程统计，top 为 CPU 与内存排行的进程数。
+// ps 的 pcpu 是进程整个生命周期的平均值，长期运行的进程突发占用会被摊薄；
+// sample 大于 0 且 /proc 可读时间隔 sample 两次读取进程 CPU 时间，以采样期间的占用率替代 pcpu
+func Processes(ctx context.Context, sample time.Duration, top int, watches []protocol.ProcessWatch) (*ProcessStats, error) {
+	var first, second map[int]uint64
+	start := time.Now()
+	if sample > 0 {
+		first = procCPUTimes(ProcPath)
+	}
+	if first != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sample):
+		}
+		second = procCPUTimes(ProcPath)
+	}
+	elapsed := time.Since(start)
+
+	out, err := exec.CommandContext(ctx, "ps", "-eo", psFormat).Output()
+	if err != nil {
+		return nil, err
+	}
+	entries := ParsePS(out)
+	if second != nil {
+		applyCPUSample(entries, first, second, elapsed)
+	}
+	return SummarizeProcesses(entries, top, watches), nil
+}
+
+// procCPUTimes 读取各进程累计的用户态与内核态 CPU 时间（单位 clockTicks），/proc 不可读时返回 nil
+func procCPUTimes(root string) map[int]uint64 {
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	times := make(map[int]uint64, len(dirs))
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		// 进程可能已退出
+		data, err := os.ReadFile(filepath.Join(root, d.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		if t, ok := parseProcStat(data); ok {
+			times[pid] = t
+		}
+	}
+	return times
+}
+
+// parseProcStat 解析 /proc/<pid>/stat 的 utime 与 stime（第 14、15 列），进程名可能包含空格与括号，从最后一个 ")" 之后开始计数
+func parseProcStat(data []byte) (uint64, bool) {
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 13 {
+		return 0, false
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return utime + stime, true
+}
+
+// applyCPUSample 按两次采样间的 CPU 时间计算占用率（多线程进程可超过 100%），
+// 采样期间新启动的进程从 0 开始计算，第二次采样后才出现的进程保留 ps 的结果
+func applyCPUSample(entries []psEntry, first, second map[int]uint64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	for i := range entries {
+		after, ok := second[entries[i].PID]
+		if !ok {
+			continue
+		}
+		before := first[entries[i].PID]
+		if after < before {
+			continue
+		}
+		cpu := float64(after-before) / clockTicks / elapsed.Seconds() * 100
+		entries[i].CPU = math.Round(cpu*10) / 10
+	}
+}
+
+// psEntry ps 输出的一行
+type psEntry struct {
+	protocol.Process
+	Stat string
+}
+
+// ParsePS 解析 ps -eo pid=,stat=,nlwp=,user=,pcpu=,pmem=,rss=,args= 的输出
+func ParsePS(out []byte) []psEntry {
+	var entries []psEntry
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		e := psEntry{Stat: fields[1]}
+		e.PID = pid
+		e.Threads, _ = strconv.Atoi(fields[2])
+		e.User = fields[3]
+		e.CPU, _ = strconv.ParseFloat(fields[4], 64)
+		e.Memory, _ = strconv.ParseFloat(fields[5], 64)
+		rss, _ := strconv.ParseUint(fields[6], 10, 64)
+		e.RSS = rss * 1024
+		e.Command = strings.Join(fields[7:], " ")
+		e.Name = processName(fields[7])
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// processName 由命令行第一段得到进程名，内核线程保留方括号形式
+func processName(arg0 string) string {
+	if strings.HasPrefix(arg0, "[") {
+		return arg0
+	}
+	return filepath.Base(arg0)
+}
+
+// SummarizeProcesses 统计进程数、线程数、僵尸进程、资源排行与进程守护结果
+func SummarizeProcesses(entries []psEntry, top int, watches []protocol.ProcessWatch) *ProcessStats {
+	stats := &ProcessStats{Count: len(entries)}
+	procs := make([]protocol.Process, 0, len(entries))
+	for _, e := range entries {
+		stats.Threads += e.Threads
+		if strings.HasPrefix(e.Stat, "Z") {
+			stats.Zombies++
+		}
+		p := e.Process
+		p.Name = truncate(p.Name, maxNameLen)
+		p.Command = truncate(p.Command, maxCommandLen)
+		procs = append(procs, p)
+	}
+
+	stats.TopCPU = topProcesses(procs, top, func(a, b protocol.Process) bool { return a.CPU > b.CPU })
+	stats.TopMemory = topProcesses(procs, top, func(a, b protocol.Process) bool { return a.RSS > b.RSS })
+
+	for _, w := range watches {
+		// 配置加载时已校验正则，这里编译失败视为没有匹配
+		re, err := regexp.Compile(w.Pattern)
+		w.Count = 0
+		if err == nil {
+			for _, e := range entries {
+				if re.MatchString(e.Command) {
+					w.Count++
+				}
+			}
+		}
+		stats.Watches = append(stats.Watches, w)
+	}
+	return stats
+}
+
+// topProcesses 按 less 排序取前 n 个进程
+func topProcesses(procs []protocol.Process, n int, less func(a, b protocol.Process) bool) []protocol.Process {
+	if n <= 0 {
+		return nil
+	}
+	sorted := append([]protocol.Process(nil), procs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// truncate 按字符截断，避免截断多字节字符
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
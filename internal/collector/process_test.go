@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestSummarizeProcesses(t *testing.T) {
+	watches := []protocol.ProcessWatch{
+		{Name: "nginx-master", Pattern: `^nginx: master`, Min: 1, Max: 1},
+		{Name: "nginx-worker", Pattern: `^nginx: worker`, Min: 4},
+		{Name: "sshd", Pattern: `sshd`, Min: 1},
+	}
+	stats := SummarizeProcesses(ParsePS(fixture(t, "ps.txt")), 2, watches)
+
+	if stats.Count != 9 || stats.Threads != 147 || stats.Zombies != 2 {
+		t.Errorf("进程统计错误: count=%d threads=%d zombies=%d", stats.Count, stats.Threads, stats.Zombies)
+	}
+	if len(stats.TopCPU) != 2 || stats.TopCPU[0].Name != "mysqld" || stats.TopCPU[1].Name != "java" {
+		t.Errorf("CPU 排行错误: %+v", stats.TopCPU)
+	}
+	if top := stats.TopMemory[0]; top.Name != "java" || top.PID != 2301 || top.RSS != 6815744*1024 || top.User != "app" {
+		t.Errorf("内存排行错误: %+v", top)
+	}
+
+	for i, want := range []struct {
+		count int
+		ok    bool
+	}{{1, true}, {2, false}, {0, false}} {
+		if w := stats.Watches[i]; w.Count != want.count || w.OK() != want.ok {
+			t.Errorf("进程守护 %s: count=%d ok=%v，期望 %d %v", w.Name, w.Count, w.OK(), want.count, want.ok)
+		}
+	}
+}
+
+func TestProcessCPUSample(t *testing.T) {
+	first := procCPUTimes("testdata/proc")
+	if len(first) != 2 || first[1200] != 93245 || first[2301] != 6000 {
+		t.Fatalf("CPU 时间解析错误: %v", first)
+	}
+
+	// 一秒内 mysqld 使用 1.5 个核，java 几乎空闲，nginx worker 在采样期间启动
+	second := map[int]uint64{1200: 93245 + 150, 2301: 6000 + 5, 814: 20}
+	entries := ParsePS(fixture(t, "ps.txt"))
+	applyCPUSample(entries, first, second, time.Second)
+
+	stats := SummarizeProcesses(entries, 3, nil)
+	want := []struct {
+		name string
+		cpu  float64
+	}{{"mysqld", 150}, {"nginx:", 20}, {"java", 5}}
+	for i, w := range want {
+		if p := stats.TopCPU[i]; p.Name != w.name || p.CPU != w.cpu {
+			t.Errorf("CPU 排行第 %d 位 = %s %.1f，期望 %s %.1f", i+1, p.Name, p.CPU, w.name, w.cpu)
+		}
+	}
+}
+
+func TestSummarizeProcessesTruncate(t *testing.T) {
+	long := strings.Repeat("进程", maxCommandLen)
+	entries := []psEntry{{Process: protocol.Process{PID: 1, Name: long, Command: long}}}
+	p := SummarizeProcesses(entries, 1, nil).TopCPU[0]
+	if utf8.RuneCountInString(p.Name) != maxNameLen || utf8.RuneCountInString(p.Command) != maxCommandLen {
+		t.Errorf("截断长度错误: name=%d command=%d", utf8.RuneCountInString(p.Name), utf8.RuneCountInString(p.Command))
+	}
+	if !utf8.ValidString(p.Name) || !utf8.ValidString(p.Command) {
+		t.Error("截断后不应出现残缺的多字节字符")
+	}
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// unitProperties systemctl show 读取的单元属性
+const unitProperties = "Id,LoadState,ActiveState,SubState,NRestarts,ActiveEnterTimestampMonotonic"
+
+// Services 采集关注的 systemd 单元状态与全部 failed 单元
+func Services(ctx context.Context, units []string) ([]protocol.Unit, []string, error) {
+	out, err := exec.CommandContext(ctx, "systemctl", "list-units", "--state=failed", "--no-legend", "--plain", "--no-pager").Output()
+	if err != nil {
+		return nil, nil, err
+	}
+	failed := ParseFailedUnits(out)
+	if len(units) == 0 {
+		return nil, failed, nil
+	}
+
+	args := append([]string{"show", "--property=" + unitProperties, "--"}, units...)
+	out, err = exec.CommandContext(ctx, "systemctl", args...).Output()
+	if err != nil {
+		return nil, failed, err
+	}
+	return ParseSystemctlShow(out, uptime()), failed, nil
+}
+
+// uptime 系统启动后经过的秒数，与 ActiveEnterTimestampMonotonic 使用同一时钟
+func uptime() float64 {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(fields[0], 64)
+	return v
+}
+
+// ParseSystemctlShow 解析 systemctl show 的输出，多个单元之间以空行分隔；uptime 为系统启动后的秒数
+func ParseSystemctlShow(out []byte, uptime float64) []protocol.Unit {
+	var units []protocol.Unit
+	props := make(map[string]string)
+	flush := func() {
+		if props["Id"] == "" {
+			return
+		}
+		u := protocol.Unit{
+			Name:        strings.TrimSuffix(props["Id"], ".service"),
+			LoadState:   props["LoadState"],
+			ActiveState: props["ActiveState"],
+			SubState:    props["SubState"],
+		}
+		u.Restarts, _ = strconv.Atoi(props["NRestarts"])
+		if enter, _ := strconv.ParseInt(props["ActiveEnterTimestampMonotonic"], 10, 64); u.Running() && enter > 0 {
+			if since := int64(uptime - float64(enter)/1e6); since > 0 {
+				u.Uptime = since
+			}
+		}
+		units = append(units, u)
+		props = make(map[string]string)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[key] = value
+		}
+	}
+	flush()
+	return units
+}
+
+// ParseFailedUnits 解析 systemctl list-units --state=failed --no-legend --plain 的输出
+func ParseFailedUnits(out []byte) []string {
+	var units []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "●"))
+		if len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+	return units
+}
@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestParseSystemctlShow(t *testing.T) {
+	units := ParseSystemctlShow(fixture(t, "systemctl-show.txt"), 86400)
+	want := []protocol.Unit{
+		{Name: "nginx", LoadState: "loaded", ActiveState: "active", SubState: "running", Restarts: 2, Uptime: 400},
+		{Name: "mysqld", LoadState: "loaded", ActiveState: "failed", SubState: "failed", Restarts: 5},
+		{Name: "redis", LoadState: "not-found", ActiveState: "inactive", SubState: "dead"},
+	}
+	if !reflect.DeepEqual(units, want) {
+		t.Fatalf("ParseSystemctlShow =\n%+v\nwant\n%+v", units, want)
+	}
+}
+
+func TestParseFailedUnits(t *testing.T) {
+	failed := ParseFailedUnits(fixture(t, "systemctl-failed.txt"))
+	if want := []string{"mysqld.service", "logrotate.service"}; !reflect.DeepEqual(failed, want) {
+		t.Fatalf("ParseFailedUnits = %v, want %v", failed, want)
+	}
+}
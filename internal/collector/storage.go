@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// MDStatPath 软 RAID 状态文件
+const MDStatPath = "/proc/mdstat"
+
+// Storage 采集软 RAID 阵列与磁盘 SMART 状态；未安装 smartctl 或没有软 RAID 时对应结果为空
+func Storage(ctx context.Context) ([]protocol.DiskHealth, []protocol.MDArray, error) {
+	var arrays []protocol.MDArray
+	if data, err := os.ReadFile(MDStatPath); err == nil {
+		arrays = ParseMDStat(data)
+	}
+
+	if _, err := exec.LookPath("smartctl"); err != nil {
+		return nil, arrays, nil
+	}
+	devices, err := smartDevices(ctx)
+	if err != nil {
+		return nil, arrays, err
+	}
+
+	var disks []protocol.DiskHealth
+	for _, dev := range devices {
+		// smartctl 以退出码的位标记磁盘问题，非零退出时输出仍然有效
+		out, _ := exec.CommandContext(ctx, "smartctl", "--json", "-i", "-H", "-A", "-d", dev.Type, dev.Name).Output()
+		disk, err := ParseSmartctl(out)
+		if err != nil {
+			continue
+		}
+		disks = append(disks, disk)
+	}
+	return disks, arrays, nil
+}
+
+// smartDevice smartctl --scan 发现的设备
+type smartDevice struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// smartDevices 列出可读取 SMART 的设备
+func smartDevices(ctx context.Context) ([]smartDevice, error) {
+	out, err := exec.CommandContext(ctx, "smartctl", "--json", "--scan").Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("smartctl --scan 失败: %w", err)
+	}
+	var scan struct {
+		Devices []smartDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("smartctl --scan 输出解析失败: %w", err)
+	}
+	return scan.Devices, nil
+}
+
+// smartctlOutput smartctl --json 输出中用到的字段
+type smartctlOutput struct {
+	Device struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATAAttributes struct {
+		Table []struct {
+			ID    int `json:"id"`
+			Value int `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeLog *struct {
+		PercentageUsed float64 `json:"percentage_used"`
+		MediaErrors    int64   `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	Smartctl struct {
+		Messages []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+}
+
+// ATA SMART 属性
+const (
+	ataReallocated   = 5   // Reallocated_Sector_Ct
+	ataWearLeveling  = 177 // Wear_Leveling_Count，归一化值 100 为全新
+	ataPending       = 197 // Current_Pending_Sector
+	ataSSDLifeLeft   = 231 // SSD_Life_Left
+	ataMediaWearout  = 233 // Media_Wearout_Indicator
+	ataNormalizedMax = 100
+)
+
+// ParseSmartctl 解析 smartctl --json -i -H -A 的输出
+func ParseSmartctl(out []byte) (protocol.DiskHealth, error) {
+	var s smartctlOutput
+	if err := json.Unmarshal(out, &s); err != nil {
+		return protocol.DiskHealth{}, fmt.Errorf("smartctl 输出解析失败: %w", err)
+	}
+	if s.SmartStatus == nil {
+		msg := "未返回 SMART 状态"
+		if len(s.Smartctl.Messages) > 0 {
+			msg = s.Smartctl.Messages[0].String
+		}
+		return protocol.DiskHealth{}, fmt.Errorf("%s: %s", s.Device.Name, msg)
+	}
+
+	d := protocol.DiskHealth{
+		Device:       s.Device.Name,
+		Model:        s.ModelName,
+		Serial:       s.SerialNumber,
+		Protocol:     s.Device.Protocol,
+		Passed:       s.SmartStatus.Passed,
+		Temperature:  s.Temperature.Current,
+		PowerOnHours: s.PowerOnTime.Hours,
+	}
+	for _, attr := range s.ATAAttributes.Table {
+		switch attr.ID {
+		case ataReallocated:
+			d.ReallocatedSectors = attr.Raw.Value
+		case ataPending:
+			d.PendingSectors = attr.Raw.Value
+		case ataWearLeveling, ataSSDLifeLeft, ataMediaWearout:
+			if wear := float64(ataNormalizedMax - attr.Value); wear > d.Wear {
+				d.Wear = wear
+			}
+		}
+	}
+	if s.NVMeLog != nil {
+		d.Wear = s.NVMeLog.PercentageUsed
+		d.MediaErrors = s.NVMeLog.MediaErrors
+	}
+	return d, nil
+}
+
+var (
+	mdHeader = regexp.MustCompile(`^(md\S+) : (\S+)(.*)$`)
+	mdStatus = regexp.MustCompile(`\[(\d+)/(\d+)\] \[([U_]+)\]`)
+	mdSync   = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([\d.]+%)`)
+)
+
+// ParseMDStat 解析 /proc/mdstat
+func ParseMDStat(data []byte) []protocol.MDArray {
+	var arrays []protocol.MDArray
+	var cur *protocol.MDArray
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := mdHeader.FindStringSubmatch(line); m != nil {
+			arrays = append(arrays, protocol.MDArray{Name: m[1], State: m[2], Failed: []string{}})
+			cur = &arrays[len(arrays)-1]
+			parseMDMembers(cur, strings.Fields(m[3]))
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if m := mdStatus.FindStringSubmatch(line); m != nil {
+			cur.Devices, _ = strconv.Atoi(m[1])
+			cur.Active, _ = strconv.Atoi(m[2])
+			if cur.Active < cur.Devices && cur.State == "active" {
+				cur.State = "degraded"
+			}
+		}
+		if m := mdSync.FindStringSubmatch(line); m != nil {
+			cur.Sync = m[1] + " " + m[2]
+		}
+	}
+	return arrays
+}
+
+// parseMDMembers 解析阵列行中的级别与成员，如 "raid5 sdd1[3](F) sdc1[1]"
+func parseMDMembers(array *protocol.MDArray, fields []string) {
+	for _, f := range fields {
+		if strings.HasPrefix(f, "(") {
+			// "(auto-read-only)" 等状态说明
+			continue
+		}
+		i := strings.Index(f, "[")
+		if i < 0 {
+			array.Level = f
+			continue
+		}
+		if strings.HasSuffix(f, "(F)") {
+			array.Failed = append(array.Failed, f[:i])
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"cyber-inspector/internal/protocol"
+)
+
+func TestParseMDStat(t *testing.T) {
+	arrays := ParseMDStat(fixture(t, "mdstat.txt"))
+	want := []protocol.MDArray{
+		{Name: "md0", Level: "raid1", State: "active", Devices: 2, Active: 2, Failed: []string{}},
+		{Name: "md1", Level: "raid5", State: "degraded", Devices: 3, Active: 2, Failed: []string{"sdd1"}, Sync: "recovery 12.6%"},
+		{Name: "md2", State: "inactive", Failed: []string{}},
+	}
+	if !reflect.DeepEqual(arrays, want) {
+		t.Fatalf("ParseMDStat =\n%+v\nwant\n%+v", arrays, want)
+	}
+}
+
+func TestParseSmartctl(t *testing.T) {
+	sata, err := ParseSmartctl(fixture(t, "smartctl-sata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.DiskHealth{
+		Device: "/dev/sda", Model: "Samsung SSD 860 EVO 500GB", Serial: "S3Z1NB0K123456", Protocol: "ATA",
+		Passed: true, Temperature: 37, PowerOnHours: 35120, ReallocatedSectors: 8, PendingSectors: 2, Wear: 12,
+	}
+	if sata != want {
+		t.Errorf("SATA =\n%+v\nwant\n%+v", sata, want)
+	}
+
+	nvme, err := ParseSmartctl(fixture(t, "smartctl-nvme.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nvme.Passed || nvme.Temperature != 71 || nvme.Wear != 97 || nvme.MediaErrors != 3 {
+		t.Errorf("NVMe 解析错误: %+v", nvme)
+	}
+
+	if _, err := ParseSmartctl(fixture(t, "smartctl-unsupported.json")); err == nil {
+		t.Error("不支持 SMART 的设备应返回错误")
+	}
+}
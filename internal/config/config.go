@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,15 +15,18 @@ var Conf *Config
 
 // Config 总配置
 type Config struct {
-	App    AppConfig    `mapstructure:"app"`
-	Server ServerConfig `mapstructure:"server"`
-	MySQL  MySQLConfig  `mapstructure:"mysql"`
-	Check  CheckConfig  `mapstructure:"check"`
-	Alert  AlertConfig  `mapstructure:"alert"`
-	Mail   MailConfig   `mapstructure:"mail"`
-	LLM    LLMConfig    `mapstructure:"llm"`
-	Log    LogConfig    `mapstructure:"log"`
-	JWT    JWTConfig    `mapstructure:"jwt"` // <-- 新增
+	App      AppConfig      `mapstructure:"app"`
+	Server   ServerConfig   `mapstructure:"server"`
+	Database DatabaseConfig `mapstructure:"database"`
+	MySQL    MySQLConfig    `mapstructure:"mysql"` // 兼容旧配置，database.dsn 为空时使用
+	Check    CheckConfig    `mapstructure:"check"`
+	Alert    AlertConfig    `mapstructure:"alert"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	LLM      LLMConfig      `mapstructure:"llm"`
+	Log      LogConfig      `mapstructure:"log"`
+	JWT      JWTConfig      `mapstructure:"jwt"` // <-- 新增
+	TLS      TLSConfig      `mapstructure:"tls"`
+	Vuln     VulnConfig     `mapstructure:"vuln"`
 }
 
 // AppConfig 应用配置
@@ -41,6 +45,23 @@ type ServerConfig struct {
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
 }
 
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// DatabaseConfig 数据库配置
+type DatabaseConfig struct {
+	Driver          string        `mapstructure:"driver"` // mysql / postgres / sqlite
+	DSN             string        `mapstructure:"dsn"`    // 连接字符串，sqlite 为数据库文件路径
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	AutoMigrate     bool          `mapstructure:"auto_migrate"` // 启动时自动执行未应用的迁移
+}
+
 // MySQLConfig MySQL 配置
 type MySQLConfig struct {
 	DSN             string        `mapstructure:"dsn"`
@@ -55,6 +76,16 @@ type CheckConfig struct {
 	Timeout       time.Duration `mapstructure:"timeout"`
 	MaxConcurrent int           `mapstructure:"max_concurrent"`
 	RetryTimes    int           `mapstructure:"retry_times"`
+	RetryBackoff  time.Duration `mapstructure:"retry_backoff"` // 首次重试等待时间，之后指数增长
+	Breaker       BreakerConfig `mapstructure:"breaker"`
+}
+
+// BreakerConfig 节点熔断配置：连续失败达到阈值后暂停拉取，冷却后单次探测
+type BreakerConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Threshold   int           `mapstructure:"threshold"`    // 连续失败次数阈值
+	Cooldown    time.Duration `mapstructure:"cooldown"`     // 首次熔断的冷却时间，探测失败后翻倍
+	MaxCooldown time.Duration `mapstructure:"max_cooldown"` // 冷却时间上限
 }
 
 // AlertConfig 告警配置
@@ -62,11 +93,34 @@ type AlertConfig struct {
 	Enabled   bool          `mapstructure:"enabled"`
 	Cooldown  time.Duration `mapstructure:"cooldown"`
 	Threshold struct {
-		CPU     float64 `mapstructure:"cpu"`
-		Memory  float64 `mapstructure:"memory"`
-		Disk    float64 `mapstructure:"disk"`
-		LoadAvg float64 `mapstructure:"load_avg"`
+		CPU              float64 `mapstructure:"cpu"`
+		Memory           float64 `mapstructure:"memory"`
+		Disk             float64 `mapstructure:"disk"`
+		Inodes           float64 `mapstructure:"inodes"`
+		LoadAvg          float64 `mapstructure:"load_avg"`
+		CertWarningDays  int     `mapstructure:"cert_warning_days"`  // 证书剩余天数不超过该值为 WARNING
+		CertCriticalDays int     `mapstructure:"cert_critical_days"` // 证书剩余天数不超过该值为 CRITICAL
 	} `mapstructure:"threshold"`
+	Rules    []RuleConfig   `mapstructure:"rules"` // 全局默认规则，为空时由 Threshold 生成
+	Flapping FlappingConfig `mapstructure:"flapping"`
+}
+
+// FlappingConfig 抖动检测配置
+type FlappingConfig struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	Window        int     `mapstructure:"window"`         // 滑动窗口（巡检次数）
+	HighThreshold float64 `mapstructure:"high_threshold"` // 进入抖动的分数阈值（%）
+	LowThreshold  float64 `mapstructure:"low_threshold"`  // 退出抖动的分数阈值（%）
+}
+
+// RuleConfig 阈值规则配置
+type RuleConfig struct {
+	Name     string        `mapstructure:"name"`
+	Metric   string        `mapstructure:"metric"`
+	Operator string        `mapstructure:"operator"`
+	Value    float64       `mapstructure:"value"`
+	For      time.Duration `mapstructure:"for"`
+	Severity string        `mapstructure:"severity"`
 }
 
 // MailConfig 邮件配置
@@ -106,6 +160,18 @@ type JWTConfig struct {
 	ExpireHours int    `mapstructure:"expire_hours"`
 }
 
+// TLSConfig Master 与 Agent 之间的双向 TLS 配置
+type TLSConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	CADir        string        `mapstructure:"ca_dir"`        // 内置 CA 证书与私钥目录
+	CertValidity time.Duration `mapstructure:"cert_validity"` // 签发证书的有效期
+}
+
+// VulnConfig 本地漏洞库配置
+type VulnConfig struct {
+	Feed string `mapstructure:"feed"` // OSV 格式的漏洞库：JSON 文件、目录或 zip 包，为空时不匹配漏洞
+}
+
 // Load 加载配置
 func Load(configFile string) error {
 	v := viper.New()
@@ -140,6 +206,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.write_timeout", "10s")
 	v.SetDefault("server.idle_timeout", "60s")
 
+	v.SetDefault("database.driver", DriverMySQL)
+	v.SetDefault("database.max_open_conns", 50)
+	v.SetDefault("database.max_idle_conns", 10)
+	v.SetDefault("database.conn_max_lifetime", "5m")
+	v.SetDefault("database.auto_migrate", true)
+
 	v.SetDefault("mysql.max_open_conns", 50)
 	v.SetDefault("mysql.max_idle_conns", 10)
 	v.SetDefault("mysql.conn_max_lifetime", "5m")
@@ -148,13 +220,25 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("check.timeout", "30s")
 	v.SetDefault("check.max_concurrent", 10)
 	v.SetDefault("check.retry_times", 3)
+	v.SetDefault("check.retry_backoff", "1s")
+	v.SetDefault("check.breaker.enabled", true)
+	v.SetDefault("check.breaker.threshold", 3)
+	v.SetDefault("check.breaker.cooldown", "5m")
+	v.SetDefault("check.breaker.max_cooldown", "1h")
 
 	v.SetDefault("alert.enabled", true)
 	v.SetDefault("alert.cooldown", "5m")
 	v.SetDefault("alert.threshold.cpu", 85.0)
 	v.SetDefault("alert.threshold.memory", 90.0)
 	v.SetDefault("alert.threshold.disk", 90.0)
+	v.SetDefault("alert.threshold.inodes", 90.0)
 	v.SetDefault("alert.threshold.load_avg", 5.0)
+	v.SetDefault("alert.threshold.cert_warning_days", 30)
+	v.SetDefault("alert.threshold.cert_critical_days", 7)
+	v.SetDefault("alert.flapping.enabled", true)
+	v.SetDefault("alert.flapping.window", 20)
+	v.SetDefault("alert.flapping.high_threshold", 50.0)
+	v.SetDefault("alert.flapping.low_threshold", 25.0)
 
 	v.SetDefault("mail.enabled", false)
 	v.SetDefault("mail.port", 994)
@@ -175,16 +259,77 @@ func setDefaults(v *viper.Viper) {
 	// JWT 默认值
 	v.SetDefault("jwt.secret", "change-me")
 	v.SetDefault("jwt.expire_hours", 24)
+
+	v.SetDefault("tls.enabled", false)
+	v.SetDefault("tls.ca_dir", "data/pki")
+	v.SetDefault("tls.cert_validity", "2160h")
+
+	v.SetDefault("vuln.feed", "")
 }
 
 // validateConfig 基础校验
 func validateConfig() error {
-	if Conf.MySQL.DSN == "" {
-		return fmt.Errorf("MySQL DSN 不能为空")
+	// 兼容旧的 mysql 配置段，只适用于 MySQL 驱动
+	if Conf.Database.DSN == "" && Conf.MySQL.DSN != "" {
+		if Conf.Database.Driver != "" && Conf.Database.Driver != DriverMySQL {
+			return fmt.Errorf("database.driver 为 %s 时需配置 database.dsn，mysql.dsn 只适用于 mysql 驱动", Conf.Database.Driver)
+		}
+		Conf.Database = DatabaseConfig{
+			Driver:          DriverMySQL,
+			DSN:             Conf.MySQL.DSN,
+			MaxOpenConns:    Conf.MySQL.MaxOpenConns,
+			MaxIdleConns:    Conf.MySQL.MaxIdleConns,
+			ConnMaxLifetime: Conf.MySQL.ConnMaxLifetime,
+			AutoMigrate:     Conf.Database.AutoMigrate,
+		}
+	}
+
+	switch Conf.Database.Driver {
+	case DriverMySQL, DriverPostgres, DriverSQLite:
+	default:
+		return fmt.Errorf("不支持的数据库驱动: %s", Conf.Database.Driver)
+	}
+	if Conf.Database.DSN == "" {
+		return fmt.Errorf("数据库 DSN 不能为空")
 	}
 	if Conf.Server.Listen == "" {
 		return fmt.Errorf("服务器监听地址不能为空")
 	}
+	if Conf.Check.Timeout <= 0 {
+		return fmt.Errorf("check.timeout 必须大于 0")
+	}
+	if Conf.Check.Breaker.Enabled && (Conf.Check.Breaker.Threshold <= 0 || Conf.Check.Breaker.Cooldown <= 0) {
+		return fmt.Errorf("check.breaker.threshold 与 check.breaker.cooldown 必须大于 0")
+	}
+	if Conf.TLS.Enabled && Conf.TLS.CertValidity <= 0 {
+		return fmt.Errorf("tls.cert_validity 必须大于 0")
+	}
+	for i, r := range Conf.Alert.Rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("alert.rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate 校验规则的指标、运算符与级别，写错的规则不会触发
+func (r RuleConfig) Validate() error {
+	if r.Metric == "" {
+		return fmt.Errorf("metric 不能为空")
+	}
+	switch r.Operator {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("不支持的运算符: %q", r.Operator)
+	}
+	switch strings.ToUpper(r.Severity) {
+	case "WARNING", "CRITICAL":
+	default:
+		return fmt.Errorf("severity 必须为 WARNING 或 CRITICAL: %q", r.Severity)
+	}
+	if r.For < 0 {
+		return fmt.Errorf("for 不能为负数")
+	}
 	return nil
 }
 
@@ -0,0 +1,74 @@
+// file: internal/database/database.go
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"cyber-inspector/internal/config"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// sqlitePragmas SQLite 默认参数：等待锁而不是立即报错，并启用外键与 WAL
+var sqlitePragmas = []string{
+	"_pragma=busy_timeout(5000)",
+	"_pragma=foreign_keys(1)",
+	"_pragma=journal_mode(WAL)",
+}
+
+// Dialector 根据驱动名称创建 GORM 方言
+func Dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverMySQL, "":
+		return mysql.Open(cfg.DSN), nil
+	case config.DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	case config.DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg.DSN)), nil
+	}
+	return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+}
+
+// Open 打开数据库连接并配置连接池
+func Open(cfg config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite 只允许单个写连接，避免 database is locked
+	if cfg.Driver == config.DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+		return db, nil
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
+
+// sqliteDSN 为未显式指定参数的 SQLite DSN 追加默认参数
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "_pragma=") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(sqlitePragmas, "&")
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ExpiringCertificate 即将到期的证书
+type ExpiringCertificate struct {
+	AgentID      uint64    `json:"agent_id"`
+	AgentName    string    `json:"agent_name"`
+	Source       string    `json:"source"`
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SANs         []string  `json:"sans"`
+	NotAfter     time.Time `json:"not_after"`
+	DaysLeft     int       `json:"days_left"` // 按当前时间计算，已过期为负数
+	InspectionID uint64    `json:"inspection_id"`
+}
+
+// ListExpiringCertificates 全部节点中即将到期的证书，?days= 指定天数，默认取证书告警天数
+func ListExpiringCertificates(repo repository.AgentStore, inspectionRepo repository.InspectionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days := config.Conf.Alert.Threshold.CertWarningDays
+		if v := c.Query("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "days 必须是非负整数"})
+				return
+			}
+			days = n
+		}
+
+		now := time.Now()
+		certs, err := inspectionRepo.ExpiringCertificates(now.AddDate(0, 0, days))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		names := make(map[uint64]string)
+		if agents, err := repo.ListAgents(); err == nil {
+			for _, a := range agents {
+				names[a.ID] = a.Name
+			}
+		}
+
+		items := make([]ExpiringCertificate, 0, len(certs))
+		for _, cert := range certs {
+			items = append(items, ExpiringCertificate{
+				AgentID:      cert.AgentID,
+				AgentName:    names[cert.AgentID],
+				Source:       cert.Source,
+				Subject:      cert.Subject,
+				Issuer:       cert.Issuer,
+				SANs:         cert.SANs,
+				NotAfter:     cert.NotAfter,
+				DaysLeft:     int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+				InspectionID: cert.InspectionID,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"days": days, "certificates": items})
+	}
+}
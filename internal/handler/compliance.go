@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentCompliance 节点的安全基线合规情况
+type AgentCompliance struct {
+	AgentID      uint64                    `json:"agent_id"`
+	AgentName    string                    `json:"agent_name"`
+	Score        float64                   `json:"score"`
+	Critical     int                       `json:"critical"` // CRITICAL 问题数
+	Warning      int                       `json:"warning"`  // WARNING 问题数
+	Findings     []model.InspectionFinding `json:"findings"`
+	InspectionID uint64                    `json:"inspection_id"`
+	InspectedAt  time.Time                 `json:"inspected_at"`
+}
+
+// ListCompliance 各节点最近一次审计的合规得分与审计问题，按得分从低到高排列，未审计的节点不列出
+func ListCompliance(repo repository.AgentStore, inspectionRepo repository.InspectionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspections, err := inspectionRepo.LatestAuditedInspections()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		names := make(map[uint64]string)
+		if agents, err := repo.ListAgents(); err == nil {
+			for _, a := range agents {
+				names[a.ID] = a.Name
+			}
+		}
+
+		items := make([]AgentCompliance, 0, len(inspections))
+		for _, ins := range inspections {
+			item := AgentCompliance{
+				AgentID:      ins.AgentID,
+				AgentName:    names[ins.AgentID],
+				Score:        *ins.ComplianceScore,
+				Findings:     ins.Findings,
+				InspectionID: ins.ID,
+				InspectedAt:  ins.CreatedAt,
+			}
+			if item.Findings == nil {
+				item.Findings = []model.InspectionFinding{}
+			}
+			for _, f := range ins.Findings {
+				switch f.Severity {
+				case model.LevelCritical:
+					item.Critical++
+				case model.LevelWarning:
+					item.Warning++
+				}
+			}
+			items = append(items, item)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Score != items[j].Score {
+				return items[i].Score < items[j].Score
+			}
+			return items[i].AgentID < items[j].AgentID
+		})
+		c.JSON(http.StatusOK, gin.H{"agents": items})
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAgentPort Agent 默认监听端口
+const defaultAgentPort = 8083
+
+// CreateEnrollmentTokenRequest 创建注册令牌请求
+type CreateEnrollmentTokenRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Reusable    bool   `json:"reusable"`
+	AutoApprove bool   `json:"auto_approve"`
+	Tags        string `json:"tags"`
+	ExpiresIn   string `json:"expires_in"` // 有效期，如 "24h"，为空表示不过期
+}
+
+// ListEnrollmentTokens 获取注册令牌列表（不返回令牌内容）
+func ListEnrollmentTokens(repo repository.EnrollmentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokens, err := repo.ListEnrollmentTokens()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// CreateEnrollmentToken 创建注册令牌，明文令牌只在此处返回一次
+func CreateEnrollmentToken(repo repository.EnrollmentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateEnrollmentTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		token := &model.EnrollmentToken{
+			Name:        req.Name,
+			Reusable:    req.Reusable,
+			AutoApprove: req.AutoApprove,
+			Tags:        req.Tags,
+			Enabled:     true,
+		}
+		if req.ExpiresIn != "" {
+			d, err := time.ParseDuration(req.ExpiresIn)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的有效期: %s", req.ExpiresIn)})
+				return
+			}
+			expires := time.Now().Add(d)
+			token.ExpiresAt = &expires
+		}
+
+		plain, err := agent.GenerateToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+			return
+		}
+		token.Token = agent.HashToken(plain)
+
+		if err := repo.CreateEnrollmentToken(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"enrollment_token": token,
+			"token":            plain,
+			"message":          "请将令牌配置到 Agent，令牌不会再次显示",
+		})
+	}
+}
+
+// DeleteEnrollmentToken 删除注册令牌
+func DeleteEnrollmentToken(repo repository.EnrollmentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		if err := repo.DeleteEnrollmentToken(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	}
+}
+
+// ApproveAgent 审批通过自注册的节点
+func ApproveAgent(repo repository.AgentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		node, err := repo.GetAgentByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+			return
+		}
+		if node.Status != model.AgentPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "节点不在待审批状态"})
+			return
+		}
+
+		if err := repo.UpdateAgentStatus(id, model.AgentUnknown); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"agent_id": id, "message": "节点已审批通过"})
+	}
+}
+
+// RegisterAgent Agent 使用注册令牌自注册，返回节点ID与节点令牌
+func RegisterAgent(repo repository.EnrollmentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req agent.Registration
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		enrollment, err := repo.GetEnrollmentToken(agent.HashToken(req.EnrollmentToken))
+		if err != nil || !enrollment.Usable(time.Now()) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": repository.ErrEnrollmentTokenUsed.Error()})
+			return
+		}
+
+		ip := registrationIP(req.IPs, c.ClientIP())
+		if ip == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无法确定节点 IP"})
+			return
+		}
+		// 上报的地址必须指向请求来源，避免 Master 被用来拉取任意地址
+		addr := req.URL
+		if addr != "" && !agentURLMatches(addr, ip) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "节点地址与请求来源不一致"})
+			return
+		}
+		if addr == "" {
+			port := req.Port
+			if port == 0 {
+				port = defaultAgentPort
+			}
+			// 启用双向 TLS 的 Agent 只接受 HTTPS
+			scheme := "http"
+			for _, capability := range req.Capabilities {
+				if capability == protocol.CapabilityMTLS {
+					scheme = "https"
+				}
+			}
+			addr = scheme + "://" + net.JoinHostPort(ip, strconv.Itoa(port))
+		}
+
+		mode := model.AgentModePull
+		if req.Mode != "" {
+			mode = model.AgentMode(req.Mode)
+		}
+
+		status := model.AgentPending
+		if enrollment.AutoApprove {
+			status = model.AgentUnknown
+		}
+		node := &model.Agent{
+			Name:          req.Hostname,
+			IP:            ip,
+			URL:           addr,
+			Enabled:       true,
+			CheckInterval: 300,
+			Status:        status,
+			Tags:          enrollment.Tags,
+			OS:            req.OS,
+			Version:       req.Version,
+			Capabilities:  strings.Join(req.Capabilities, ","),
+			Mode:          mode,
+		}
+
+		token, err := agent.GenerateToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+			return
+		}
+
+		sealed, err := agent.SealKey(config.Conf.App.Secret, agent.SigningKey(token))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "加密签名密钥失败"})
+			return
+		}
+
+		if err := repo.EnrollAgent(enrollment.ID, node, agent.TokenDigest(token), sealed); err != nil {
+			if errors.Is(err, repository.ErrEnrollmentTokenUsed) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "节点注册失败: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, agent.Credentials{
+			AgentID: node.ID,
+			Token:   token,
+			Status:  string(node.Status),
+		})
+	}
+}
+
+// registrationIP 选择节点 IP：以请求来源为准，上报地址只有与来源一致时才使用
+func registrationIP(reported []string, remote string) string {
+	source := net.ParseIP(remote)
+	if source == nil {
+		return ""
+	}
+	for _, ip := range reported {
+		if source.Equal(net.ParseIP(ip)) {
+			return ip
+		}
+	}
+	return remote
+}
+
+// agentURLMatches 节点地址是否为指向 ip 的 HTTP(S) 地址
+func agentURLMatches(raw, ip string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := net.ParseIP(u.Hostname())
+	return host != nil && host.Equal(net.ParseIP(ip))
+}
@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"context"
 	"cyber-inspector/internal/auth"
 	"cyber-inspector/internal/config"
 	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/profile"
 	"cyber-inspector/internal/repository"
 	"cyber-inspector/internal/service"
 	"github.com/gin-gonic/gin"
@@ -26,7 +28,7 @@ type LoginResponse struct {
 }
 
 // Login 用户登录
-func Login(repo *repository.Repository) gin.HandlerFunc {
+func Login(repo repository.UserStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -79,7 +81,7 @@ func Logout() gin.HandlerFunc {
 }
 
 // ListAgents 获取节点列表
-func ListAgents(repo *repository.Repository) gin.HandlerFunc {
+func ListAgents(repo repository.AgentStore, inspectionRepo repository.InspectionStore, profileRepo repository.ProfileStore, streams *service.StreamHub) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		agents, err := repo.ListAgents()
 		if err != nil {
@@ -88,15 +90,31 @@ func ListAgents(repo *repository.Repository) gin.HandlerFunc {
 		}
 
 		// 获取最新巡检记录
-		inspections, _ := repo.LatestInspections()
+		inspections, _ := inspectionRepo.LatestInspections()
 		statusMap := make(map[uint64]model.Inspection)
 		for _, ins := range inspections {
 			statusMap[ins.AgentID] = ins
 		}
 
+		// 长连接状态
+		connections := map[uint64]service.StreamState{}
+		if streams != nil {
+			connections = streams.States()
+		}
+
+		// 应生效的远程配置版本，与节点上报的版本不一致即为配置漂移
+		expectedConfig := make(map[uint64]string)
+		if profiles, err := profileRepo.ListConfigProfiles(); err == nil {
+			for _, a := range agents {
+				expectedConfig[a.ID] = profile.Resolve(profiles, a).Version
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"agents":    agents,
-			"statusMap": statusMap,
+			"agents":         agents,
+			"statusMap":      statusMap,
+			"connections":    connections,
+			"expectedConfig": expectedConfig,
 		})
 	}
 }
@@ -107,11 +125,22 @@ type CreateAgentRequest struct {
 	IP            string `json:"ip" binding:"required,ip"`
 	URL           string `json:"url" binding:"required,url"`
 	CheckInterval int    `json:"check_interval" binding:"min=30"`
+	Timeout       int    `json:"timeout" binding:"min=0,max=600"` // 拉取超时（秒），0 使用全局配置
 	Enabled       bool   `json:"enabled"`
+	Tags          string `json:"tags" binding:"max=255"`
+	Mode          string `json:"mode" binding:"omitempty,oneof=pull push"` // 巡检模式，默认 pull
+}
+
+// agentMode 请求中的巡检模式，为空时为拉取模式
+func (r CreateAgentRequest) agentMode() model.AgentMode {
+	if r.Mode == "" {
+		return model.AgentModePull
+	}
+	return model.AgentMode(r.Mode)
 }
 
 // CreateAgent 创建节点
-func CreateAgent(repo *repository.Repository) gin.HandlerFunc {
+func CreateAgent(repo repository.AgentStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req CreateAgentRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -124,7 +153,10 @@ func CreateAgent(repo *repository.Repository) gin.HandlerFunc {
 			IP:            req.IP,
 			URL:           req.URL,
 			CheckInterval: req.CheckInterval,
+			Timeout:       req.Timeout,
 			Enabled:       req.Enabled,
+			Tags:          req.Tags,
+			Mode:          req.agentMode(),
 		}
 
 		if err := repo.CreateAgent(agent); err != nil {
@@ -137,7 +169,7 @@ func CreateAgent(repo *repository.Repository) gin.HandlerFunc {
 }
 
 // UpdateAgent 更新节点
-func UpdateAgent(repo *repository.Repository) gin.HandlerFunc {
+func UpdateAgent(repo repository.AgentStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 
@@ -157,7 +189,13 @@ func UpdateAgent(repo *repository.Repository) gin.HandlerFunc {
 		agent.IP = req.IP
 		agent.URL = req.URL
 		agent.CheckInterval = req.CheckInterval
+		agent.Timeout = req.Timeout
 		agent.Enabled = req.Enabled
+		agent.Tags = req.Tags
+		// 未指定模式时保持原有模式
+		if req.Mode != "" {
+			agent.Mode = model.AgentMode(req.Mode)
+		}
 
 		if err := repo.UpdateAgent(agent); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -169,7 +207,7 @@ func UpdateAgent(repo *repository.Repository) gin.HandlerFunc {
 }
 
 // DeleteAgent 删除节点
-func DeleteAgent(repo *repository.Repository) gin.HandlerFunc {
+func DeleteAgent(repo repository.AgentStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 
@@ -182,13 +220,41 @@ func DeleteAgent(repo *repository.Repository) gin.HandlerFunc {
 	}
 }
 
+// GetAgentInfo 查询节点 Agent 的协议版本、采集器与能力
+func GetAgentInfo(repo repository.AgentStore, checker *service.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		agent, err := repo.GetAgentByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+			return
+		}
+		if agent.Mode == model.AgentModePush {
+			c.JSON(http.StatusConflict, gin.H{"error": "推送模式节点无法主动查询"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), agent.PullTimeout(config.Conf.Check.Timeout))
+		defer cancel()
+
+		info, err := checker.AgentInfo(ctx, *agent)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, info)
+	}
+}
+
 // UpdateIntervalRequest 更新间隔请求
 type UpdateIntervalRequest struct {
 	Seconds int `json:"seconds" binding:"min=30"`
 }
 
 // UpdateInterval 更新巡检间隔
-func UpdateInterval(repo *repository.Repository) gin.HandlerFunc {
+func UpdateInterval(repo repository.AgentStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 
@@ -215,6 +281,20 @@ func TriggerCheck(checker *service.Checker) gin.HandlerFunc {
 	}
 }
 
+// ListFlapping 获取处于抖动状态的节点条件
+func ListFlapping(checker *service.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"flapping": checker.Flapping()})
+	}
+}
+
+// ListBreakers 获取连续拉取失败或熔断中的节点
+func ListBreakers(checker *service.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"breakers": checker.Breakers()})
+	}
+}
+
 // GetStatus 获取巡检状态
 type StatusResponse struct {
 	IsRunning     bool      `json:"is_running"`
@@ -0,0 +1,522 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/repository/memory"
+	"cyber-inspector/internal/service"
+	"cyber-inspector/internal/vuln"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// setupConfig 设置测试用全局配置
+func setupConfig(t *testing.T) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.ExpireHours = 1
+	cfg.Alert.Threshold.CPU = 85
+
+	old := config.Conf
+	config.Conf = cfg
+	t.Cleanup(func() { config.Conf = old })
+}
+
+// newRouter 使用内存仓库注册路由
+func newRouter(store *memory.Store) *gin.Engine {
+	r := gin.New()
+	r.POST("/api/auth/login", Login(store))
+	r.GET("/api/agents", ListAgents(store, store, store, nil))
+	r.POST("/api/agents", CreateAgent(store))
+	r.PUT("/api/agents/:id", UpdateAgent(store))
+	r.DELETE("/api/agents/:id", DeleteAgent(store))
+	r.GET("/api/agents/:id/rules", GetAgentRules(store, store))
+	r.POST("/api/rules", CreateAlertRule(store))
+	r.PUT("/api/rules/:id", UpdateAlertRule(store))
+	r.POST("/api/config-profiles", CreateConfigProfile(store, nil))
+	r.GET("/api/agents/:id/config", GetAgentConfig(store, store))
+	r.GET("/api/certificates/expiring", ListExpiringCertificates(store, store))
+	r.GET("/api/compliance", ListCompliance(store, store))
+	r.GET("/api/packages", ListPackages(store, store))
+	r.GET("/api/vulnerabilities", ListVulnerabilities(store, store, vuln.NewDatabase()))
+	r.POST("/agent-api/register", RegisterAgent(store))
+	return r
+}
+
+// do 发送 JSON 请求
+func do(r http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLogin(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	if err := store.InitAdminUser("admin", "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	r := newRouter(store)
+
+	w := do(r, http.MethodPost, "/api/auth/login", gin.H{"username": "admin", "password": "admin123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录应成功，实际 %d: %s", w.Code, w.Body)
+	}
+	var resp LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+		t.Fatalf("登录响应缺少令牌: %s", w.Body)
+	}
+
+	w = do(r, http.MethodPost, "/api/auth/login", gin.H{"username": "admin", "password": "wrong-pass"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("密码错误应返回 401，实际 %d", w.Code)
+	}
+
+	w = do(r, http.MethodPost, "/api/auth/login", gin.H{"username": "admin"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("缺少密码应返回 400，实际 %d", w.Code)
+	}
+}
+
+func TestAgentCRUD(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	r := newRouter(store)
+
+	req := gin.H{"name": "web-01", "ip": "10.0.0.1", "url": "http://10.0.0.1:8083", "check_interval": 60, "enabled": true, "tags": "web,prod"}
+	w := do(r, http.MethodPost, "/api/agents", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建节点失败: %d %s", w.Code, w.Body)
+	}
+	var created model.Agent
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = do(r, http.MethodPost, "/api/agents", gin.H{"name": "bad", "ip": "not-an-ip", "url": "http://x", "check_interval": 60})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("非法IP应返回 400，实际 %d", w.Code)
+	}
+
+	_ = store.SaveInspection(&model.Inspection{AgentID: created.ID, Hostname: "web-01", IP: "10.0.0.1", Level: model.LevelWarning})
+
+	w = do(r, http.MethodGet, "/api/agents", nil)
+	var list struct {
+		Agents    []model.Agent               `json:"agents"`
+		StatusMap map[string]model.Inspection `json:"statusMap"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Agents) != 1 || list.Agents[0].Tags != "web,prod" {
+		t.Fatalf("节点列表不正确: %s", w.Body)
+	}
+	if len(list.StatusMap) != 1 {
+		t.Fatalf("应包含最新巡检状态: %s", w.Body)
+	}
+
+	created.Mode = model.AgentModePush
+	_ = store.UpdateAgent(&created)
+	req["name"] = "web-01-renamed"
+	w = do(r, http.MethodPut, "/api/agents/1", req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("更新节点失败: %d %s", w.Code, w.Body)
+	}
+	if a, _ := store.GetAgentByID(created.ID); a.Name != "web-01-renamed" || a.Mode != model.AgentModePush {
+		t.Fatalf("节点名称未更新或模式被重置: %s %s", a.Name, a.Mode)
+	}
+
+	w = do(r, http.MethodPut, "/api/agents/999", req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("不存在的节点应返回 404，实际 %d", w.Code)
+	}
+
+	w = do(r, http.MethodDelete, "/api/agents/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("删除节点失败: %d", w.Code)
+	}
+	if agents, _ := store.ListAgents(); len(agents) != 0 {
+		t.Fatalf("节点未删除")
+	}
+}
+
+func TestRegisterAgentURL(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	r := newRouter(store)
+	if err := store.CreateEnrollmentToken(&model.EnrollmentToken{Name: "all", Token: agent.HashToken("enroll"), Enabled: true, Reusable: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		capabilities []string
+		want         string
+	}{
+		{[]string{protocol.CapabilityInspect}, "http://192.0.2.1:8083"},
+		{[]string{protocol.CapabilityInspect, protocol.CapabilityMTLS}, "https://192.0.2.1:8083"},
+	}
+	for _, tc := range cases {
+		// 上报地址与请求来源（httptest 默认 192.0.2.1）不一致时以来源为准
+		w := do(r, http.MethodPost, "/agent-api/register", agent.Registration{
+			EnrollmentToken: "enroll",
+			Hostname:        "node",
+			IPs:             []string{"10.0.0.1", "192.0.2.1"},
+			Capabilities:    tc.capabilities,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("注册失败: %d %s", w.Code, w.Body.String())
+		}
+		var creds agent.Credentials
+		_ = json.Unmarshal(w.Body.Bytes(), &creds)
+		if node, _ := store.GetAgentByID(creds.AgentID); node.URL != tc.want {
+			t.Errorf("%v: URL = %s, want %s", tc.capabilities, node.URL, tc.want)
+		}
+	}
+
+	// 上报的 url 必须指向请求来源
+	for raw, want := range map[string]int{
+		"https://192.0.2.1:9443":            http.StatusCreated,
+		"http://169.254.169.254/latest":     http.StatusBadRequest,
+		"http://192.0.2.1.example.com:8083": http.StatusBadRequest,
+		"file:///etc/passwd":                http.StatusBadRequest,
+	} {
+		w := do(r, http.MethodPost, "/agent-api/register", agent.Registration{EnrollmentToken: "enroll", Hostname: "node", URL: raw})
+		if w.Code != want {
+			t.Errorf("url=%s: 状态码 %d，want %d %s", raw, w.Code, want, w.Body)
+		}
+	}
+
+	// 主机名作为节点名称展示，只接受合法主机名
+	w := do(r, http.MethodPost, "/agent-api/register", agent.Registration{
+		EnrollmentToken: "enroll",
+		Hostname:        "<img src=x onerror=alert(1)>",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("非法主机名应返回 400，实际 %d %s", w.Code, w.Body)
+	}
+}
+
+func TestIngestInspectionMode(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	pull := &model.Agent{Name: "pull-01", IP: "10.0.0.1", URL: "http://10.0.0.1:8083", Enabled: true, Mode: model.AgentModePull}
+	push := &model.Agent{Name: "push-01", IP: "10.0.0.2", Enabled: true, Mode: model.AgentModePush}
+	_ = store.CreateAgent(pull)
+	_ = store.CreateAgent(push)
+
+	r := gin.New()
+	r.POST("/agent-api/inspections/:id", func(c *gin.Context) {
+		node, _ := store.GetAgentByID(map[string]uint64{"pull": pull.ID, "push": push.ID}[c.Param("id")])
+		c.Set("agent", node)
+	}, IngestInspection(service.NewChecker(store, agent.NewClient())))
+
+	report := gin.H{"hostname": "node", "raw_data": gin.H{"cpu_used": "10%"}}
+	if w := do(r, http.MethodPost, "/agent-api/inspections/pull", report); w.Code != http.StatusConflict {
+		t.Errorf("拉取模式的节点推送应返回 409，实际 %d %s", w.Code, w.Body)
+	}
+	if w := do(r, http.MethodPost, "/agent-api/inspections/push", report); w.Code != http.StatusOK {
+		t.Errorf("推送模式的节点应接受推送，实际 %d %s", w.Code, w.Body)
+	}
+	if inspections, _ := store.GetInspectionsByAgentID(pull.ID, 10); len(inspections) != 0 {
+		t.Errorf("拒绝的推送不应入库: %+v", inspections)
+	}
+}
+
+func TestAgentAuthMiddlewareDisabled(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	node := &model.Agent{Name: "push-01", IP: "10.0.0.2", Enabled: true, Mode: model.AgentModePush}
+	_ = store.CreateAgent(node)
+	token, _ := agent.GenerateToken()
+	sealed, _ := agent.SealKey(config.Conf.App.Secret, agent.SigningKey(token))
+	if err := store.SaveAgentToken(node.ID, agent.TokenDigest(token), sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/agent-api/inspections", AgentAuthMiddleware(store, agent.NewKeyVerifier(time.Minute)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/agent-api/inspections", nil)
+		_ = agent.SignAgentRequest(req, node.ID, token, time.Now())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("启用的节点应通过校验，实际 %d", code)
+	}
+	node.Enabled = false
+	_ = store.UpdateAgent(node)
+	if code := send(); code != http.StatusForbidden {
+		t.Errorf("禁用的节点应返回 403，实际 %d", code)
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	agent := &model.Agent{Name: "db-01", IP: "10.0.0.5", URL: "http://10.0.0.5:8083", Enabled: true, Tags: "db"}
+	_ = store.CreateAgent(agent)
+	r := newRouter(store)
+
+	w := do(r, http.MethodPost, "/api/rules", gin.H{"metric": "cpu_used", "operator": "=>", "value": 90, "severity": "CRITICAL"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("非法运算符应返回 400，实际 %d", w.Code)
+	}
+
+	w = do(r, http.MethodPost, "/api/rules", gin.H{"metric": "cpu_used", "operator": ">", "value": 95, "for": "10m", "severity": "CRITICAL", "tag": "db"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建规则失败: %d %s", w.Code, w.Body)
+	}
+	var created model.AlertRule
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.For != 600 || !created.Enabled {
+		t.Fatalf("规则字段不正确: %+v", created)
+	}
+
+	w = do(r, http.MethodGet, "/api/agents/1/rules", nil)
+	var effective struct {
+		Rules []struct {
+			Metric string  `json:"metric"`
+			Value  float64 `json:"value"`
+			Source string  `json:"source"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &effective); err != nil {
+		t.Fatal(err)
+	}
+	if len(effective.Rules) != 1 || effective.Rules[0].Value != 95 || effective.Rules[0].Source != "tag:db" {
+		t.Fatalf("标签规则应覆盖默认规则: %s", w.Body)
+	}
+
+	w = do(r, http.MethodPut, "/api/rules/2", gin.H{"metric": "cpu_used", "operator": ">", "value": 95, "for": "-1m", "severity": "CRITICAL", "tag": "db"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("负数持续时间应返回 400，实际 %d", w.Code)
+	}
+}
+
+func TestConfigProfiles(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	_ = store.CreateAgent(&model.Agent{Name: "db-01", IP: "10.0.0.5", URL: "http://10.0.0.5:8083", Enabled: true, Tags: "db"})
+	r := newRouter(store)
+
+	w := do(r, http.MethodPost, "/api/config-profiles", gin.H{"name": "bad", "settings": gin.H{"master.url": "http://evil"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("不允许远程下发的配置项应返回 400，实际 %d", w.Code)
+	}
+
+	for _, body := range []gin.H{
+		{"name": "global", "settings": gin.H{"thresholds.cpu": 70, "cache.ttl": "1m"}},
+		{"name": "db", "tag": "db", "settings": gin.H{"thresholds.cpu": 60}},
+	} {
+		if w := do(r, http.MethodPost, "/api/config-profiles", body); w.Code != http.StatusCreated {
+			t.Fatalf("创建配置失败: %d %s", w.Code, w.Body)
+		}
+	}
+
+	var resp struct {
+		ExpectedVersion string                 `json:"expected_version"`
+		Drift           bool                   `json:"drift"`
+		Settings        map[string]interface{} `json:"settings"`
+	}
+	w = do(r, http.MethodGet, "/api/agents/1/config", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Settings["thresholds.cpu"] != 60.0 || resp.Settings["cache.ttl"] != "1m" || !resp.Drift {
+		t.Fatalf("标签配置应覆盖全局配置且未同步时存在漂移: %s", w.Body)
+	}
+
+	_ = store.UpdateAgentConfigVersion(1, resp.ExpectedVersion)
+	w = do(r, http.MethodGet, "/api/agents/1/config", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Drift {
+		t.Fatalf("上报版本与应生效版本一致时不应漂移: %s", w.Body)
+	}
+}
+
+// fixture 证书、合规与软件包接口共用的数据：web-01、db-01 与未启用这些采集器的 legacy-01
+type fixture struct {
+	store           *memory.Store
+	web, db, legacy *model.Agent
+	now             time.Time
+}
+
+// newFixture 创建节点并保存各节点的巡检记录
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	f := &fixture{
+		store:  memory.New(),
+		web:    &model.Agent{Name: "web-01", IP: "10.0.0.2", URL: "http://10.0.0.2:8083", Enabled: true},
+		db:     &model.Agent{Name: "db-01", IP: "10.0.0.5", URL: "http://10.0.0.5:8083", Enabled: true},
+		legacy: &model.Agent{Name: "legacy-01", IP: "10.0.0.9", URL: "http://10.0.0.9:8083", Enabled: true},
+		now:    time.Now(),
+	}
+	for _, a := range []*model.Agent{f.web, f.db, f.legacy} {
+		if err := f.store.CreateAgent(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cert := func(agentID uint64, source string, days int) model.InspectionCertificate {
+		return model.InspectionCertificate{AgentID: agentID, Source: source, NotAfter: f.now.AddDate(0, 0, days).Add(time.Hour)}
+	}
+	broken := cert(f.db.ID, "/etc/mysql/missing.pem", 0)
+	broken.Error = "no such file"
+	score := func(v float64) *float64 { return &v }
+
+	for _, ins := range []*model.Inspection{
+		// web-01 旧巡检中的证书已续期
+		{AgentID: f.web.ID, Certificates: []model.InspectionCertificate{cert(f.web.ID, "/etc/nginx/ssl/site.crt", 3)}},
+		{AgentID: f.web.ID, Certificates: []model.InspectionCertificate{
+			cert(f.web.ID, "/etc/nginx/ssl/site.crt", 90), cert(f.web.ID, "api.example.com:443", 20),
+		}, ComplianceScore: score(100), PackageManager: "dpkg", Distro: "debian", DistroRelease: "12", PackageCount: 420},
+		{AgentID: f.db.ID, Certificates: []model.InspectionCertificate{cert(f.db.ID, "/etc/mysql/server.pem", 5), broken},
+			ComplianceScore: score(62.5), Findings: []model.InspectionFinding{
+				{AgentID: f.db.ID, FindingID: "ssh.permit_root_login", Check: "ssh.permit_root_login", Severity: model.LevelCritical},
+				{AgentID: f.db.ID, FindingID: "file.suid:/usr/bin/vim", Check: "file.suid", Severity: model.LevelWarning},
+				{AgentID: f.db.ID, FindingID: "network.listen_any:3306", Check: "network.listen_any", Severity: model.LevelWarning},
+			},
+			PackageManager: "rpm", Distro: "rocky", DistroRelease: "9.3", PackageCount: 610, PackageUpdates: 2, SecurityUpdates: 1,
+			Packages: []model.InspectionPackage{
+				{AgentID: f.db.ID, Name: "openssl", Version: "1:3.0.7-24.el9", Update: "1:3.0.7-25.el9_3", Security: true},
+				{AgentID: f.db.ID, Name: "vim-minimal", Version: "2:8.2.2637-20.el9_1", Update: "2:8.2.2637-21.el9"},
+			},
+			Vulnerabilities: []model.InspectionVuln{
+				{AgentID: f.db.ID, VulnID: "RLSA-2024:0001", Package: "openssl", Severity: model.LevelCritical},
+				{AgentID: f.db.ID, VulnID: "RLSA-2024:0002", Package: "vim-minimal", Severity: model.LevelWarning},
+			}},
+		{AgentID: f.legacy.ID},
+	} {
+		if err := f.store.SaveInspection(ins); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func TestExpiringCertificates(t *testing.T) {
+	setupConfig(t)
+	config.Conf.Alert.Threshold.CertWarningDays = 30
+
+	f := newFixture(t)
+	// db-01 最新一次巡检不可达，没有证书
+	_ = f.store.SaveInspection(&model.Inspection{AgentID: f.db.ID, Level: model.LevelCritical})
+
+	r := newRouter(f.store)
+	var resp struct {
+		Days         int                   `json:"days"`
+		Certificates []ExpiringCertificate `json:"certificates"`
+	}
+	w := do(r, http.MethodGet, "/api/certificates/expiring", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询失败: %d %s", w.Code, w.Body)
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Days != 30 || len(resp.Certificates) != 2 {
+		t.Fatalf("默认应列出 30 天内到期的 2 张证书: %s", w.Body)
+	}
+	first, second := resp.Certificates[0], resp.Certificates[1]
+	if first.AgentName != "db-01" || first.DaysLeft != 5 || second.Source != "api.example.com:443" || second.DaysLeft != 20 {
+		t.Errorf("应按到期时间排序: %+v", resp.Certificates)
+	}
+
+	w = do(r, http.MethodGet, "/api/certificates/expiring?days=7", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Certificates) != 1 {
+		t.Errorf("7 天内应只有 1 张证书: %s", w.Body)
+	}
+	if w := do(r, http.MethodGet, "/api/certificates/expiring?days=abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("非法 days 应返回 400，实际 %d", w.Code)
+	}
+}
+
+func TestCompliance(t *testing.T) {
+	setupConfig(t)
+
+	f := newFixture(t)
+	// db-01 最新一次巡检不可达，没有合规得分
+	_ = f.store.SaveInspection(&model.Inspection{AgentID: f.db.ID, Level: model.LevelCritical})
+
+	r := newRouter(f.store)
+	w := do(r, http.MethodGet, "/api/compliance", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询失败: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Agents []AgentCompliance `json:"agents"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Agents) != 2 {
+		t.Fatalf("应列出 2 个已审计的节点: %s", w.Body)
+	}
+	first, second := resp.Agents[0], resp.Agents[1]
+	if first.AgentName != "db-01" || first.Score != 62.5 || first.Critical != 1 || first.Warning != 2 || len(first.Findings) != 3 {
+		t.Errorf("应按得分从低到高排列并统计问题: %+v", first)
+	}
+	if second.AgentName != "web-01" || second.Findings == nil {
+		t.Errorf("没有问题的节点 findings 应为空列表: %+v", second)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	setupConfig(t)
+
+	store := newFixture(t).store
+	r := newRouter(store)
+	w := do(r, http.MethodGet, "/api/packages", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询失败: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Agents []AgentPackages `json:"agents"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Agents) != 2 {
+		t.Fatalf("应列出 2 个已采集软件包的节点: %s", w.Body)
+	}
+	first, second := resp.Agents[0], resp.Agents[1]
+	if first.AgentName != "db-01" || first.SecurityUpdates != 1 || first.Vulnerabilities != 2 || len(first.Packages) != 2 {
+		t.Errorf("安全更新多的节点应排在前面: %+v", first)
+	}
+	if second.AgentName != "web-01" || second.Packages == nil {
+		t.Errorf("没有更新的节点 packages 应为空列表: %+v", second)
+	}
+
+	w = do(r, http.MethodGet, "/api/vulnerabilities?severity=CRITICAL", nil)
+	var vulns struct {
+		Vulnerabilities []AgentVulnerability `json:"vulnerabilities"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &vulns)
+	if len(vulns.Vulnerabilities) != 1 || vulns.Vulnerabilities[0].AgentName != "db-01" || vulns.Vulnerabilities[0].Package != "openssl" {
+		t.Errorf("按级别过滤错误: %s", w.Body)
+	}
+}
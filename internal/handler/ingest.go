@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxReportSize 单次推送的最大请求体
+const maxReportSize = 4 << 20
+
+// IngestInspection 接收推送模式或已建立长连接的 Agent 推送的巡检结果，需经过 AgentAuthMiddleware
+func IngestInspection(checker *service.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		node := c.MustGet("agent").(*model.Agent)
+
+		// 拉取模式的节点由 Master 主动巡检，接受推送会产生重复记录
+		if node.Mode != model.AgentModePush && !checker.Stream().Connected(node.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "节点为拉取模式，不接受推送的巡检结果"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxReportSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := protocol.DecodeReport(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "巡检数据格式错误: " + err.Error()})
+			return
+		}
+
+		inspection := agent.ParseInspection(*node, body)
+
+		// 采集时间不能晚于当前时间，防止 Agent 时钟偏差影响规则持续时间计算
+		if now := time.Now(); inspection.CreatedAt.After(now) {
+			inspection.CreatedAt = now
+		}
+
+		if err := checker.Ingest(node, inspection); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"inspection_id": inspection.ID,
+			"level":         inspection.Level,
+		})
+	}
+}
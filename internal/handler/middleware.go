@@ -3,9 +3,15 @@ package handler
 import (
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"cyber-inspector/internal/agent"
 	"cyber-inspector/internal/auth"
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
@@ -54,7 +60,7 @@ func AuthMiddleware() gin.HandlerFunc {
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
-		if !exists || role != "admin" {
+		if !exists || role != model.RoleAdmin {
 			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
 			c.Abort()
 			return
@@ -62,3 +68,55 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AgentAuthMiddleware Agent 请求 Master 的签名校验中间件
+// 按 X-Agent-ID 加载节点，解密其签名密钥校验签名，通过后将节点存入上下文
+func AgentAuthMiddleware(repo repository.AgentStore, verifier *agent.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.GetHeader(agent.HeaderAgentID), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少节点标识"})
+			c.Abort()
+			return
+		}
+
+		node, err := repo.GetAgentByID(id)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "节点不存在"})
+			c.Abort()
+			return
+		}
+		if node.Status == model.AgentPending {
+			c.JSON(http.StatusForbidden, gin.H{"error": "节点等待审批"})
+			c.Abort()
+			return
+		}
+		if !node.Enabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "节点已禁用"})
+			c.Abort()
+			return
+		}
+		token, err := repo.GetAgentToken(id)
+		if err != nil || !token.Enabled {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "节点令牌未签发或已吊销"})
+			c.Abort()
+			return
+		}
+
+		key, err := agent.OpenKey(config.Conf.App.Secret, node.APIKey)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "节点签名密钥无效，请轮换令牌"})
+			c.Abort()
+			return
+		}
+
+		if err := verifier.VerifyKey(c.Request, key, time.Now()); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("agent", node)
+		c.Next()
+	}
+}
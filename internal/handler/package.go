@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository"
+	"cyber-inspector/internal/vuln"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentPackages 节点的软件包更新情况
+type AgentPackages struct {
+	AgentID         uint64                    `json:"agent_id"`
+	AgentName       string                    `json:"agent_name"`
+	Manager         string                    `json:"manager"`
+	Distro          string                    `json:"distro"`
+	Release         string                    `json:"release"`
+	Installed       int                       `json:"installed"`
+	Updates         int                       `json:"updates"`
+	SecurityUpdates int                       `json:"security_updates"`
+	Vulnerabilities int                       `json:"vulnerabilities"`
+	Packages        []model.InspectionPackage `json:"packages"` // 有可用更新的软件包
+	InspectionID    uint64                    `json:"inspection_id"`
+	InspectedAt     time.Time                 `json:"inspected_at"`
+}
+
+// AgentVulnerability 节点上匹配到的漏洞
+type AgentVulnerability struct {
+	model.InspectionVuln
+	AgentName string `json:"agent_name"`
+}
+
+// agentNames 节点 ID 与名称的对应关系
+func agentNames(repo repository.AgentStore) map[uint64]string {
+	names := make(map[uint64]string)
+	if agents, err := repo.ListAgents(); err == nil {
+		for _, a := range agents {
+			names[a.ID] = a.Name
+		}
+	}
+	return names
+}
+
+// ListPackages 各节点最新巡检的软件包数量与待更新的软件包，安全更新多的节点排在前面，未采集软件包的节点不列出
+func ListPackages(repo repository.AgentStore, inspectionRepo repository.InspectionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspections, err := inspectionRepo.LatestInspections()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		names := agentNames(repo)
+
+		items := make([]AgentPackages, 0, len(inspections))
+		for _, ins := range inspections {
+			if ins.PackageManager == "" {
+				continue
+			}
+			item := AgentPackages{
+				AgentID:         ins.AgentID,
+				AgentName:       names[ins.AgentID],
+				Manager:         ins.PackageManager,
+				Distro:          ins.Distro,
+				Release:         ins.DistroRelease,
+				Installed:       ins.PackageCount,
+				Updates:         ins.PackageUpdates,
+				SecurityUpdates: ins.SecurityUpdates,
+				Vulnerabilities: len(ins.Vulnerabilities),
+				Packages:        ins.Packages,
+				InspectionID:    ins.ID,
+				InspectedAt:     ins.CreatedAt,
+			}
+			if item.Packages == nil {
+				item.Packages = []model.InspectionPackage{}
+			}
+			items = append(items, item)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i], items[j]
+			if a.SecurityUpdates != b.SecurityUpdates {
+				return a.SecurityUpdates > b.SecurityUpdates
+			}
+			if a.Updates != b.Updates {
+				return a.Updates > b.Updates
+			}
+			return a.AgentID < b.AgentID
+		})
+		c.JSON(http.StatusOK, gin.H{"agents": items})
+	}
+}
+
+// ListVulnerabilities 漏洞库状态与各节点最新巡检匹配到的漏洞，?severity= 按级别过滤
+func ListVulnerabilities(repo repository.AgentStore, inspectionRepo repository.InspectionStore, db *vuln.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inspections, err := inspectionRepo.LatestInspections()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		names := agentNames(repo)
+		severity := model.InspectionLevel(c.Query("severity"))
+
+		items := make([]AgentVulnerability, 0)
+		for _, ins := range inspections {
+			for _, v := range ins.Vulnerabilities {
+				if severity != "" && v.Severity != severity {
+					continue
+				}
+				items = append(items, AgentVulnerability{InspectionVuln: v, AgentName: names[ins.AgentID]})
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i], items[j]
+			if a.Severity != b.Severity {
+				return a.Severity == model.LevelCritical
+			}
+			if a.VulnID != b.VulnID {
+				return a.VulnID < b.VulnID
+			}
+			return a.AgentID < b.AgentID
+		})
+		c.JSON(http.StatusOK, gin.H{"feed": db.Status(), "vulnerabilities": items})
+	}
+}
+
+// ReloadVulnerabilities 重新加载 vuln.feed 指定的漏洞库，新的匹配结果从下一次巡检开始生效
+func ReloadVulnerabilities(db *vuln.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		feed := config.Conf.Vuln.Feed
+		if feed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未配置 vuln.feed"})
+			return
+		}
+		if err := db.Load(feed); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "加载漏洞库失败: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"feed": db.Status()})
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/pki"
+	"cyber-inspector/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// CertificateRequest 证书签发请求
+type CertificateRequest struct {
+	CSR string `json:"csr" binding:"required"`
+}
+
+// IssueAgentCertificate 为已认证的节点签发服务端证书，需经过 AgentAuthMiddleware
+func IssueAgentCertificate(repo repository.AgentStore, ca *pki.CA, validity time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		node := c.MustGet("agent").(*model.Agent)
+
+		var req CertificateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		certPEM, cert, err := ca.IssueAgent([]byte(req.CSR), "agent-"+strconv.FormatUint(node.ID, 10), agentHosts(node), validity)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := repo.UpdateAgentCert(node.ID, cert.NotAfter); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"certificate": string(certPEM),
+			"ca":          string(ca.CertPEM),
+			"not_after":   cert.NotAfter,
+		})
+	}
+}
+
+// GetCACertificate 获取 CA 证书
+func GetCACertificate(ca *pki.CA) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"certificate": string(ca.CertPEM),
+			"not_after":   ca.Cert.NotAfter,
+		})
+	}
+}
+
+// agentHosts 节点证书的 SAN，只取 Master 记录的地址
+func agentHosts(node *model.Agent) []string {
+	hosts := []string{}
+	if node.IP != "" {
+		hosts = append(hosts, node.IP)
+	}
+	if u, err := url.Parse(node.URL); err == nil && u.Hostname() != "" && u.Hostname() != node.IP {
+		hosts = append(hosts, u.Hostname())
+	}
+	return hosts
+}
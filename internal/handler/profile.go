@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/profile"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/repository"
+	"cyber-inspector/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigProfileRequest 远程配置请求
+type ConfigProfileRequest struct {
+	Name     string                 `json:"name" binding:"required,max=64"`
+	Settings map[string]interface{} `json:"settings" binding:"required"` // 配置项，键如 thresholds.cpu
+	AgentID  uint64                 `json:"agent_id"`
+	Tag      string                 `json:"tag" binding:"max=64"`
+	Enabled  *bool                  `json:"enabled"`
+}
+
+// apply 校验请求并写入配置模型
+func (req *ConfigProfileRequest) apply(p *model.ConfigProfile) error {
+	if err := profile.Validate(req.Settings); err != nil {
+		return err
+	}
+	settings, _ := json.Marshal(req.Settings)
+
+	p.Name = req.Name
+	p.Settings = string(settings)
+	p.AgentID = req.AgentID
+	p.Tag = req.Tag
+	p.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// notifyConfigChange 通知已建立长连接的节点重新获取配置，其余节点在下次轮询时生效
+func notifyConfigChange(streams *service.StreamHub) {
+	if streams == nil {
+		return
+	}
+	if n := streams.Broadcast(protocol.CommandReloadConfig); n > 0 {
+		log.Printf("【远程配置】已通知 %d 个节点重新加载配置", n)
+	}
+}
+
+// ListConfigProfiles 获取远程配置列表
+func ListConfigProfiles(repo repository.ProfileStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profiles, err := repo.ListConfigProfiles()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"profiles": profiles,
+			"keys":     protocol.RemoteConfigKeys,
+		})
+	}
+}
+
+// CreateConfigProfile 创建远程配置
+func CreateConfigProfile(repo repository.ProfileStore, streams *service.StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ConfigProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		p := &model.ConfigProfile{}
+		if err := req.apply(p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := repo.CreateConfigProfile(p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		notifyConfigChange(streams)
+		c.JSON(http.StatusCreated, p)
+	}
+}
+
+// UpdateConfigProfile 更新远程配置
+func UpdateConfigProfile(repo repository.ProfileStore, streams *service.StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		var req ConfigProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		p, err := repo.GetConfigProfileByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "配置不存在"})
+			return
+		}
+
+		if err := req.apply(p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := repo.UpdateConfigProfile(p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		notifyConfigChange(streams)
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// DeleteConfigProfile 删除远程配置
+func DeleteConfigProfile(repo repository.ProfileStore, streams *service.StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		if err := repo.DeleteConfigProfile(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		notifyConfigChange(streams)
+		c.JSON(http.StatusOK, gin.H{"id": id})
+	}
+}
+
+// GetAgentConfig 获取节点应生效的配置与 Agent 上报的当前版本，drift 表示两者不一致
+func GetAgentConfig(agentRepo repository.AgentStore, repo repository.ProfileStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		agent, err := agentRepo.GetAgentByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+			return
+		}
+
+		profiles, err := repo.ListConfigProfiles()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		expected := profile.Resolve(profiles, *agent)
+		c.JSON(http.StatusOK, gin.H{
+			"agent_id":         agent.ID,
+			"expected_version": expected.Version,
+			"active_version":   agent.ConfigVersion,
+			"drift":            expected.Version != agent.ConfigVersion,
+			"settings":         expected.Settings,
+		})
+	}
+}
+
+// AgentRemoteConfig 返回节点的远程配置，需经过 AgentAuthMiddleware
+func AgentRemoteConfig(repo repository.ProfileStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		node := c.MustGet("agent").(*model.Agent)
+
+		profiles, err := repo.ListConfigProfiles()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, profile.Resolve(profiles, *node))
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository"
+	"cyber-inspector/internal/rule"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleRequest 阈值规则请求
+type AlertRuleRequest struct {
+	Name     string  `json:"name" binding:"max=64"`
+	Metric   string  `json:"metric" binding:"required,max=64"`
+	Operator string  `json:"operator" binding:"required,oneof=> >= < <= == !="`
+	Value    float64 `json:"value"`
+	For      string  `json:"for"` // 持续时间，如 "10m"
+	Severity string  `json:"severity" binding:"required,oneof=WARNING CRITICAL"`
+	AgentID  uint64  `json:"agent_id"`
+	Tag      string  `json:"tag" binding:"max=64"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// apply 将请求写入规则模型
+func (req *AlertRuleRequest) apply(r *model.AlertRule) error {
+	var forSeconds int
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			return fmt.Errorf("持续时间不能为负数")
+		}
+		forSeconds = int(d / time.Second)
+	}
+
+	r.Name = req.Name
+	r.Metric = req.Metric
+	r.Operator = model.RuleOperator(req.Operator)
+	r.Value = req.Value
+	r.For = forSeconds
+	r.Severity = model.InspectionLevel(req.Severity)
+	r.AgentID = req.AgentID
+	r.Tag = req.Tag
+	r.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// ListAlertRules 获取阈值规则列表
+func ListAlertRules(repo repository.AlertStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := repo.ListAlertRules()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rules":    rules,
+			"defaults": rule.Defaults(config.Conf.Alert),
+		})
+	}
+}
+
+// CreateAlertRule 创建阈值规则
+func CreateAlertRule(repo repository.AlertStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AlertRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		r := &model.AlertRule{}
+		if err := req.apply(r); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "持续时间格式错误"})
+			return
+		}
+
+		if err := repo.CreateAlertRule(r); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, r)
+	}
+}
+
+// UpdateAlertRule 更新阈值规则
+func UpdateAlertRule(repo repository.AlertStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		var req AlertRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		r, err := repo.GetAlertRuleByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "规则不存在"})
+			return
+		}
+
+		if err := req.apply(r); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "持续时间格式错误"})
+			return
+		}
+
+		if err := repo.UpdateAlertRule(r); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, r)
+	}
+}
+
+// DeleteAlertRule 删除阈值规则
+func DeleteAlertRule(repo repository.AlertStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		if err := repo.DeleteAlertRule(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"id": id})
+	}
+}
+
+// GetAgentRules 获取节点的生效规则
+func GetAgentRules(agentRepo repository.AgentStore, repo repository.AlertStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		agent, err := agentRepo.GetAgentByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+			return
+		}
+
+		stored, err := repo.ListAlertRules()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"agent_id": agent.ID,
+			"rules":    rule.Resolve(rule.Defaults(config.Conf.Alert), stored, *agent),
+		})
+	}
+}
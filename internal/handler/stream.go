@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// upgrader 长连接升级器，Agent 不是浏览器，身份由请求签名保证，不校验 Origin
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// AgentStream Agent 长连接，需经过 AgentAuthMiddleware
+func AgentStream(hub *service.StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		node := c.MustGet("agent").(*model.Agent)
+
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("【长连接】节点 %s 升级失败: %v", node.Name, err)
+			return
+		}
+		hub.Serve(node, ws, c.ClientIP())
+	}
+}
+
+// AgentCommandRequest 下发命令请求
+type AgentCommandRequest struct {
+	Command string `json:"command" binding:"required,oneof=inspect reload_config"`
+}
+
+// SendAgentCommand 通过长连接向节点下发命令
+func SendAgentCommand(hub *service.StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		var req AgentCommandRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		cmdID, err := hub.Send(id, req.Command)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"agent_id": id, "command_id": cmdID, "command": req.Command})
+	}
+}
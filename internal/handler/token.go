@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAgentToken 获取节点令牌状态（不返回令牌内容）
+func GetAgentToken(repo repository.AgentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		token, err := repo.GetAgentToken(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点尚未签发令牌"})
+			return
+		}
+
+		c.JSON(http.StatusOK, token)
+	}
+}
+
+// RotateAgentToken 签发或轮换节点令牌，明文令牌只在此处返回一次
+func RotateAgentToken(repo repository.AgentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		if _, err := repo.GetAgentByID(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+			return
+		}
+
+		token, err := agent.GenerateToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+			return
+		}
+
+		sealed, err := agent.SealKey(config.Conf.App.Secret, agent.SigningKey(token))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "加密签名密钥失败"})
+			return
+		}
+
+		if err := repo.SaveAgentToken(id, agent.TokenDigest(token), sealed); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"agent_id": id,
+			"token":    token,
+			"message":  "请将令牌配置到 Agent，令牌不会再次显示",
+		})
+	}
+}
+
+// RevokeAgentToken 吊销节点令牌
+func RevokeAgentToken(repo repository.AgentStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		if err := repo.RevokeAgentToken(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"agent_id": id, "message": "令牌已吊销"})
+	}
+}
@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 以下为 0001 版本的表结构快照，与 model 包解耦，后续模型变更应新增迁移而不是修改此处
+
+type userV1 struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Username  string `gorm:"size:64;uniqueIndex;not null"`
+	Password  string `gorm:"size:255;not null"`
+	Role      string `gorm:"size:20;default:user;index"`
+	Enabled   bool   `gorm:"default:true"`
+	LastLogin time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (userV1) TableName() string { return "users" }
+
+type agentV1 struct {
+	ID            uint64 `gorm:"primaryKey"`
+	Name          string `gorm:"size:64;not null"`
+	IP            string `gorm:"size:15;not null;index"`
+	URL           string `gorm:"size:128;unique;not null"`
+	Enabled       bool   `gorm:"default:true;index"`
+	CheckInterval int    `gorm:"default:300"`
+	APIKey        string `gorm:"size:255"`
+	Status        string `gorm:"size:20;default:unknown;index"`
+	Tags          string `gorm:"size:255"`
+	LastCheckAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (agentV1) TableName() string { return "agents" }
+
+type inspectionV1 struct {
+	ID             uint64  `gorm:"primaryKey"`
+	AgentID        uint64  `gorm:"not null;index"`
+	Hostname       string  `gorm:"size:64;not null"`
+	IP             string  `gorm:"size:15;not null"`
+	RawData        string  `gorm:"type:text"`
+	Analysis       string  `gorm:"type:text"`
+	Alert          bool    `gorm:"default:false;index"`
+	Level          string  `gorm:"size:16;default:OK;index"`
+	CPUUsed        float64 `gorm:"type:decimal(5,2)"`
+	MemoryUsed     float64 `gorm:"type:decimal(5,2)"`
+	DiskUsed       float64 `gorm:"type:decimal(5,2)"`
+	LoadAvg        float64 `gorm:"type:decimal(5,2)"`
+	PingLoss       float64 `gorm:"type:decimal(5,2)"`
+	JournalErr1h   int
+	ProcessCount   int
+	TCPConnections int
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+func (inspectionV1) TableName() string { return "inspections" }
+
+type alertV1 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	AgentID      uint64 `gorm:"not null;index"`
+	InspectionID uint64 `gorm:"not null;index"`
+	Level        string `gorm:"size:16;not null;index"`
+	Title        string `gorm:"size:255;not null"`
+	Summary      string `gorm:"type:text"`
+	Details      string `gorm:"type:text"`
+	Solution     string `gorm:"type:text"`
+	Status       string `gorm:"size:20;default:pending;index"`
+	Notified     bool   `gorm:"default:false"`
+	ResolvedAt   *time.Time
+	CreatedAt    time.Time `gorm:"index"`
+	UpdatedAt    time.Time
+}
+
+func (alertV1) TableName() string { return "alerts" }
+
+type agentTokenV1 struct {
+	ID        uint64 `gorm:"primaryKey"`
+	AgentID   uint64 `gorm:"uniqueIndex;not null"`
+	Token     string `gorm:"size:255;not null"`
+	Enabled   bool   `gorm:"default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (agentTokenV1) TableName() string { return "agent_tokens" }
+
+type loginLogV1 struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Username  string `gorm:"size:64;not null;index"`
+	IP        string `gorm:"size:15;not null"`
+	Status    string `gorm:"size:20;not null"`
+	Message   string `gorm:"size:255"`
+	UserAgent string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (loginLogV1) TableName() string { return "login_logs" }
+
+type alertRuleV1 struct {
+	ID        uint64  `gorm:"primaryKey"`
+	Name      string  `gorm:"size:64"`
+	Metric    string  `gorm:"size:64;not null"`
+	Operator  string  `gorm:"size:4;not null"`
+	Value     float64 `gorm:"not null"`
+	For       int     `gorm:"default:0"`
+	Severity  string  `gorm:"size:16;not null"`
+	AgentID   uint64  `gorm:"default:0;index"`
+	Tag       string  `gorm:"size:64;default:'';index"`
+	Enabled   bool    `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (alertRuleV1) TableName() string { return "alert_rules" }
+
+// initialSchema 基线表结构
+// 对已由旧版 AutoMigrate 或 init.sql 创建的数据库同样适用：缺失的表和列会被补齐
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&userV1{},
+			&agentV1{},
+			&inspectionV1{},
+			&alertV1{},
+			&agentTokenV1{},
+			&loginLogV1{},
+			&alertRuleV1{},
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(
+			&alertRuleV1{},
+			&loginLogV1{},
+			&agentTokenV1{},
+			&alertV1{},
+			&inspectionV1{},
+			&agentV1{},
+			&userV1{},
+		)
+	},
+}
@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type agentV2 struct {
+	CertNotAfter *time.Time
+}
+
+func (agentV2) TableName() string { return "agents" }
+
+// agentCert 记录节点证书过期时间
+var agentCert = Migration{
+	Version: 2,
+	Name:    "agent_cert",
+	Up: func(tx *gorm.DB) error {
+		return addColumns(tx, &agentV2{}, "CertNotAfter")
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropColumns(tx, &agentV2{}, "CertNotAfter")
+	},
+}
@@ -0,0 +1,49 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type enrollmentTokenV3 struct {
+	ID          uint64 `gorm:"primaryKey"`
+	Name        string `gorm:"size:64;not null"`
+	Token       string `gorm:"size:64;uniqueIndex;not null"`
+	Reusable    bool   `gorm:"not null"`
+	AutoApprove bool   `gorm:"not null"`
+	Tags        string `gorm:"size:255"`
+	UsedCount   int    `gorm:"not null;default:0"`
+	Enabled     bool   `gorm:"not null"`
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (enrollmentTokenV3) TableName() string { return "enrollment_tokens" }
+
+type agentV3 struct {
+	OS           string `gorm:"size:64"`
+	Version      string `gorm:"size:32"`
+	Capabilities string `gorm:"size:255"`
+}
+
+func (agentV3) TableName() string { return "agents" }
+
+// enrollment Agent 自注册令牌与节点上报信息
+var enrollment = Migration{
+	Version: 3,
+	Name:    "enrollment",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&enrollmentTokenV3{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &agentV3{}, "OS", "Version", "Capabilities")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &agentV3{}, "OS", "Version", "Capabilities"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&enrollmentTokenV3{})
+	},
+}
@@ -0,0 +1,21 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type agentV4 struct {
+	Mode string `gorm:"size:16;default:pull"`
+}
+
+func (agentV4) TableName() string { return "agents" }
+
+// agentMode 节点巡检模式（拉取/推送）
+var agentMode = Migration{
+	Version: 4,
+	Name:    "agent_mode",
+	Up: func(tx *gorm.DB) error {
+		return addColumns(tx, &agentV4{}, "Mode")
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropColumns(tx, &agentV4{}, "Mode")
+	},
+}
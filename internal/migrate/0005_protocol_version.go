@@ -0,0 +1,33 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type agentV5 struct {
+	ProtocolVersion int `gorm:"not null;default:0"`
+}
+
+func (agentV5) TableName() string { return "agents" }
+
+type inspectionV5 struct {
+	ProtocolVersion int `gorm:"not null;default:0"`
+}
+
+func (inspectionV5) TableName() string { return "inspections" }
+
+// protocolVersion 记录节点与巡检数据的协议版本
+var protocolVersion = Migration{
+	Version: 5,
+	Name:    "protocol_version",
+	Up: func(tx *gorm.DB) error {
+		if err := addColumns(tx, &agentV5{}, "ProtocolVersion"); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV5{}, "ProtocolVersion")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV5{}, "ProtocolVersion"); err != nil {
+			return err
+		}
+		return dropColumns(tx, &agentV5{}, "ProtocolVersion")
+	},
+}
@@ -0,0 +1,21 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type agentV6 struct {
+	Timeout int `gorm:"default:0"`
+}
+
+func (agentV6) TableName() string { return "agents" }
+
+// agentTimeout 节点级拉取超时
+var agentTimeout = Migration{
+	Version: 6,
+	Name:    "agent_timeout",
+	Up: func(tx *gorm.DB) error {
+		return addColumns(tx, &agentV6{}, "Timeout")
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropColumns(tx, &agentV6{}, "Timeout")
+	},
+}
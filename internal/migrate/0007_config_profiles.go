@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type configProfileV7 struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Name      string `gorm:"size:64;not null"`
+	Settings  string `gorm:"type:text;not null"`
+	AgentID   uint64 `gorm:"default:0;index"`
+	Tag       string `gorm:"size:64;default:'';index"`
+	Enabled   bool   `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (configProfileV7) TableName() string { return "config_profiles" }
+
+type agentV7 struct {
+	ConfigVersion string `gorm:"size:16;default:''"`
+}
+
+func (agentV7) TableName() string { return "agents" }
+
+type inspectionV7 struct {
+	ConfigVersion string `gorm:"size:16;default:''"`
+}
+
+func (inspectionV7) TableName() string { return "inspections" }
+
+// configProfiles Agent 远程配置与节点生效的配置版本
+var configProfiles = Migration{
+	Version: 7,
+	Name:    "config_profiles",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&configProfileV7{}); err != nil {
+			return err
+		}
+		if err := addColumns(tx, &agentV7{}, "ConfigVersion"); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV7{}, "ConfigVersion")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV7{}, "ConfigVersion"); err != nil {
+			return err
+		}
+		if err := dropColumns(tx, &agentV7{}, "ConfigVersion"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&configProfileV7{})
+	},
+}
@@ -0,0 +1,45 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionMountV8 struct {
+	ID            uint64 `gorm:"primaryKey"`
+	InspectionID  uint64 `gorm:"not null;index"`
+	AgentID       uint64 `gorm:"not null;index"`
+	Device        string `gorm:"size:255"`
+	Path          string `gorm:"size:255;not null"`
+	FSType        string `gorm:"size:32"`
+	Size          uint64
+	Used          uint64
+	Available     uint64
+	UsedPercent   float64 `gorm:"type:decimal(5,2)"`
+	Inodes        uint64
+	InodesUsed    uint64
+	InodesPercent float64 `gorm:"type:decimal(5,2)"`
+}
+
+func (inspectionMountV8) TableName() string { return "inspection_mounts" }
+
+type inspectionV8 struct {
+	InodesUsed float64 `gorm:"type:decimal(5,2)"`
+}
+
+func (inspectionV8) TableName() string { return "inspections" }
+
+// inspectionMounts 按挂载点记录磁盘与 inode 使用情况
+var inspectionMounts = Migration{
+	Version: 8,
+	Name:    "inspection_mounts",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionMountV8{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV8{}, "InodesUsed")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV8{}, "InodesUsed"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionMountV8{})
+	},
+}
@@ -0,0 +1,41 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionServiceV9 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Name         string `gorm:"size:128;not null"`
+	LoadState    string `gorm:"size:32"`
+	ActiveState  string `gorm:"size:32"`
+	SubState     string `gorm:"size:32"`
+	Restarts     int
+	Uptime       int64
+}
+
+func (inspectionServiceV9) TableName() string { return "inspection_services" }
+
+type inspectionV9 struct {
+	FailedUnits int `gorm:"not null;default:0"`
+}
+
+func (inspectionV9) TableName() string { return "inspections" }
+
+// inspectionServices 记录关注的 systemd 单元状态与 failed 单元数
+var inspectionServices = Migration{
+	Version: 9,
+	Name:    "inspection_services",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionServiceV9{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV9{}, "FailedUnits")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV9{}, "FailedUnits"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionServiceV9{})
+	},
+}
@@ -0,0 +1,59 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionProcessV10 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Top          string `gorm:"size:16;not null"`
+	Rank         int
+	PID          int
+	Name         string  `gorm:"size:128"`
+	User         string  `gorm:"size:64"`
+	CPU          float64 `gorm:"type:decimal(7,2)"`
+	Memory       float64 `gorm:"type:decimal(5,2)"`
+	RSS          uint64
+	Threads      int
+	Command      string `gorm:"size:256"`
+}
+
+func (inspectionProcessV10) TableName() string { return "inspection_processes" }
+
+type inspectionProcessWatchV10 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Name         string `gorm:"size:64;not null"`
+	Pattern      string `gorm:"size:255"`
+	Min          int
+	Max          int
+	Count        int
+}
+
+func (inspectionProcessWatchV10) TableName() string { return "inspection_process_watches" }
+
+type inspectionV10 struct {
+	ThreadCount int `gorm:"not null;default:0"`
+	ZombieCount int `gorm:"not null;default:0"`
+}
+
+func (inspectionV10) TableName() string { return "inspections" }
+
+// inspectionProcesses 进程统计、资源占用排行与进程守护结果
+var inspectionProcesses = Migration{
+	Version: 10,
+	Name:    "inspection_processes",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionProcessV10{}, &inspectionProcessWatchV10{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV10{}, "ThreadCount", "ZombieCount")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV10{}, "ThreadCount", "ZombieCount"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionProcessWatchV10{}, &inspectionProcessV10{})
+	},
+}
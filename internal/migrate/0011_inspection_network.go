@@ -0,0 +1,46 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionInterfaceV11 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Name         string `gorm:"size:32;not null"`
+	RxBytes      uint64
+	TxBytes      uint64
+	RxRate       float64
+	TxRate       float64
+	RxErrors     uint64
+	TxErrors     uint64
+	RxDropped    uint64
+	TxDropped    uint64
+}
+
+func (inspectionInterfaceV11) TableName() string { return "inspection_interfaces" }
+
+type inspectionV11 struct {
+	ConntrackUsed float64 `gorm:"type:decimal(5,2)"`
+	TCPStates     string  `gorm:"type:text"`
+	ListenPorts   string  `gorm:"type:text"`
+}
+
+func (inspectionV11) TableName() string { return "inspections" }
+
+// inspectionNetwork 网卡流量、TCP 连接状态、监听端口与连接跟踪表使用率
+var inspectionNetwork = Migration{
+	Version: 11,
+	Name:    "inspection_network",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionInterfaceV11{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV11{}, "ConntrackUsed", "TCPStates", "ListenPorts")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV11{}, "ConntrackUsed", "TCPStates", "ListenPorts"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionInterfaceV11{})
+	},
+}
@@ -0,0 +1,30 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionProbeV12 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Name         string `gorm:"size:64;not null"`
+	Type         string `gorm:"size:16;not null"`
+	Target       string `gorm:"size:255"`
+	Success      bool
+	Latency      float64 `gorm:"type:decimal(10,2)"`
+	Loss         float64 `gorm:"type:decimal(5,2)"`
+	Error        string  `gorm:"size:255"`
+}
+
+func (inspectionProbeV12) TableName() string { return "inspection_probes" }
+
+// inspectionProbes 连通性探测结果
+var inspectionProbes = Migration{
+	Version: 12,
+	Name:    "inspection_probes",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&inspectionProbeV12{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&inspectionProbeV12{})
+	},
+}
@@ -0,0 +1,34 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type inspectionCertificateV13 struct {
+	ID           uint64    `gorm:"primaryKey"`
+	InspectionID uint64    `gorm:"not null;index"`
+	AgentID      uint64    `gorm:"not null;index"`
+	Source       string    `gorm:"size:255;not null"`
+	Subject      string    `gorm:"size:255"`
+	Issuer       string    `gorm:"size:255"`
+	SANs         string    `gorm:"column:sans;type:text"`
+	NotAfter     time.Time `gorm:"index"`
+	DaysLeft     int
+	Error        string `gorm:"size:255"`
+}
+
+func (inspectionCertificateV13) TableName() string { return "inspection_certificates" }
+
+// inspectionCertificates 证书到期检查结果
+var inspectionCertificates = Migration{
+	Version: 13,
+	Name:    "inspection_certificates",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&inspectionCertificateV13{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&inspectionCertificateV13{})
+	},
+}
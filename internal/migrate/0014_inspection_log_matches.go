@@ -0,0 +1,27 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionLogMatchV14 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Rule         string `gorm:"size:64;not null"`
+	Severity     string `gorm:"size:16"`
+	Count        int
+	Samples      string `gorm:"type:text"`
+}
+
+func (inspectionLogMatchV14) TableName() string { return "inspection_log_matches" }
+
+// inspectionLogMatches 日志规则匹配计数与样例
+var inspectionLogMatches = Migration{
+	Version: 14,
+	Name:    "inspection_log_matches",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&inspectionLogMatchV14{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&inspectionLogMatchV14{})
+	},
+}
@@ -0,0 +1,40 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionFindingV15 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	FindingID    string `gorm:"size:255;not null"`
+	Check        string `gorm:"size:64;not null"`
+	Severity     string `gorm:"size:16"`
+	Title        string `gorm:"size:255"`
+	Detail       string `gorm:"size:255"`
+}
+
+func (inspectionFindingV15) TableName() string { return "inspection_findings" }
+
+type inspectionV15 struct {
+	ComplianceScore *float64 `gorm:"type:decimal(5,2)"`
+}
+
+func (inspectionV15) TableName() string { return "inspections" }
+
+// inspectionFindings 安全基线审计问题与合规得分
+var inspectionFindings = Migration{
+	Version: 15,
+	Name:    "inspection_findings",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionFindingV15{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV15{}, "ComplianceScore")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV15{}, "ComplianceScore"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionFindingV15{})
+	},
+}
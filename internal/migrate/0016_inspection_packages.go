@@ -0,0 +1,61 @@
+package migrate
+
+import "gorm.io/gorm"
+
+type inspectionPackageV16 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	Name         string `gorm:"size:128;not null"`
+	Version      string `gorm:"size:64"`
+	Arch         string `gorm:"size:16"`
+	Source       string `gorm:"size:128"`
+	Update       string `gorm:"size:64"`
+	Security     bool
+}
+
+func (inspectionPackageV16) TableName() string { return "inspection_packages" }
+
+type inspectionVulnV16 struct {
+	ID           uint64 `gorm:"primaryKey"`
+	InspectionID uint64 `gorm:"not null;index"`
+	AgentID      uint64 `gorm:"not null;index"`
+	VulnID       string `gorm:"size:64;not null"`
+	Aliases      string `gorm:"type:text"`
+	Package      string `gorm:"size:128;not null"`
+	Version      string `gorm:"size:64"`
+	Fixed        string `gorm:"size:64"`
+	Severity     string `gorm:"size:16"`
+	Summary      string `gorm:"size:255"`
+}
+
+func (inspectionVulnV16) TableName() string { return "inspection_vulnerabilities" }
+
+type inspectionV16 struct {
+	PackageManager  string `gorm:"size:8;default:''"`
+	Distro          string `gorm:"size:32;default:''"`
+	DistroRelease   string `gorm:"size:32;default:''"`
+	PackageCount    int    `gorm:"not null;default:0"`
+	PackageUpdates  int    `gorm:"not null;default:0"`
+	SecurityUpdates int    `gorm:"not null;default:0"`
+}
+
+func (inspectionV16) TableName() string { return "inspections" }
+
+// inspectionPackages 软件包数量、待更新的软件包与漏洞库匹配结果
+var inspectionPackages = Migration{
+	Version: 16,
+	Name:    "inspection_packages",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&inspectionPackageV16{}, &inspectionVulnV16{}); err != nil {
+			return err
+		}
+		return addColumns(tx, &inspectionV16{}, "PackageManager", "Distro", "DistroRelease", "PackageCount", "PackageUpdates", "SecurityUpdates")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := dropColumns(tx, &inspectionV16{}, "PackageManager", "Distro", "DistroRelease", "PackageCount", "PackageUpdates", "SecurityUpdates"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&inspectionVulnV16{}, &inspectionPackageV16{})
+	},
+}
@@ -0,0 +1,226 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type systemConfigV17 struct {
+	ID          uint   `gorm:"primaryKey"`
+	ConfigKey   string `gorm:"size:64;uniqueIndex;not null"`
+	ConfigValue string `gorm:"type:text"`
+	Description string `gorm:"size:255"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (systemConfigV17) TableName() string { return "system_configs" }
+
+// systemConfigsV17 init.sql 中的默认系统配置，已存在的键保持不变
+var systemConfigsV17 = []systemConfigV17{
+	{ConfigKey: "site_name", ConfigValue: "Cyber Inspector", Description: "站点名称"},
+	{ConfigKey: "site_version", ConfigValue: "2.0.0", Description: "系统版本"},
+	{ConfigKey: "check_interval", ConfigValue: "300", Description: "默认巡检间隔（秒）"},
+	{ConfigKey: "alert_enabled", ConfigValue: "1", Description: "是否启用告警"},
+	{ConfigKey: "alert_cooldown", ConfigValue: "300", Description: "告警冷却时间（秒）"},
+	{ConfigKey: "mail_enabled", ConfigValue: "0", Description: "是否启用邮件通知"},
+	{ConfigKey: "theme", ConfigValue: "dark", Description: "默认主题"},
+}
+
+// enumColumnsV17 init.sql 中定义为 ENUM 的列，按 0001 快照转换为 VARCHAR，新增的取值（如节点的 pending 状态）才能写入
+var enumColumnsV17 = []struct {
+	table interface{}
+	field string
+}{
+	{&userV1{}, "Role"},
+	{&agentV1{}, "Status"},
+	{&inspectionV1{}, "Level"},
+	{&alertV1{}, "Level"},
+	{&alertV1{}, "Status"},
+	{&loginLogV1{}, "Status"},
+}
+
+// foreignKeysV17 init.sql 中的外键，删除节点时级联删除巡检记录与告警
+var foreignKeysV17 = []struct {
+	name, table, column, refTable string
+}{
+	{"fk_inspections_agent", "inspections", "agent_id", "agents"},
+	{"fk_alerts_agent", "alerts", "agent_id", "agents"},
+	{"fk_alerts_inspection", "alerts", "inspection_id", "inspections"},
+}
+
+// viewsV17 init.sql 中的统计视图，按创建顺序排列
+var viewsV17 = []struct {
+	name  string
+	query func(dialect string) string
+}{
+	{"latest_inspections", func(string) string {
+		return `SELECT i.id, i.agent_id, i.hostname, i.ip, i.alert, i.level, i.cpu_used, i.memory_used, i.disk_used, i.load_avg, i.ping_loss, i.created_at
+FROM inspections i
+INNER JOIN (SELECT agent_id, MAX(id) AS max_id FROM inspections GROUP BY agent_id) t ON i.id = t.max_id`
+	}},
+	{"agent_stats", func(string) string {
+		return `SELECT a.id, a.name, a.ip, a.enabled, a.status, a.last_check_at,
+    COALESCE(i.level, 'unknown') AS last_level,
+    COALESCE(i.cpu_used, 0) AS cpu_used,
+    COALESCE(i.memory_used, 0) AS memory_used,
+    COALESCE(i.disk_used, 0) AS disk_used,
+    COALESCE(i.created_at, a.created_at) AS last_inspection_at
+FROM agents a
+LEFT JOIN latest_inspections i ON a.id = i.agent_id`
+	}},
+	{"alert_stats", func(dialect string) string {
+		since := "DATE_SUB(CURDATE(), INTERVAL 30 DAY)"
+		switch dialect {
+		case "postgres":
+			since = "CURRENT_DATE - INTERVAL '30 days'"
+		case "sqlite":
+			since = "DATE('now', '-30 day')"
+		}
+		return `SELECT DATE(created_at) AS date, level, COUNT(*) AS count
+FROM alerts
+WHERE created_at >= ` + since + `
+GROUP BY DATE(created_at), level`
+	}},
+}
+
+// legacySchema 补齐 init.sql 建库时有、0001 快照中没有的部分：ENUM 列转换、系统配置表、外键与统计视图。
+// 对由 init.sql、旧版 AutoMigrate 创建的数据库和新数据库均可执行
+var legacySchema = Migration{
+	Version: 17,
+	Name:    "legacy_schema",
+	Up: func(tx *gorm.DB) error {
+		dialect := tx.Dialector.Name()
+		if dialect == "mysql" {
+			if err := convertEnums(tx); err != nil {
+				return err
+			}
+		}
+
+		m := tx.Migrator()
+		if !m.HasTable(&systemConfigV17{}) {
+			if err := m.CreateTable(&systemConfigV17{}); err != nil {
+				return err
+			}
+		}
+		rows := append([]systemConfigV17(nil), systemConfigsV17...)
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return err
+		}
+
+		// SQLite 为已有表添加外键需要重建表，会丢失索引，因此只在 MySQL 与 PostgreSQL 上添加
+		if dialect != "sqlite" {
+			for _, fk := range foreignKeysV17 {
+				if err := addForeignKey(tx, fk.name, fk.table, fk.column, fk.refTable); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, v := range viewsV17 {
+			if err := m.DropView(v.name); err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("CREATE VIEW %s AS %s", v.name, v.query(dialect))).Error; err != nil {
+				return fmt.Errorf("创建视图 %s 失败: %w", v.name, err)
+			}
+		}
+		return nil
+	},
+	// ENUM 列不再转换回去，旧版本同样可以读写 VARCHAR
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for i := len(viewsV17) - 1; i >= 0; i-- {
+			if err := m.DropView(viewsV17[i].name); err != nil {
+				return err
+			}
+		}
+
+		if dialect := tx.Dialector.Name(); dialect != "sqlite" {
+			drop := "ALTER TABLE %s DROP CONSTRAINT %s"
+			if dialect == "mysql" {
+				drop = "ALTER TABLE %s DROP FOREIGN KEY %s"
+			}
+			for _, fk := range foreignKeysV17 {
+				if !hasConstraint(tx, fk.table, fk.name) {
+					continue
+				}
+				if err := tx.Exec(fmt.Sprintf(drop, fk.table, fk.name)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return m.DropTable(&systemConfigV17{})
+	},
+}
+
+// convertEnums 将仍为 ENUM 类型的列改为快照中的 VARCHAR
+func convertEnums(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, c := range enumColumnsV17 {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(c.table); err != nil {
+			return err
+		}
+		column := stmt.Schema.LookUpField(c.field).DBName
+
+		types, err := m.ColumnTypes(c.table)
+		if err != nil {
+			return err
+		}
+		for _, t := range types {
+			if t.Name() != column || !strings.EqualFold(t.DatabaseTypeName(), "enum") {
+				continue
+			}
+			if err := m.AlterColumn(c.table, c.field); err != nil {
+				return fmt.Errorf("转换 %s.%s 失败: %w", stmt.Table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addForeignKey 列上没有外键时添加级联删除的外键，添加前清理引用不存在的记录
+func addForeignKey(tx *gorm.DB, name, table, column, refTable string) error {
+	if hasForeignKey(tx, table, column) {
+		return nil
+	}
+	orphans := fmt.Sprintf("DELETE FROM %s WHERE %s NOT IN (SELECT id FROM %s)", table, column, refTable)
+	if err := tx.Exec(orphans).Error; err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(id) ON DELETE CASCADE",
+		table, name, column, refTable)).Error
+}
+
+// currentSchema 外键所在的 schema：MySQL 为当前数据库，PostgreSQL 为当前 schema
+func currentSchema(tx *gorm.DB) string {
+	if tx.Dialector.Name() == "mysql" {
+		return "DATABASE()"
+	}
+	return "CURRENT_SCHEMA()"
+}
+
+// hasForeignKey 列上是否已有外键，init.sql 创建的外键名称由数据库生成
+func hasForeignKey(tx *gorm.DB, table, column string) bool {
+	var count int64
+	tx.Raw(`SELECT COUNT(*) FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+  ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name
+WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = `+currentSchema(tx)+` AND tc.table_name = ? AND kcu.column_name = ?`,
+		table, column).Row().Scan(&count)
+	return count > 0
+}
+
+// hasConstraint 表上是否有指定名称的约束
+func hasConstraint(tx *gorm.DB, table, name string) bool {
+	var count int64
+	tx.Raw(`SELECT COUNT(*) FROM information_schema.table_constraints
+WHERE table_schema = `+currentSchema(tx)+` AND table_name = ? AND constraint_name = ?`,
+		table, name).Row().Scan(&count)
+	return count > 0
+}
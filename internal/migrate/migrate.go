@@ -0,0 +1,140 @@
+// file: internal/migrate/migrate.go
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 单个版本的数据库迁移
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// record 已执行的迁移记录
+type record struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:128;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 表名
+func (record) TableName() string {
+	return "migrations"
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrations 返回按版本排序的全部迁移
+func Migrations() []Migration {
+	list := make([]Migration, len(migrations))
+	copy(list, migrations)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// applied 读取已执行的迁移版本
+func applied(db *gorm.DB) (map[int]record, error) {
+	if err := db.AutoMigrate(&record{}); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+
+	var records []record
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]record, len(records))
+	for _, r := range records {
+		done[r.Version] = r
+	}
+	return done, nil
+}
+
+// Up 执行全部未执行的迁移，返回本次执行的迁移
+func Up(db *gorm.DB) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range Migrations() {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	list := Migrations()
+	var rolled []Migration
+	for i := len(list) - 1; i >= 0 && len(rolled) < steps; i-- {
+		m := list[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&record{}, m.Version).Error
+		})
+		if err != nil {
+			return rolled, fmt.Errorf("回滚 %04d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		rolled = append(rolled, m)
+	}
+	return rolled, nil
+}
+
+// List 返回所有迁移的执行状态
+func List(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Status
+	for _, m := range Migrations() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if r, ok := done[m.Version]; ok {
+			at := r.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 使用临时 SQLite 文件
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Open(config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		DSN:    filepath.Join(t.TempDir(), "test.db"),
+	}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	return db
+}
+
+// tables 当前数据库中除迁移记录外的表
+func tables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	list, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, name := range list {
+		if name != "migrations" && name != "sqlite_sequence" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestUpDownRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	all := Migrations()
+
+	ran, err := Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(all) {
+		t.Fatalf("应执行全部 %d 个迁移，实际 %d", len(all), len(ran))
+	}
+	if ran, err := Up(db); err != nil || len(ran) != 0 {
+		t.Fatalf("重复执行不应有新的迁移: %v %v", ran, err)
+	}
+
+	status, err := List(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Errorf("迁移 %04d_%s 未标记为已执行", s.Version, s.Name)
+		}
+	}
+
+	var configs int64
+	db.Table("system_configs").Count(&configs)
+	if configs != int64(len(systemConfigsV17)) {
+		t.Errorf("默认系统配置 = %d", configs)
+	}
+	for _, v := range viewsV17 {
+		var n int64
+		if err := db.Table(v.name).Count(&n).Error; err != nil {
+			t.Errorf("视图 %s 不可用: %v", v.name, err)
+		}
+	}
+
+	// 逐个回滚，每一步都应成功
+	for i := len(all) - 1; i >= 0; i-- {
+		rolled, err := Down(db, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rolled) != 1 || rolled[0].Version != all[i].Version {
+			t.Fatalf("应回滚 %04d，实际 %v", all[i].Version, rolled)
+		}
+	}
+	if left := tables(t, db); len(left) != 0 {
+		t.Errorf("全部回滚后应只剩迁移记录表: %v", left)
+	}
+	status, _ = List(db)
+	for _, s := range status {
+		if s.Applied {
+			t.Errorf("迁移 %04d_%s 应为未执行", s.Version, s.Name)
+		}
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("回滚后重新执行失败: %v", err)
+	}
+}
+
+func TestUpgradeLegacySchema(t *testing.T) {
+	db := openTestDB(t)
+
+	// 旧版 AutoMigrate 建立的表结构与 init.sql 的系统配置表，已有数据
+	if err := db.AutoMigrate(&agentV1{}, &inspectionV1{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{
+		`CREATE TABLE system_configs (id INTEGER PRIMARY KEY AUTOINCREMENT, config_key VARCHAR(64) UNIQUE NOT NULL,
+			config_value TEXT, description VARCHAR(255), created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO agents (name, ip, url, status) VALUES ('web-01', '10.0.0.1', 'http://10.0.0.1:8083', 'online')`,
+		`INSERT INTO inspections (agent_id, hostname, ip, level, cpu_used) VALUES (1, 'web-01', '10.0.0.1', 'WARNING', 42.5)`,
+		`INSERT INTO system_configs (config_key, config_value) VALUES ('theme', 'light')`,
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("升级旧数据库失败: %v", err)
+	}
+
+	// 已有数据保留，后续迁移的列已补齐
+	if !db.Migrator().HasColumn(&agentV3{}, "Capabilities") || !db.Migrator().HasColumn(&inspectionV16{}, "PackageManager") {
+		t.Error("旧表应补齐新增的列")
+	}
+	var stats struct {
+		Name      string
+		LastLevel string
+		CPUUsed   float64
+	}
+	if err := db.Table("agent_stats").Select("name, last_level, cpu_used").Take(&stats).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stats.Name != "web-01" || stats.LastLevel != "WARNING" || stats.CPUUsed != 42.5 {
+		t.Errorf("agent_stats = %+v", stats)
+	}
+
+	// 已修改的系统配置保持不变，缺少的默认配置补齐
+	var theme string
+	db.Table("system_configs").Select("config_value").Where("config_key = ?", "theme").Row().Scan(&theme)
+	var configs int64
+	db.Table("system_configs").Count(&configs)
+	if theme != "light" || configs != int64(len(systemConfigsV17)) {
+		t.Errorf("theme = %q, configs = %d", theme, configs)
+	}
+}
@@ -0,0 +1,52 @@
+package migrate
+
+import "gorm.io/gorm"
+
+// migrations 已注册的迁移，新增迁移追加到末尾，版本号不可复用
+var migrations = []Migration{
+	initialSchema,
+	agentCert,
+	enrollment,
+	agentMode,
+	protocolVersion,
+	agentTimeout,
+	configProfiles,
+	inspectionMounts,
+	inspectionServices,
+	inspectionProcesses,
+	inspectionNetwork,
+	inspectionProbes,
+	inspectionCertificates,
+	inspectionLogMatches,
+	inspectionFindings,
+	inspectionPackages,
+	legacySchema,
+}
+
+// addColumns 添加不存在的列
+func addColumns(tx *gorm.DB, table interface{}, fields ...string) error {
+	m := tx.Migrator()
+	for _, f := range fields {
+		if m.HasColumn(table, f) {
+			continue
+		}
+		if err := m.AddColumn(table, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumns 删除存在的列
+func dropColumns(tx *gorm.DB, table interface{}, fields ...string) error {
+	m := tx.Migrator()
+	for _, f := range fields {
+		if !m.HasColumn(table, f) {
+			continue
+		}
+		if err := m.DropColumn(table, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // AgentStatus Agent状态
 type AgentStatus string
@@ -9,6 +12,15 @@ const (
 	AgentOnline  AgentStatus = "online"
 	AgentOffline AgentStatus = "offline"
 	AgentUnknown AgentStatus = "unknown"
+	AgentPending AgentStatus = "pending" // 自注册后等待管理员审批
+)
+
+// AgentMode 巡检数据获取方式
+type AgentMode string
+
+const (
+	AgentModePull AgentMode = "pull" // Master 定时拉取
+	AgentModePush AgentMode = "push" // Agent 主动推送
 )
 
 // Agent Agent节点模型
@@ -19,12 +31,21 @@ type Agent struct {
 	URL           string      `gorm:"size:128;unique;not null" json:"url"`   // Agent URL
 	Enabled       bool        `gorm:"default:true" json:"enabled"`           // 是否启用
 	CheckInterval int         `gorm:"default:300" json:"check_interval"`     // 巡检间隔（秒）
+	Timeout       int         `gorm:"default:0" json:"timeout"`              // 拉取超时（秒），0 表示使用全局配置
 	APIKey        string      `gorm:"size:255" json:"-"`                     // API密钥
 	Status        AgentStatus `gorm:"size:20;default:unknown" json:"status"` // 节点状态
+	Tags          string      `gorm:"size:255" json:"tags"`                  // 标签（逗号分隔）
 	//LastCheckAt   time.Time   `json:"last_check_at"`
-	LastCheckAt *time.Time `gorm:"default:null;column:last_check_at" json:"last_check_at,omitempty"`
-	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	LastCheckAt     *time.Time `gorm:"default:null;column:last_check_at" json:"last_check_at,omitempty"`
+	CertNotAfter    *time.Time `gorm:"default:null" json:"cert_not_after,omitempty"` // 节点证书过期时间
+	OS              string     `gorm:"size:64" json:"os"`                            // 操作系统（自注册上报）
+	Version         string     `gorm:"size:32" json:"version"`                       // Agent 版本（自注册上报）
+	Capabilities    string     `gorm:"size:255" json:"capabilities"`                 // 支持的能力（逗号分隔）
+	Mode            AgentMode  `gorm:"size:16;default:pull" json:"mode"`             // 巡检数据获取方式
+	ProtocolVersion int        `gorm:"not null;default:0" json:"protocol_version"`   // 最近一次上报使用的协议版本，0 表示未知
+	ConfigVersion   string     `gorm:"size:16;default:''" json:"config_version"`     // Agent 当前生效的远程配置版本
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 // TableName 表名
@@ -32,6 +53,25 @@ func (Agent) TableName() string {
 	return "agents"
 }
 
+// PullTimeout 节点拉取超时，未单独设置时使用 fallback
+func (a Agent) PullTimeout(fallback time.Duration) time.Duration {
+	if a.Timeout > 0 {
+		return time.Duration(a.Timeout) * time.Second
+	}
+	return fallback
+}
+
+// TagList 返回节点标签列表
+func (a Agent) TagList() []string {
+	var tags []string
+	for _, t := range strings.Split(a.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
 // InspectionLevel 巡检级别
 type InspectionLevel string
 
@@ -43,24 +83,53 @@ const (
 
 // Inspection 巡检记录模型
 type Inspection struct {
-	ID             uint64          `gorm:"primaryKey" json:"id"`
-	AgentID        uint64          `gorm:"not null;index" json:"agent_id"`       // Agent ID
-	Hostname       string          `gorm:"size:64;not null" json:"hostname"`     // 主机名
-	IP             string          `gorm:"size:15;not null" json:"ip"`           // IP地址
-	RawData        string          `gorm:"type:text" json:"raw_data"`            // 原始数据
-	Analysis       string          `gorm:"type:text" json:"analysis"`            // 分析结果
-	Alert          bool            `gorm:"default:false" json:"alert"`           // 是否告警
-	Level          InspectionLevel `gorm:"size:16;default:OK" json:"level"`      // 告警级别
-	CPUUsed        float64         `gorm:"type:decimal(5,2)" json:"cpu_used"`    // CPU使用率
-	MemoryUsed     float64         `gorm:"type:decimal(5,2)" json:"memory_used"` // 内存使用率
-	DiskUsed       float64         `gorm:"type:decimal(5,2)" json:"disk_used"`   // 磁盘使用率
-	LoadAvg        float64         `gorm:"type:decimal(5,2)" json:"load_avg"`    // 平均负载
-	PingLoss       float64         `gorm:"type:decimal(5,2)" json:"ping_loss"`   // 网络丢包率
-	JournalErr1h   int             `json:"journal_err_1h"`                       // 1小时内错误日志数
-	ProcessCount   int             `json:"process_count"`                        // 进程数
-	TCPConnections int             `json:"tcp_connections"`                      // TCP连接数
-	CreatedAt      time.Time       `gorm:"autoCreateTime" json:"created_at"`
-	Agent          Agent           `gorm:"foreignKey:AgentID" json:"-"`
+	ID              uint64                   `gorm:"primaryKey" json:"id"`
+	AgentID         uint64                   `gorm:"not null;index" json:"agent_id"`                          // Agent ID
+	Hostname        string                   `gorm:"size:64;not null" json:"hostname"`                        // 主机名
+	IP              string                   `gorm:"size:15;not null" json:"ip"`                              // IP地址
+	RawData         string                   `gorm:"type:text" json:"raw_data"`                               // 原始数据
+	Analysis        string                   `gorm:"type:text" json:"analysis"`                               // 分析结果
+	Alert           bool                     `gorm:"default:false" json:"alert"`                              // 是否告警
+	Level           InspectionLevel          `gorm:"size:16;default:OK" json:"level"`                         // 告警级别
+	CPUUsed         float64                  `gorm:"type:decimal(5,2)" json:"cpu_used"`                       // CPU使用率
+	MemoryUsed      float64                  `gorm:"type:decimal(5,2)" json:"memory_used"`                    // 内存使用率
+	DiskUsed        float64                  `gorm:"type:decimal(5,2)" json:"disk_used"`                      // 磁盘使用率
+	LoadAvg         float64                  `gorm:"type:decimal(5,2)" json:"load_avg"`                       // 平均负载
+	PingLoss        float64                  `gorm:"type:decimal(5,2)" json:"ping_loss"`                      // 网络丢包率
+	JournalErr1h    int                      `json:"journal_err_1h"`                                          // 1小时内错误日志数
+	ProcessCount    int                      `json:"process_count"`                                           // 进程数
+	ThreadCount     int                      `gorm:"not null;default:0" json:"thread_count"`                  // 线程数
+	ZombieCount     int                      `gorm:"not null;default:0" json:"zombie_count"`                  // 僵尸进程数
+	TCPConnections  int                      `json:"tcp_connections"`                                         // TCP连接数
+	ProtocolVersion int                      `gorm:"not null;default:0" json:"protocol_version"`              // 上报数据的协议版本
+	ConfigVersion   string                   `gorm:"size:16;default:''" json:"config_version"`                // 采集时生效的远程配置版本
+	InodesUsed      float64                  `gorm:"type:decimal(5,2)" json:"inodes_used"`                    // 最高的 inode 使用率
+	FailedUnits     int                      `gorm:"not null;default:0" json:"failed_units"`                  // 处于 failed 状态的 systemd 单元数
+	ConntrackUsed   float64                  `gorm:"type:decimal(5,2)" json:"conntrack_used"`                 // 连接跟踪表使用率
+	TCPStates       map[string]int           `gorm:"serializer:json;type:text" json:"tcp_states,omitempty"`   // 各状态的 TCP 连接数
+	ListenPorts     []int                    `gorm:"serializer:json;type:text" json:"listen_ports,omitempty"` // 监听中的 TCP 端口
+	ComplianceScore *float64                 `gorm:"type:decimal(5,2)" json:"compliance_score"`               // 安全基线合规得分，未审计时为空
+	PackageManager  string                   `gorm:"size:8;default:''" json:"package_manager"`                // dpkg / rpm，未采集软件包时为空
+	Distro          string                   `gorm:"size:32;default:''" json:"distro"`                        // 发行版，如 debian、rocky
+	DistroRelease   string                   `gorm:"size:32;default:''" json:"distro_release"`                // 发行版版本，如 12、9.3
+	PackageCount    int                      `gorm:"not null;default:0" json:"package_count"`                 // 已安装的软件包数
+	PackageUpdates  int                      `gorm:"not null;default:0" json:"package_updates"`               // 有可用更新的软件包数
+	SecurityUpdates int                      `gorm:"not null;default:0" json:"security_updates"`              // 有安全更新的软件包数
+	CreatedAt       time.Time                `gorm:"autoCreateTime" json:"created_at"`
+	Agent           Agent                    `gorm:"foreignKey:AgentID" json:"-"`
+	Mounts          []InspectionMount        `gorm:"foreignKey:InspectionID" json:"mounts,omitempty"`          // 各文件系统的使用情况
+	Services        []InspectionService      `gorm:"foreignKey:InspectionID" json:"services,omitempty"`        // 关注的 systemd 单元状态
+	Processes       []InspectionProcess      `gorm:"foreignKey:InspectionID" json:"processes,omitempty"`       // 资源占用最高的进程
+	ProcessWatches  []InspectionProcessWatch `gorm:"foreignKey:InspectionID" json:"process_watches,omitempty"` // 进程守护结果
+	Interfaces      []InspectionInterface    `gorm:"foreignKey:InspectionID" json:"interfaces,omitempty"`      // 各网卡的流量与错误计数
+	Probes          []InspectionProbe        `gorm:"foreignKey:InspectionID" json:"probes,omitempty"`          // 连通性探测结果
+	Certificates    []InspectionCertificate  `gorm:"foreignKey:InspectionID" json:"certificates,omitempty"`    // 证书到期情况
+	LogMatches      []InspectionLogMatch     `gorm:"foreignKey:InspectionID" json:"log_matches,omitempty"`     // 日志规则匹配情况
+	Findings        []InspectionFinding      `gorm:"foreignKey:InspectionID" json:"findings,omitempty"`        // 安全基线审计发现的问题
+	Packages        []InspectionPackage      `gorm:"foreignKey:InspectionID" json:"packages,omitempty"`        // 有可用更新的软件包
+	Vulnerabilities []InspectionVuln         `gorm:"foreignKey:InspectionID" json:"vulnerabilities,omitempty"` // 与漏洞库匹配的已安装软件包
+	// 全部已安装的软件包，只用于匹配漏洞库，不保存
+	InstalledPackages []InspectionPackage `gorm:"-" json:"-"`
 }
 
 // TableName 表名
@@ -68,6 +137,166 @@ func (Inspection) TableName() string {
 	return "inspections"
 }
 
+// InspectionMount 巡检时各文件系统的使用情况，容量单位为字节
+type InspectionMount struct {
+	ID            uint64  `gorm:"primaryKey" json:"id"`
+	InspectionID  uint64  `gorm:"not null;index" json:"inspection_id"`
+	AgentID       uint64  `gorm:"not null;index" json:"agent_id"`
+	Device        string  `gorm:"size:255" json:"device"`
+	Path          string  `gorm:"size:255;not null" json:"path"`
+	FSType        string  `gorm:"size:32" json:"fstype"`
+	Size          uint64  `json:"size"`
+	Used          uint64  `json:"used"`
+	Available     uint64  `json:"available"`
+	UsedPercent   float64 `gorm:"type:decimal(5,2)" json:"used_percent"`
+	Inodes        uint64  `json:"inodes"`
+	InodesUsed    uint64  `json:"inodes_used"`
+	InodesPercent float64 `gorm:"type:decimal(5,2)" json:"inodes_percent"`
+}
+
+// TableName 表名
+func (InspectionMount) TableName() string {
+	return "inspection_mounts"
+}
+
+// InspectionService 巡检时关注的 systemd 单元状态
+type InspectionService struct {
+	ID           uint64 `gorm:"primaryKey" json:"id"`
+	InspectionID uint64 `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64 `gorm:"not null;index" json:"agent_id"`
+	Name         string `gorm:"size:128;not null" json:"name"`
+	LoadState    string `gorm:"size:32" json:"load_state"`
+	ActiveState  string `gorm:"size:32" json:"active_state"`
+	SubState     string `gorm:"size:32" json:"sub_state"`
+	Restarts     int    `json:"restarts"`
+	Uptime       int64  `json:"uptime"` // 距最近一次启动的秒数
+}
+
+// TableName 表名
+func (InspectionService) TableName() string {
+	return "inspection_services"
+}
+
+// 进程排行类型
+const (
+	ProcessTopCPU    = "cpu"
+	ProcessTopMemory = "memory"
+)
+
+// InspectionProcess 巡检时资源占用排行中的进程
+type InspectionProcess struct {
+	ID           uint64  `gorm:"primaryKey" json:"id"`
+	InspectionID uint64  `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64  `gorm:"not null;index" json:"agent_id"`
+	Top          string  `gorm:"size:16;not null" json:"top"` // 排行类型：cpu / memory
+	Rank         int     `json:"rank"`                        // 排名，从 1 开始
+	PID          int     `json:"pid"`
+	Name         string  `gorm:"size:128" json:"name"`
+	User         string  `gorm:"size:64" json:"user"`
+	CPU          float64 `gorm:"type:decimal(7,2)" json:"cpu"`
+	Memory       float64 `gorm:"type:decimal(5,2)" json:"memory"`
+	RSS          uint64  `json:"rss"`
+	Threads      int     `json:"threads"`
+	Command      string  `gorm:"size:256" json:"command"`
+}
+
+// TableName 表名
+func (InspectionProcess) TableName() string {
+	return "inspection_processes"
+}
+
+// InspectionProcessWatch 巡检时的进程守护结果
+type InspectionProcessWatch struct {
+	ID           uint64 `gorm:"primaryKey" json:"id"`
+	InspectionID uint64 `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64 `gorm:"not null;index" json:"agent_id"`
+	Name         string `gorm:"size:64;not null" json:"name"`
+	Pattern      string `gorm:"size:255" json:"pattern"`
+	Min          int    `json:"min"`
+	Max          int    `json:"max"` // 0 表示不限上限
+	Count        int    `json:"count"`
+}
+
+// TableName 表名
+func (InspectionProcessWatch) TableName() string {
+	return "inspection_process_watches"
+}
+
+// InspectionInterface 巡检时各网卡的流量与错误计数，速率单位为字节/秒
+type InspectionInterface struct {
+	ID           uint64  `gorm:"primaryKey" json:"id"`
+	InspectionID uint64  `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64  `gorm:"not null;index" json:"agent_id"`
+	Name         string  `gorm:"size:32;not null" json:"name"`
+	RxBytes      uint64  `json:"rx_bytes"`
+	TxBytes      uint64  `json:"tx_bytes"`
+	RxRate       float64 `json:"rx_rate"`
+	TxRate       float64 `json:"tx_rate"`
+	RxErrors     uint64  `json:"rx_errors"`
+	TxErrors     uint64  `json:"tx_errors"`
+	RxDropped    uint64  `json:"rx_dropped"`
+	TxDropped    uint64  `json:"tx_dropped"`
+}
+
+// TableName 表名
+func (InspectionInterface) TableName() string {
+	return "inspection_interfaces"
+}
+
+// InspectionProbe 巡检时的连通性探测结果
+type InspectionProbe struct {
+	ID           uint64  `gorm:"primaryKey" json:"id"`
+	InspectionID uint64  `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64  `gorm:"not null;index" json:"agent_id"`
+	Name         string  `gorm:"size:64;not null" json:"name"`
+	Type         string  `gorm:"size:16;not null" json:"type"` // icmp / tcp / http / dns
+	Target       string  `gorm:"size:255" json:"target"`
+	Success      bool    `json:"success"`
+	Latency      float64 `gorm:"type:decimal(10,2)" json:"latency"` // 耗时（毫秒）
+	Loss         float64 `gorm:"type:decimal(5,2)" json:"loss"`     // ICMP 丢包率
+	Error        string  `gorm:"size:255" json:"error,omitempty"`
+}
+
+// TableName 表名
+func (InspectionProbe) TableName() string {
+	return "inspection_probes"
+}
+
+// InspectionCertificate 巡检时检查的证书
+type InspectionCertificate struct {
+	ID           uint64    `gorm:"primaryKey" json:"id"`
+	InspectionID uint64    `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64    `gorm:"not null;index" json:"agent_id"`
+	Source       string    `gorm:"size:255;not null" json:"source"` // 文件路径或 host:port
+	Subject      string    `gorm:"size:255" json:"subject"`
+	Issuer       string    `gorm:"size:255" json:"issuer"`
+	SANs         []string  `gorm:"column:sans;serializer:json;type:text" json:"sans,omitempty"`
+	NotAfter     time.Time `gorm:"index" json:"not_after"`
+	DaysLeft     int       `json:"days_left"` // 采集时的剩余天数
+	Error        string    `gorm:"size:255" json:"error,omitempty"`
+}
+
+// TableName 表名
+func (InspectionCertificate) TableName() string {
+	return "inspection_certificates"
+}
+
+// InspectionLogMatch 巡检时日志规则自上次采集以来的匹配情况
+type InspectionLogMatch struct {
+	ID           uint64          `gorm:"primaryKey" json:"id"`
+	InspectionID uint64          `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64          `gorm:"not null;index" json:"agent_id"`
+	Rule         string          `gorm:"size:64;not null" json:"rule"`
+	Severity     InspectionLevel `gorm:"size:16" json:"severity"`
+	Count        int             `json:"count"`
+	Samples      []string        `gorm:"serializer:json;type:text" json:"samples,omitempty"` // 最近的匹配行
+}
+
+// TableName 表名
+func (InspectionLogMatch) TableName() string {
+	return "inspection_log_matches"
+}
+
 // AlertStatus 告警状态
 type AlertStatus string
 
@@ -102,3 +331,57 @@ type Alert struct {
 func (Alert) TableName() string {
 	return "alerts"
 }
+
+// InspectionFinding 巡检时安全基线审计发现的问题，FindingID 在多次审计间保持不变
+type InspectionFinding struct {
+	ID           uint64          `gorm:"primaryKey" json:"id"`
+	InspectionID uint64          `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64          `gorm:"not null;index" json:"agent_id"`
+	FindingID    string          `gorm:"size:255;not null" json:"finding_id"` // 如 file.suid:/usr/bin/vim
+	Check        string          `gorm:"size:64;not null" json:"check"`
+	Severity     InspectionLevel `gorm:"size:16" json:"severity"`
+	Title        string          `gorm:"size:255" json:"title"`
+	Detail       string          `gorm:"size:255" json:"detail"`
+}
+
+// TableName 表名
+func (InspectionFinding) TableName() string {
+	return "inspection_findings"
+}
+
+// InspectionPackage 巡检时有可用更新的软件包
+type InspectionPackage struct {
+	ID           uint64 `gorm:"primaryKey" json:"id"`
+	InspectionID uint64 `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64 `gorm:"not null;index" json:"agent_id"`
+	Name         string `gorm:"size:128;not null" json:"name"`
+	Version      string `gorm:"size:64" json:"version"`
+	Arch         string `gorm:"size:16" json:"arch"`
+	Source       string `gorm:"size:128" json:"source,omitempty"` // 源码包名（dpkg）
+	Update       string `gorm:"size:64" json:"update"`            // 可更新到的版本
+	Security     bool   `json:"security"`                         // 可用更新包含安全修复
+}
+
+// TableName 表名
+func (InspectionPackage) TableName() string {
+	return "inspection_packages"
+}
+
+// InspectionVuln 巡检时已安装软件包匹配到的漏洞
+type InspectionVuln struct {
+	ID           uint64          `gorm:"primaryKey" json:"id"`
+	InspectionID uint64          `gorm:"not null;index" json:"inspection_id"`
+	AgentID      uint64          `gorm:"not null;index" json:"agent_id"`
+	VulnID       string          `gorm:"size:64;not null" json:"vuln_id"` // 漏洞库中的编号，如 DSA-5532-1
+	Aliases      []string        `gorm:"serializer:json;type:text" json:"aliases,omitempty"`
+	Package      string          `gorm:"size:128;not null" json:"package"`
+	Version      string          `gorm:"size:64" json:"version"` // 已安装的版本
+	Fixed        string          `gorm:"size:64" json:"fixed"`   // 修复版本，漏洞库未给出时为空
+	Severity     InspectionLevel `gorm:"size:16" json:"severity"`
+	Summary      string          `gorm:"size:255" json:"summary"`
+}
+
+// TableName 表名
+func (InspectionVuln) TableName() string {
+	return "inspection_vulnerabilities"
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// EnrollmentToken Agent 自注册令牌
+type EnrollmentToken struct {
+	ID          uint64     `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:64;not null" json:"name"`             // 名称
+	Token       string     `gorm:"size:64;uniqueIndex;not null" json:"-"`    // 令牌摘要（SHA-256）
+	Reusable    bool       `gorm:"not null" json:"reusable"`                 // 可重复使用，否则使用一次后失效
+	AutoApprove bool       `gorm:"not null" json:"auto_approve"`             // 注册后自动通过审批
+	Tags        string     `gorm:"size:255" json:"tags"`                     // 注册节点的默认标签
+	UsedCount   int        `gorm:"not null;default:0" json:"used_count"`     // 已使用次数
+	Enabled     bool       `gorm:"not null" json:"enabled"`                  // 是否启用
+	ExpiresAt   *time.Time `gorm:"default:null" json:"expires_at,omitempty"` // 过期时间，为空表示不过期
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 表名
+func (EnrollmentToken) TableName() string {
+	return "enrollment_tokens"
+}
+
+// Usable 令牌当前是否可用于注册
+func (t EnrollmentToken) Usable(now time.Time) bool {
+	if !t.Enabled {
+		return false
+	}
+	if t.ExpiresAt != nil && !now.Before(*t.ExpiresAt) {
+		return false
+	}
+	return t.Reusable || t.UsedCount == 0
+}
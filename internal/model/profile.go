@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// ConfigProfile Agent 远程配置
+// AgentID 与 Tag 均为空时为全局配置，否则为指定节点或标签的配置；
+// 节点的生效配置按 全局 < 标签 < 节点 的顺序逐键合并
+type ConfigProfile struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:64;not null" json:"name"`        // 配置名称
+	Settings  string    `gorm:"type:text;not null" json:"settings"`  // 配置项（JSON），键如 thresholds.cpu
+	AgentID   uint64    `gorm:"default:0;index" json:"agent_id"`     // 分配的节点ID
+	Tag       string    `gorm:"size:64;default:'';index" json:"tag"` // 分配的节点标签
+	Enabled   bool      `gorm:"not null" json:"enabled"`             // 是否启用
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 表名
+func (ConfigProfile) TableName() string {
+	return "config_profiles"
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// RuleOperator 规则比较运算符
+type RuleOperator string
+
+const (
+	OpGreater      RuleOperator = ">"
+	OpGreaterEqual RuleOperator = ">="
+	OpLess         RuleOperator = "<"
+	OpLessEqual    RuleOperator = "<="
+	OpEqual        RuleOperator = "=="
+	OpNotEqual     RuleOperator = "!="
+)
+
+// AlertRule 阈值告警规则
+// AgentID 与 Tag 均为空时为全局规则，否则为指定节点或标签的覆盖规则
+type AlertRule struct {
+	ID        uint64          `gorm:"primaryKey" json:"id"`
+	Name      string          `gorm:"size:64" json:"name"`                 // 规则名称
+	Metric    string          `gorm:"size:64;not null" json:"metric"`      // 指标名称
+	Operator  RuleOperator    `gorm:"size:4;not null" json:"operator"`     // 比较运算符
+	Value     float64         `gorm:"not null" json:"value"`               // 阈值
+	For       int             `gorm:"default:0" json:"for"`                // 持续时间（秒）
+	Severity  InspectionLevel `gorm:"size:16;not null" json:"severity"`    // 告警级别
+	AgentID   uint64          `gorm:"default:0;index" json:"agent_id"`     // 覆盖的节点ID
+	Tag       string          `gorm:"size:64;default:'';index" json:"tag"` // 覆盖的节点标签
+	Enabled   bool            `gorm:"not null" json:"enabled"`             // 是否启用
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 表名
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
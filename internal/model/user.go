@@ -31,7 +31,7 @@ func (User) TableName() string {
 type AgentToken struct {
 	ID        uint64    `gorm:"primaryKey" json:"id"`
 	AgentID   uint64    `gorm:"uniqueIndex;not null" json:"agent_id"` // Agent ID
-	Token     string    `gorm:"size:255;not null" json:"token"`       // API Token
+	Token     string    `gorm:"size:255;not null" json:"-"`           // 令牌摘要（SHA-256）
 	Enabled   bool      `gorm:"default:true" json:"enabled"`          // 是否启用
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
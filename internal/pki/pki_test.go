@@ -0,0 +1,163 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCA 在临时目录创建 CA
+func newTestCA(t *testing.T) *CA {
+	t.Helper()
+
+	ca, err := LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 CA 失败: %v", err)
+	}
+	return ca
+}
+
+// verify 按用途校验证书链
+func verify(ca *CA, cert *x509.Certificate, usage x509.ExtKeyUsage, dnsName string) error {
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     ca.Pool(),
+		DNSName:   dnsName,
+		KeyUsages: []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ca.Cert.IsCA || !ca.Cert.MaxPathLenZero {
+		t.Errorf("CA 证书属性错误: %+v", ca.Cert)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "ca.key")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("CA 私钥权限应为 0600: %v %v", info.Mode(), err)
+	}
+
+	// 再次加载得到同一个 CA
+	again, err := LoadOrCreateCA(dir)
+	if err != nil || !again.Cert.Equal(ca.Cert) {
+		t.Fatalf("应加载已有的 CA: %v", err)
+	}
+
+	// 只剩证书或私钥时不重新生成，避免覆盖已分发的 CA
+	if err := os.Remove(filepath.Join(dir, "ca.key")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateCA(dir); err == nil {
+		t.Error("CA 文件不完整时应返回错误")
+	}
+}
+
+func TestIssueAgent(t *testing.T) {
+	ca := newTestCA(t)
+
+	// CSR 中自报的 CN 与名称被忽略，SAN 只取 Master 记录的地址
+	_, csrPEM, err := NewKeyAndCSR("evil.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, cert, err := ca.IssueAgent(csrPEM, "agent-7", []string{"10.0.0.7", "web-07.internal", ""}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := pem.Decode(certPEM); block == nil || block.Type != "CERTIFICATE" {
+		t.Fatalf("证书 PEM 格式错误: %s", certPEM)
+	}
+	if cert.Subject.CommonName != "agent-7" || len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "10.0.0.7" ||
+		len(cert.DNSNames) != 1 || cert.DNSNames[0] != "web-07.internal" {
+		t.Errorf("证书身份错误: cn=%s ip=%v dns=%v", cert.Subject.CommonName, cert.IPAddresses, cert.DNSNames)
+	}
+
+	// 服务端证书只能用于服务端认证
+	if err := verify(ca, cert, x509.ExtKeyUsageServerAuth, "web-07.internal"); err != nil {
+		t.Errorf("服务端证书应校验通过: %v", err)
+	}
+	if err := verify(ca, cert, x509.ExtKeyUsageServerAuth, "evil.example.com"); err == nil {
+		t.Error("CSR 自报的名称不应写入证书")
+	}
+	if err := verify(ca, cert, x509.ExtKeyUsageClientAuth, ""); err == nil {
+		t.Error("节点证书不应能作为客户端证书使用")
+	}
+
+	// 其他 CA 签发的证书不被信任
+	if err := verify(newTestCA(t), cert, x509.ExtKeyUsageServerAuth, "web-07.internal"); err == nil {
+		t.Error("其他 CA 不应信任该证书")
+	}
+
+	// 有效期不超过 CA
+	if _, cert, _ = ca.IssueAgent(csrPEM, "agent-7", []string{"10.0.0.7"}, 100*365*24*time.Hour); cert.NotAfter.After(ca.Cert.NotAfter) {
+		t.Errorf("证书有效期不应超过 CA: %v > %v", cert.NotAfter, ca.Cert.NotAfter)
+	}
+
+	// 无效或被篡改的证书请求
+	if _, _, err := ca.IssueAgent([]byte("not a csr"), "agent-7", nil, time.Hour); err == nil {
+		t.Error("无效的证书请求应被拒绝")
+	}
+	block, _ := pem.Decode(csrPEM)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	if _, _, err := ca.IssueAgent(pem.EncodeToMemory(block), "agent-7", nil, time.Hour); err == nil {
+		t.Error("签名无效的证书请求应被拒绝")
+	}
+}
+
+func TestIssueClient(t *testing.T) {
+	ca := newTestCA(t)
+
+	pair, err := ca.IssueClient(MasterCommonName, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.Leaf == nil || pair.Leaf.Subject.CommonName != MasterCommonName {
+		t.Fatalf("客户端证书 CN 错误: %+v", pair.Leaf)
+	}
+	if err := verify(ca, pair.Leaf, x509.ExtKeyUsageClientAuth, ""); err != nil {
+		t.Errorf("客户端证书应校验通过: %v", err)
+	}
+	if err := verify(ca, pair.Leaf, x509.ExtKeyUsageServerAuth, ""); err == nil {
+		t.Error("客户端证书不应能作为服务端证书使用")
+	}
+
+	// 回调复用未到续期时间的证书
+	get := ca.ClientCertificate(MasterCommonName, time.Hour)
+	first, err := get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := get(nil); second != first {
+		t.Error("未到续期时间不应重新签发")
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: start, NotAfter: start.Add(90 * 24 * time.Hour)}
+
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{start, false},
+		{start.Add(59 * 24 * time.Hour), false},
+		{start.Add(60 * 24 * time.Hour), false}, // 恰好剩余三分之一
+		{start.Add(60*24*time.Hour + time.Second), true},
+		{start.Add(100 * 24 * time.Hour), true},
+	}
+	for _, c := range cases {
+		if got := NeedsRenewal(cert, c.at); got != c.want {
+			t.Errorf("NeedsRenewal(%v) = %v, want %v", c.at.Sub(start), got, c.want)
+		}
+	}
+	if !NeedsRenewal(nil, start) {
+		t.Error("没有证书时需要签发")
+	}
+}
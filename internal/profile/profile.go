@@ -0,0 +1,290 @@
+// Package profile 计算与校验 Master 下发给 Agent 的远程配置
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+)
+
+// Parse 解析配置项 JSON 并校验
+func Parse(settings string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(settings), &m); err != nil {
+		return nil, fmt.Errorf("配置项必须是 JSON 对象: %w", err)
+	}
+	if err := Validate(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate 校验配置项的键与取值，只允许 protocol.RemoteConfigKeys 中的键
+func Validate(settings map[string]interface{}) error {
+	allowed := make(map[string]bool, len(protocol.RemoteConfigKeys))
+	for _, k := range protocol.RemoteConfigKeys {
+		allowed[k] = true
+	}
+
+	for key, value := range settings {
+		if !allowed[key] {
+			return fmt.Errorf("不支持远程下发的配置项: %s", key)
+		}
+		if err := validateValue(key, value); err != nil {
+			return fmt.Errorf("配置项 %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// validateValue 校验单个配置项
+func validateValue(key string, value interface{}) error {
+	switch key {
+	case "hostname", "llm.api_url", "llm.model":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("必须是字符串")
+		}
+	case "llm.enabled":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("必须是布尔值")
+		}
+	case "cache.ttl", "llm.timeout", "packages.interval":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("必须是时长字符串，如 \"30s\"")
+		}
+		if d, err := time.ParseDuration(s); err != nil || d < 0 {
+			return fmt.Errorf("时长格式错误: %s", s)
+		}
+	case "collectors":
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("必须是非空的采集器列表")
+		}
+		for _, item := range list {
+			name, _ := item.(string)
+			if !knownCollector(name) {
+				return fmt.Errorf("未知的采集器: %v", item)
+			}
+		}
+	case "thresholds.load_factor", "llm.temperature":
+		if v, ok := value.(float64); !ok || v < 0 {
+			return fmt.Errorf("必须是非负数")
+		}
+	case "services":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是单元名列表")
+		}
+		for _, item := range list {
+			if name, _ := item.(string); name == "" {
+				return fmt.Errorf("单元名必须是非空字符串: %v", item)
+			}
+		}
+	case "certificates.files", "certificates.endpoints", "audit.suid_allow":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是字符串列表")
+		}
+		for _, item := range list {
+			s, _ := item.(string)
+			if s == "" {
+				return fmt.Errorf("必须是非空字符串: %v", item)
+			}
+			if _, _, err := net.SplitHostPort(s); key == "certificates.endpoints" && err != nil {
+				return fmt.Errorf("%s 必须是 host:port", s)
+			}
+		}
+	case "audit.allowed_ports":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是端口列表")
+		}
+		for _, item := range list {
+			if v, ok := item.(float64); !ok || v <= 0 || v > 65535 || v != float64(int(v)) {
+				return fmt.Errorf("不是有效的端口: %v", item)
+			}
+		}
+	case "processes.watch":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是进程守护列表")
+		}
+		for _, item := range list {
+			if err := validateWatch(item); err != nil {
+				return err
+			}
+		}
+	case "probes":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是探测列表")
+		}
+		names := make(map[string]bool, len(list))
+		for _, item := range list {
+			name, err := validateProbe(item)
+			if err != nil {
+				return err
+			}
+			if names[name] {
+				return fmt.Errorf("探测名称 %s 重复", name)
+			}
+			names[name] = true
+		}
+	case "logs.sources", "logs.rules":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("必须是对象列表")
+		}
+		for _, item := range list {
+			if err := validateLogItem(key, item); err != nil {
+				return err
+			}
+		}
+	case "thresholds.journal_errors", "thresholds.service_restarts", "thresholds.zombies", "thresholds.cert_warning_days", "thresholds.cert_critical_days", "thresholds.security_updates", "processes.top", "logs.samples":
+		if v, ok := value.(float64); !ok || v < 0 || v != float64(int(v)) {
+			return fmt.Errorf("必须是非负整数")
+		}
+	default:
+		// 百分比阈值
+		if v, ok := value.(float64); !ok || v <= 0 || v > 100 {
+			return fmt.Errorf("必须在 (0, 100] 之间")
+		}
+	}
+	return nil
+}
+
+// validateWatch 校验单个进程守护配置，如 {"name":"nginx","pattern":"^nginx: master","min":1,"max":1}
+func validateWatch(item interface{}) error {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("进程守护必须是对象: %v", item)
+	}
+	name, _ := m["name"].(string)
+	pattern, _ := m["pattern"].(string)
+	if name == "" || pattern == "" {
+		return fmt.Errorf("进程守护的 name 与 pattern 不能为空")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("%s 的 pattern 不是有效的正则表达式", name)
+	}
+	for _, key := range []string{"min", "max"} {
+		if v, ok := m[key]; ok {
+			if n, ok := v.(float64); !ok || n < 0 || n != float64(int(n)) {
+				return fmt.Errorf("%s 的 %s 必须是非负整数", name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// validateProbe 校验单个探测配置，如 {"name":"db","type":"tcp","target":"10.0.0.5:3306","timeout":"3s"}，返回探测名称
+func validateProbe(item interface{}) (string, error) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("探测必须是对象: %v", item)
+	}
+	name, _ := m["name"].(string)
+	target, _ := m["target"].(string)
+	if name == "" || target == "" {
+		return "", fmt.Errorf("探测的 name 与 target 不能为空")
+	}
+	typ, _ := m["type"].(string)
+	known := false
+	for _, t := range protocol.ProbeTypes {
+		known = known || t == typ
+	}
+	if !known {
+		return "", fmt.Errorf("%s 的探测类型 %q 不支持", name, typ)
+	}
+	if v, ok := m["timeout"]; ok {
+		s, _ := v.(string)
+		if d, err := time.ParseDuration(s); err != nil || d < 0 {
+			return "", fmt.Errorf("%s 的 timeout 必须是时长，如 3s: %v", name, v)
+		}
+	}
+	return name, nil
+}
+
+// validateLogItem 校验日志来源 {"name":"kernel","unit":"kernel"} 或日志规则 {"name":"oom","pattern":"Out of memory","severity":"CRITICAL"}
+func validateLogItem(key string, item interface{}) error {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("必须是对象: %v", item)
+	}
+	name, _ := m["name"].(string)
+	if name == "" {
+		return fmt.Errorf("name 不能为空")
+	}
+	if key == "logs.sources" {
+		file, _ := m["file"].(string)
+		unit, _ := m["unit"].(string)
+		if (file == "") == (unit == "") {
+			return fmt.Errorf("%s 的 file 与 unit 必须二选一", name)
+		}
+		return nil
+	}
+
+	pattern, _ := m["pattern"].(string)
+	if _, err := regexp.Compile(pattern); err != nil || pattern == "" {
+		return fmt.Errorf("%s 的 pattern 不是有效的正则表达式", name)
+	}
+	switch severity, _ := m["severity"].(string); strings.ToUpper(severity) {
+	case "", "WARNING", "CRITICAL":
+	default:
+		return fmt.Errorf("%s 的 severity 必须是 WARNING 或 CRITICAL", name)
+	}
+	return nil
+}
+
+// knownCollector 是否为支持的采集器
+func knownCollector(name string) bool {
+	for _, c := range protocol.Collectors {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve 计算节点的生效配置
+// 合并顺序：全局配置 < 标签配置 < 节点配置，后者逐键覆盖前者；同一层级按 ID 升序合并，后创建的覆盖先创建的。
+// 禁用或格式错误的配置被忽略
+func Resolve(profiles []model.ConfigProfile, agent model.Agent) protocol.RemoteConfig {
+	profiles = append([]model.ConfigProfile(nil), profiles...)
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+
+	tags := make(map[string]bool)
+	for _, t := range agent.TagList() {
+		tags[t] = true
+	}
+
+	settings := make(map[string]interface{})
+	for _, scope := range []func(model.ConfigProfile) bool{
+		func(p model.ConfigProfile) bool { return p.AgentID == 0 && p.Tag == "" },
+		func(p model.ConfigProfile) bool { return p.AgentID == 0 && p.Tag != "" && tags[p.Tag] },
+		func(p model.ConfigProfile) bool { return p.AgentID != 0 && p.AgentID == agent.ID },
+	} {
+		for _, p := range profiles {
+			if !p.Enabled || !scope(p) {
+				continue
+			}
+			m, err := Parse(p.Settings)
+			if err != nil {
+				continue
+			}
+			for k, v := range m {
+				settings[k] = v
+			}
+		}
+	}
+
+	return protocol.RemoteConfig{Version: protocol.ConfigVersion(settings), Settings: settings}
+}
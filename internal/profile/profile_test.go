@@ -0,0 +1,47 @@
+package profile
+
+import (
+	"testing"
+
+	"cyber-inspector/internal/model"
+)
+
+func TestResolve(t *testing.T) {
+	profiles := []model.ConfigProfile{
+		// 节点配置写在最前面，仍然最后覆盖
+		{ID: 6, Settings: `{"thresholds.cpu": 99}`, AgentID: 7, Enabled: true},
+		// 同一层级的标签配置按 ID 升序合并，与传入顺序无关
+		{ID: 5, Settings: `{"thresholds.memory": 70, "thresholds.disk": 75}`, Tag: "web", Enabled: true},
+		{ID: 3, Settings: `{"thresholds.memory": 60, "thresholds.cpu": 60}`, Tag: "db", Enabled: true},
+		{ID: 1, Settings: `{"thresholds.cpu": 80, "thresholds.memory": 80, "thresholds.disk": 80}`, Enabled: true},
+		{ID: 2, Settings: `{"thresholds.disk": 50}`, Enabled: false},
+		{ID: 4, Settings: `{"thresholds.disk": 500}`, Enabled: true},
+		{ID: 8, Settings: `{"thresholds.cpu": 10}`, AgentID: 8, Enabled: true},
+	}
+
+	cfg := Resolve(profiles, model.Agent{ID: 7, Tags: "web, db"})
+	want := map[string]float64{"thresholds.cpu": 99, "thresholds.memory": 70, "thresholds.disk": 75}
+	for k, v := range want {
+		if cfg.Settings[k] != v {
+			t.Errorf("%s = %v, want %v", k, cfg.Settings[k], v)
+		}
+	}
+	if len(cfg.Settings) != len(want) {
+		t.Errorf("settings = %v", cfg.Settings)
+	}
+
+	// 合并结果与传入顺序无关，版本号稳定
+	reversed := make([]model.ConfigProfile, len(profiles))
+	for i, p := range profiles {
+		reversed[len(profiles)-1-i] = p
+	}
+	if again := Resolve(reversed, model.Agent{ID: 7, Tags: "db, web"}); again.Version != cfg.Version {
+		t.Errorf("传入顺序不同时版本不一致: %s != %s", again.Version, cfg.Version)
+	}
+
+	// 禁用与格式错误的配置被忽略
+	global := Resolve(profiles, model.Agent{ID: 9})
+	if global.Settings["thresholds.disk"] != float64(80) || global.Settings["thresholds.cpu"] != float64(80) {
+		t.Errorf("global = %v", global.Settings)
+	}
+}
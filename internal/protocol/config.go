@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ConfigPath Agent 获取远程配置的接口
+const ConfigPath = "/agent-api/config"
+
+// RemoteConfig Master 下发的 Agent 配置，Settings 为扁平键值，如 {"thresholds.cpu": 70}
+type RemoteConfig struct {
+	Version  string                 `json:"version"` // 内容摘要，为空表示未分配配置
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// RemoteConfigKeys 允许远程下发的配置键，其余配置（监听地址、Master 连接等）只能在本地修改
+var RemoteConfigKeys = []string{
+	"hostname",
+	"collectors",
+	"services",
+	"processes.top",
+	"processes.watch",
+	"probes",
+	"certificates.files",
+	"certificates.endpoints",
+	"logs.sources",
+	"logs.rules",
+	"logs.samples",
+	"audit.suid_allow",
+	"audit.allowed_ports",
+	"packages.interval",
+	"cache.ttl",
+	"thresholds.cpu",
+	"thresholds.load_factor",
+	"thresholds.memory",
+	"thresholds.disk",
+	"thresholds.inodes",
+	"thresholds.disk_temperature",
+	"thresholds.disk_wear",
+	"thresholds.service_restarts",
+	"thresholds.zombies",
+	"thresholds.conntrack",
+	"thresholds.cert_warning_days",
+	"thresholds.cert_critical_days",
+	"thresholds.compliance",
+	"thresholds.security_updates",
+	"thresholds.disk_report",
+	"thresholds.journal_errors",
+	"thresholds.ping_loss",
+	"llm.enabled",
+	"llm.api_url",
+	"llm.model",
+	"llm.temperature",
+	"llm.timeout",
+}
+
+// ConfigVersion 计算配置内容的版本号，相同内容的版本号相同，空配置为空字符串
+func ConfigVersion(settings map[string]interface{}) string {
+	if len(settings) == 0 {
+		return ""
+	}
+	// encoding/json 按键排序输出 map，结果稳定
+	data, _ := json.Marshal(settings)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
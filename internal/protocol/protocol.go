@@ -0,0 +1,411 @@
+// Package protocol 定义 Master 与 Agent 之间的数据协议，两端共用
+//
+// 版本说明：
+//   - v1：早期 Agent 的隐式格式，只有 hostname、raw_data、analysis，指标为 "12.3%" 形式的字符串，
+//     位于 raw_data 中（cpu_used、mem_used、cpu_load、ping_loss 等），不带 protocol_version 字段。
+//   - v2：增加 protocol_version、agent_version、collected_at 与数值类型的 metrics；
+//     raw_data 与 analysis 保持不变，因此 v1 的 Master 仍可读取 v2 的数据。
+//
+// 滚动升级期间 Master 同时接受 v1 与 v2；高于 Version 的数据按 v2 尽量解析，未知字段被忽略。
+package protocol
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 协议版本
+const (
+	Version    = 2 // 当前版本
+	MinVersion = 1 // 仍兼容的最低版本
+)
+
+// HeaderVersion 拉取请求中 Master 支持的最高协议版本，Agent 按协商结果应答
+const HeaderVersion = "X-Protocol-Version"
+
+// Negotiate 根据对端支持的最高版本协商使用的版本，requested 为 0 表示对端未声明（v1）
+func Negotiate(requested int) int {
+	switch {
+	case requested <= 0:
+		return MinVersion
+	case requested > Version:
+		return Version
+	default:
+		return requested
+	}
+}
+
+// Report Agent 巡检数据：/inspect 的响应、推送请求体与长连接 report 消息的载荷
+type Report struct {
+	ProtocolVersion int             `json:"protocol_version,omitempty"` // 为空表示 v1
+	AgentVersion    string          `json:"agent_version,omitempty"`
+	Hostname        string          `json:"hostname"`
+	CollectedAt     *time.Time      `json:"collected_at,omitempty"`   // 采集时间，推送补发时用于还原巡检时间
+	ConfigVersion   string          `json:"config_version,omitempty"` // 采集时生效的远程配置版本
+	Metrics         *Metrics        `json:"metrics,omitempty"`        // v2 数值指标
+	RawData         json.RawMessage `json:"raw_data"`                 // 原始采集数据
+	Analysis        json.RawMessage `json:"analysis"`                 // 分析结果，格式见 Analysis
+}
+
+// Metrics 数值指标，百分比取值 0-100
+type Metrics struct {
+	CPUUsed        float64        `json:"cpu_used"`       // CPU 使用率（%）
+	MemoryUsed     float64        `json:"memory_used"`    // 内存使用率（%）
+	DiskUsed       float64        `json:"disk_used"`      // 最高的磁盘使用率（%）
+	LoadAvg        float64        `json:"load_avg"`       // 1 分钟平均负载
+	PingLoss       float64        `json:"ping_loss"`      // 网关丢包率（%）
+	JournalErr1h   int            `json:"journal_err_1h"` // 1 小时内错误日志数
+	RAIDState      string         `json:"raid_state,omitempty"`
+	InodesUsed     float64        `json:"inodes_used"`               // 最高的 inode 使用率（%）
+	Mounts         []Mount        `json:"mounts,omitempty"`          // 各文件系统的使用情况
+	Disks          []DiskHealth   `json:"disks,omitempty"`           // 磁盘 SMART 健康状态
+	MDArrays       []MDArray      `json:"md_arrays,omitempty"`       // 软 RAID 阵列状态
+	Units          []Unit         `json:"units,omitempty"`           // 关注的 systemd 单元
+	FailedUnits    []string       `json:"failed_units,omitempty"`    // 处于 failed 状态的全部单元
+	ProcessCount   int            `json:"process_count"`             // 进程数
+	ThreadCount    int            `json:"thread_count"`              // 线程数
+	ZombieCount    int            `json:"zombie_count"`              // 僵尸进程数
+	TopCPU         []Process      `json:"top_cpu,omitempty"`         // CPU 占用最高的进程
+	TopMemory      []Process      `json:"top_memory,omitempty"`      // 内存（RSS）占用最高的进程
+	Watches        []ProcessWatch `json:"process_watches,omitempty"` // 需要保持运行的进程
+	Interfaces     []Interface    `json:"interfaces,omitempty"`      // 网卡流量与错误计数，不含 lo
+	TCPStates      map[string]int `json:"tcp_states,omitempty"`      // 按状态统计的 TCP 连接数（含 IPv6），如 ESTABLISHED、TIME_WAIT
+	TCPConnections int            `json:"tcp_connections"`           // 除 LISTEN 外的 TCP 连接数
+	ListenPorts    []int          `json:"listen_ports,omitempty"`    // 监听中的 TCP 端口
+	ConntrackCount int            `json:"conntrack_count"`           // 连接跟踪表条目数，未加载 nf_conntrack 时为 0
+	ConntrackMax   int            `json:"conntrack_max"`
+	ConntrackUsed  float64        `json:"conntrack_used"`         // 连接跟踪表使用率（%）
+	Probes         []Probe        `json:"probes,omitempty"`       // 连通性探测结果
+	Certificates   []Certificate  `json:"certificates,omitempty"` // 证书到期情况
+	LogMatches     []LogMatch     `json:"log_matches,omitempty"`  // 日志规则自上次采集以来的匹配情况
+	Audit          *Audit         `json:"audit,omitempty"`        // 安全基线审计结果
+	Packages       *Packages      `json:"packages,omitempty"`     // 已安装的软件包与待更新情况
+}
+
+// Mount 文件系统使用情况，容量单位为字节
+type Mount struct {
+	Device        string  `json:"device"`
+	Path          string  `json:"path"`
+	FSType        string  `json:"fstype"`
+	Size          uint64  `json:"size"`
+	Used          uint64  `json:"used"`
+	Available     uint64  `json:"available"`
+	UsedPercent   float64 `json:"used_percent"`
+	Inodes        uint64  `json:"inodes"` // 不支持 inode 统计的文件系统（如 btrfs）为 0
+	InodesUsed    uint64  `json:"inodes_used"`
+	InodesPercent float64 `json:"inodes_percent"`
+}
+
+// DiskHealth 磁盘 SMART 健康状态，不支持的项为 0
+type DiskHealth struct {
+	Device             string  `json:"device"`
+	Model              string  `json:"model"`
+	Serial             string  `json:"serial"`
+	Protocol           string  `json:"protocol"`        // ATA / NVMe / SCSI
+	Passed             bool    `json:"passed"`          // SMART 整体评估是否通过
+	Temperature        int     `json:"temperature"`     // 当前温度（℃）
+	PowerOnHours       int64   `json:"power_on_hours"`  // 通电时间（小时）
+	ReallocatedSectors int64   `json:"reallocated"`     // 已重映射扇区数
+	PendingSectors     int64   `json:"pending_sectors"` // 待重映射扇区数
+	MediaErrors        int64   `json:"media_errors"`    // NVMe 介质错误数
+	Wear               float64 `json:"wear"`            // 固态盘寿命消耗（%）
+}
+
+// MDArray 软 RAID（mdraid）阵列状态
+type MDArray struct {
+	Name    string   `json:"name"`
+	Level   string   `json:"level"`          // raid1 / raid5 等，inactive 阵列为空
+	State   string   `json:"state"`          // active / degraded / inactive
+	Devices int      `json:"devices"`        // 应有成员数
+	Active  int      `json:"active"`         // 在用成员数
+	Failed  []string `json:"failed"`         // 故障成员
+	Sync    string   `json:"sync,omitempty"` // 进行中的同步，如 "recovery 12.6%"
+}
+
+// Unit systemd 单元状态
+type Unit struct {
+	Name        string `json:"name"`         // 单元名，.service 后缀省略
+	LoadState   string `json:"load_state"`   // loaded / not-found 等
+	ActiveState string `json:"active_state"` // active / inactive / failed 等
+	SubState    string `json:"sub_state"`    // running / dead / exited 等
+	Restarts    int    `json:"restarts"`     // systemd 自动重启次数
+	Uptime      int64  `json:"uptime"`       // 距最近一次启动的秒数，未运行时为 0
+}
+
+// Running 单元是否处于运行状态
+func (u Unit) Running() bool {
+	return u.ActiveState == "active"
+}
+
+// Process 进程资源占用
+type Process struct {
+	PID     int     `json:"pid"`
+	Name    string  `json:"name"`
+	User    string  `json:"user"`
+	CPU     float64 `json:"cpu"`     // CPU 使用率（%），按单核计
+	Memory  float64 `json:"memory"`  // 内存使用率（%）
+	RSS     uint64  `json:"rss"`     // 常驻内存（字节）
+	Threads int     `json:"threads"` // 线程数
+	Command string  `json:"command"` // 完整命令行，过长时截断
+}
+
+// ProcessWatch 进程守护检查结果，Max 为 0 表示不限上限
+type ProcessWatch struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // 匹配命令行的正则表达式
+	Min     int    `json:"min"`
+	Max     int    `json:"max"`
+	Count   int    `json:"count"` // 实际匹配的进程数
+}
+
+// OK 匹配的进程数是否在期望范围内
+func (w ProcessWatch) OK() bool {
+	return w.Count >= w.Min && (w.Max == 0 || w.Count <= w.Max)
+}
+
+// Interface 网卡统计，累计值自网卡启用起计算
+type Interface struct {
+	Name      string  `json:"name"`
+	RxBytes   uint64  `json:"rx_bytes"`
+	TxBytes   uint64  `json:"tx_bytes"`
+	RxRate    float64 `json:"rx_rate"` // 采样期间的接收速率（字节/秒）
+	TxRate    float64 `json:"tx_rate"` // 采样期间的发送速率（字节/秒）
+	RxErrors  uint64  `json:"rx_errors"`
+	TxErrors  uint64  `json:"tx_errors"`
+	RxDropped uint64  `json:"rx_dropped"`
+	TxDropped uint64  `json:"tx_dropped"`
+}
+
+// 探测类型
+const (
+	ProbeICMP = "icmp" // ping 目标主机
+	ProbeTCP  = "tcp"  // 连接 host:port
+	ProbeHTTP = "http" // GET 请求，校验状态码与响应内容
+	ProbeDNS  = "dns"  // 向指定服务器解析域名
+)
+
+// ProbeTypes 支持的探测类型
+var ProbeTypes = []string{ProbeICMP, ProbeTCP, ProbeHTTP, ProbeDNS}
+
+// Probe 连通性探测结果
+type Probe struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Target  string  `json:"target"`
+	Success bool    `json:"success"`
+	Latency float64 `json:"latency"`         // 耗时（毫秒），ICMP 为平均往返时间
+	Loss    float64 `json:"loss,omitempty"`  // ICMP 丢包率（%）
+	Error   string  `json:"error,omitempty"` // 失败原因
+}
+
+// Certificate 证书信息，文件取第一张证书，TLS 端点取服务端证书
+type Certificate struct {
+	Source   string    `json:"source"` // 文件路径或 host:port
+	Subject  string    `json:"subject,omitempty"`
+	Issuer   string    `json:"issuer,omitempty"`
+	SANs     []string  `json:"sans,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+	DaysLeft int       `json:"days_left"`       // 剩余天数，已过期为负数
+	Error    string    `json:"error,omitempty"` // 读取或连接失败的原因
+}
+
+// LogMatch 日志规则的匹配结果
+type LogMatch struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`          // WARNING / CRITICAL
+	Count    int      `json:"count"`             // 自上次采集以来的匹配行数
+	Samples  []string `json:"samples,omitempty"` // 最近的匹配行，以 [来源] 开头
+}
+
+// Audit 安全基线审计结果，无法执行的检查项（如缺少权限）不计入
+type Audit struct {
+	Score     float64   `json:"score"`  // 合规得分，通过的检查项占比（%）
+	Checks    int       `json:"checks"` // 执行的检查项数
+	Passed    int       `json:"passed"` // 通过的检查项数
+	Findings  []Finding `json:"findings,omitempty"`
+	AuditedAt time.Time `json:"audited_at"`
+}
+
+// Finding 审计发现的问题
+type Finding struct {
+	ID       string `json:"id"`       // 稳定标识，同一问题在多次审计中不变，如 account.uid0:toor
+	Check    string `json:"check"`    // 所属检查项，如 account.uid0
+	Severity string `json:"severity"` // WARNING / CRITICAL
+	Title    string `json:"title"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// 包管理器
+const (
+	PackageDpkg = "dpkg"
+	PackageRPM  = "rpm"
+)
+
+// Packages 软件包采集结果，更新信息来自本地软件源缓存，采集时不访问网络
+type Packages struct {
+	Manager   string    `json:"manager"`          // dpkg / rpm
+	Distro    string    `json:"distro"`           // /etc/os-release 的 ID，如 debian、rocky
+	Release   string    `json:"release"`          // /etc/os-release 的 VERSION_ID，如 12、9.3
+	Installed int       `json:"installed"`        // 已安装的软件包数
+	Updates   int       `json:"updates"`          // 有可用更新的软件包数
+	Security  int       `json:"security_updates"` // 有安全更新的软件包数
+	List      []Package `json:"list,omitempty"`   // 全部已安装的软件包
+}
+
+// Package 已安装的软件包
+type Package struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"` // 含 epoch，如 1:2.3-4.el9
+	Arch     string `json:"arch,omitempty"`
+	Source   string `json:"source,omitempty"`   // 源码包名，与包名相同时为空（dpkg）
+	Update   string `json:"update,omitempty"`   // 可更新到的版本，没有更新时为空
+	Security bool   `json:"security,omitempty"` // 可用更新包含安全修复
+}
+
+// SetMounts 设置各文件系统的使用情况，并以最高使用率作为 DiskUsed 与 InodesUsed
+func (m *Metrics) SetMounts(mounts []Mount) {
+	m.Mounts = mounts
+	m.DiskUsed, m.InodesUsed = 0, 0
+	for _, mount := range mounts {
+		m.DiskUsed = math.Max(m.DiskUsed, mount.UsedPercent)
+		m.InodesUsed = math.Max(m.InodesUsed, mount.InodesPercent)
+	}
+}
+
+// Analysis 分析结果
+type Analysis struct {
+	Alert   bool     `json:"alert"`
+	Level   string   `json:"level"` // OK / WARNING / CRITICAL
+	Summary string   `json:"summary"`
+	Details []string `json:"details"`
+	Plan    string   `json:"plan"`
+}
+
+// Version 返回数据的协议版本
+func (r *Report) Version() int {
+	if r.ProtocolVersion <= 0 {
+		return 1
+	}
+	return r.ProtocolVersion
+}
+
+// ForVersion 按协商的版本返回数据副本，v1 去掉新增字段
+func (r *Report) ForVersion(version int) *Report {
+	out := *r
+	if version < 2 {
+		out.ProtocolVersion, out.AgentVersion, out.Metrics = 0, "", nil
+	} else {
+		out.ProtocolVersion = version
+	}
+	return &out
+}
+
+// DecodeReport 解析巡检数据，v1 数据从 raw_data 的字符串字段换算出 Metrics
+func DecodeReport(data []byte) (*Report, error) {
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Metrics == nil {
+		m := LegacyMetrics(r.RawData)
+		r.Metrics = &m
+	}
+	return &r, nil
+}
+
+// legacyRaw v1 raw_data 中的字符串指标
+type legacyRaw struct {
+	CPUUsed      string          `json:"cpu_used"`
+	MemUsed      string          `json:"mem_used"`
+	DiskAlert    string          `json:"disk_alert"`
+	LoadAvg      string          `json:"cpu_load"`
+	PingLoss     string          `json:"ping_loss"`
+	RAIDState    string          `json:"raid_state"`
+	JournalErr1h json.RawMessage `json:"journal_err_1h"`
+}
+
+// LegacyMetrics 从 v1 raw_data 解析数值指标，无法解析的字段取 0
+func LegacyMetrics(raw json.RawMessage) Metrics {
+	var m Metrics
+	var l legacyRaw
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return m
+	}
+
+	m.CPUUsed = parseNumber(l.CPUUsed)
+	m.MemoryUsed = parseNumber(l.MemUsed)
+	m.LoadAvg = parseNumber(l.LoadAvg)
+	m.PingLoss = parseNumber(l.PingLoss)
+	m.JournalErr1h = int(parseNumber(strings.Trim(string(l.JournalErr1h), `"`)))
+	if l.RAIDState != "null" {
+		m.RAIDState = l.RAIDState
+	}
+
+	// disk_alert 形如 "/dev/sda1:/:91%;/dev/sdb1:/data:85%"，取最高使用率
+	for _, item := range strings.Split(l.DiskAlert, ";") {
+		if i := strings.LastIndex(item, ":"); i >= 0 {
+			if v := parseNumber(item[i+1:]); v > m.DiskUsed {
+				m.DiskUsed = v
+			}
+		}
+	}
+	return m
+}
+
+// parseNumber 解析 "12.3%"、" 0.5" 之类的数值字符串
+func parseNumber(s string) float64 {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Info Agent 的 /info 响应与长连接心跳载荷，用于能力协商
+type Info struct {
+	ProtocolVersion    int      `json:"protocol_version"`     // 支持的最高协议版本
+	MinProtocolVersion int      `json:"min_protocol_version"` // 支持的最低协议版本
+	AgentVersion       string   `json:"agent_version"`
+	Hostname           string   `json:"hostname"`
+	Collectors         []string `json:"collectors"`               // 支持的采集器
+	Capabilities       []string `json:"capabilities"`             // 支持的能力，如 push、stream、mtls
+	ConfigVersion      string   `json:"config_version,omitempty"` // 当前生效的远程配置版本
+}
+
+// 采集器名称
+const (
+	CollectorCPU     = "cpu"
+	CollectorMemory  = "memory"
+	CollectorDisk    = "disk"
+	CollectorLoad    = "load"
+	CollectorRAID    = "raid"
+	CollectorStorage = "storage"
+	CollectorService = "services"
+	CollectorProcess = "processes"
+	CollectorNetwork = "network"
+	CollectorProbe   = "probes"
+	CollectorCert    = "certificates"
+	CollectorLogs    = "logs"
+	CollectorAudit   = "audit"
+	CollectorPackage = "packages"
+	CollectorJournal = "journal"
+	CollectorPing    = "ping"
+	CollectorLLM     = "llm"
+)
+
+// Collectors 支持按配置启停的采集器
+var Collectors = []string{
+	CollectorCPU, CollectorMemory, CollectorDisk, CollectorLoad, CollectorRAID, CollectorStorage, CollectorService, CollectorProcess, CollectorNetwork, CollectorProbe, CollectorCert, CollectorLogs, CollectorAudit, CollectorPackage, CollectorJournal, CollectorPing,
+}
+
+// 能力名称
+const (
+	CapabilityInspect = "inspect"
+	CapabilityPush    = "push"
+	CapabilityStream  = "stream"
+	CapabilityMTLS    = "mtls"
+)
@@ -0,0 +1,46 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeReportLegacy(t *testing.T) {
+	data := []byte(`{"hostname":"node-1","raw_data":{"cpu_used":"12.5%","mem_used":"80.00%","cpu_load":" 1.25","disk_alert":"/dev/sda1:/:91%;/dev/sdb1:/data:85%","raid_state":"null","journal_err_1h":3,"ping_loss":"0"},"analysis":{}}`)
+
+	r, err := DecodeReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version() != 1 {
+		t.Fatalf("version = %d, want 1", r.Version())
+	}
+	want := Metrics{CPUUsed: 12.5, MemoryUsed: 80, DiskUsed: 91, LoadAvg: 1.25, JournalErr1h: 3}
+	if !reflect.DeepEqual(*r.Metrics, want) {
+		t.Fatalf("metrics = %+v, want %+v", *r.Metrics, want)
+	}
+}
+
+func TestDecodeReportV2(t *testing.T) {
+	data := []byte(`{"protocol_version":2,"agent_version":"2.0.0","hostname":"node-1","metrics":{"cpu_used":42,"memory_used":50,"disk_used":60,"load_avg":0.5,"ping_loss":1,"journal_err_1h":0},"raw_data":{"cpu_used":"1%"},"analysis":{}}`)
+
+	r, err := DecodeReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version() != 2 || r.AgentVersion != "2.0.0" {
+		t.Fatalf("version = %d/%s", r.Version(), r.AgentVersion)
+	}
+	// v2 以 metrics 为准，不再解析 raw_data
+	if r.Metrics.CPUUsed != 42 {
+		t.Fatalf("cpu_used = %v, want 42", r.Metrics.CPUUsed)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for requested, want := range map[int]int{0: 1, 1: 1, 2: 2, 99: Version} {
+		if got := Negotiate(requested); got != want {
+			t.Errorf("Negotiate(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
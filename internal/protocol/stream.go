@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MessageType 长连接消息类型
+type MessageType string
+
+const (
+	MsgHeartbeat MessageType = "heartbeat" // Agent → Master 心跳，载荷为 Info
+	MsgReport    MessageType = "report"    // Agent → Master 巡检结果，载荷为 Report
+	MsgAck       MessageType = "ack"       // Master → Agent 巡检结果确认
+	MsgCommand   MessageType = "command"   // Master → Agent 命令
+	MsgResult    MessageType = "result"    // Agent → Master 命令执行结果
+)
+
+// 长连接命令
+const (
+	CommandInspect      = "inspect"       // 立即巡检并上报
+	CommandReloadConfig = "reload_config" // 重新加载配置
+)
+
+// Message 长连接消息
+type Message struct {
+	Type    MessageType     `json:"type"`
+	ID      string          `json:"id,omitempty"`      // 请求ID，用于关联确认与命令结果
+	Command string          `json:"command,omitempty"` // 命令名称
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Code    int             `json:"code,omitempty"`  // 确认状态码，语义与 HTTP 状态码一致
+	Error   string          `json:"error,omitempty"` // 错误信息
+	Time    time.Time       `json:"time"`
+}
+
+// 长连接参数
+const (
+	StreamPath       = "/agent-api/stream"
+	HeartbeatPeriod  = 30 * time.Second
+	StreamPongWait   = 3 * HeartbeatPeriod // 超过该时间未收到任何消息视为断开
+	StreamWriteWait  = 10 * time.Second
+	StreamMaxMessage = 4 << 20
+)
@@ -0,0 +1,775 @@
+// Package memory 提供基于内存的数据仓库实现，用于单元测试与本地调试
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Store 内存数据仓库
+type Store struct {
+	mu          sync.RWMutex
+	nextID      uint64
+	users       map[uint64]model.User
+	loginLogs   []model.LoginLog
+	agents      map[uint64]model.Agent
+	tokens      map[uint64]model.AgentToken
+	enrollments map[uint64]model.EnrollmentToken
+	inspections []model.Inspection
+	alerts      map[uint64]model.Alert
+	rules       map[uint64]model.AlertRule
+	profiles    map[uint64]model.ConfigProfile
+}
+
+// 确保内存实现满足接口
+var _ repository.Store = (*Store)(nil)
+
+// New 创建内存仓库
+func New() *Store {
+	return &Store{
+		users:       make(map[uint64]model.User),
+		agents:      make(map[uint64]model.Agent),
+		tokens:      make(map[uint64]model.AgentToken),
+		enrollments: make(map[uint64]model.EnrollmentToken),
+		alerts:      make(map[uint64]model.Alert),
+		rules:       make(map[uint64]model.AlertRule),
+		profiles:    make(map[uint64]model.ConfigProfile),
+	}
+}
+
+// id 生成自增ID，调用方需持有写锁
+func (s *Store) id() uint64 {
+	s.nextID++
+	return s.nextID
+}
+
+// CreateUser 创建用户
+func (s *Store) CreateUser(user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == user.Username {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	user.ID = s.id()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	s.users[user.ID] = *user
+	return nil
+}
+
+// GetUserByUsername 根据用户名获取用户
+func (s *Store) GetUserByUsername(username string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetUserByID 根据ID获取用户
+func (s *Store) GetUserByID(id uint64) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+// ListUsers 获取所有用户
+func (s *Store) ListUsers() ([]model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID > users[j].ID })
+	return users, nil
+}
+
+// UpdateUser 更新用户
+func (s *Store) UpdateUser(user *model.User) error {
+	return s.updateUser(user.ID, func(u *model.User) {
+		if user.Username != "" {
+			u.Username = user.Username
+		}
+		if user.Password != "" {
+			u.Password = user.Password
+		}
+		if user.Role != "" {
+			u.Role = user.Role
+		}
+		if user.Enabled {
+			u.Enabled = true
+		}
+	})
+}
+
+// UpdateUserPassword 更新用户密码
+func (s *Store) UpdateUserPassword(id uint64, password string) error {
+	return s.updateUser(id, func(u *model.User) { u.Password = password })
+}
+
+// UpdateUserStatus 更新用户状态
+func (s *Store) UpdateUserStatus(id uint64, enabled bool) error {
+	return s.updateUser(id, func(u *model.User) { u.Enabled = enabled })
+}
+
+// UpdateUserLastLogin 更新最后登录时间
+func (s *Store) UpdateUserLastLogin(id uint64) error {
+	return s.updateUser(id, func(u *model.User) { u.LastLogin = time.Now() })
+}
+
+// updateUser 修改用户，与 GORM 的 Where().Update() 一致，记录不存在时不报错
+func (s *Store) updateUser(id uint64, fn func(u *model.User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		fn(&u)
+		u.UpdatedAt = time.Now()
+		s.users[id] = u
+	}
+	return nil
+}
+
+// DeleteUser 删除用户
+func (s *Store) DeleteUser(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, id)
+	return nil
+}
+
+// CreateLoginLog 创建登录日志
+func (s *Store) CreateLoginLog(log *model.LoginLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.ID = s.id()
+	log.CreatedAt = time.Now()
+	s.loginLogs = append(s.loginLogs, *log)
+	return nil
+}
+
+// InitAdminUser 初始化管理员用户
+func (s *Store) InitAdminUser(username, password string) error {
+	s.mu.RLock()
+	for _, u := range s.users {
+		if u.Role == model.RoleAdmin {
+			s.mu.RUnlock()
+			return nil
+		}
+	}
+	s.mu.RUnlock()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.CreateUser(&model.User{
+		Username: username,
+		Password: string(hashedPassword),
+		Role:     model.RoleAdmin,
+		Enabled:  true,
+	})
+}
+
+// CreateAgent 创建Agent
+func (s *Store) CreateAgent(agent *model.Agent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createAgent(agent)
+}
+
+// createAgent 创建Agent，调用方需持有写锁
+func (s *Store) createAgent(agent *model.Agent) error {
+	for _, a := range s.agents {
+		if a.URL == agent.URL {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if agent.Status == "" {
+		agent.Status = model.AgentUnknown
+	}
+	agent.ID = s.id()
+	agent.CreatedAt = time.Now()
+	agent.UpdatedAt = agent.CreatedAt
+	s.agents[agent.ID] = *agent
+	return nil
+}
+
+// ListAgents 获取所有Agent
+func (s *Store) ListAgents() ([]model.Agent, error) {
+	return s.filterAgents(func(model.Agent) bool { return true }), nil
+}
+
+// GetActiveAgents 获取活跃的Agent
+func (s *Store) GetActiveAgents() ([]model.Agent, error) {
+	return s.filterAgents(func(a model.Agent) bool { return a.Enabled && a.Status != model.AgentPending }), nil
+}
+
+// filterAgents 按条件筛选Agent，按ID倒序
+func (s *Store) filterAgents(keep func(model.Agent) bool) []model.Agent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	agents := make([]model.Agent, 0, len(s.agents))
+	for _, a := range s.agents {
+		if keep(a) {
+			agents = append(agents, a)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID > agents[j].ID })
+	return agents
+}
+
+// GetAgentByID 根据ID获取Agent
+func (s *Store) GetAgentByID(id uint64) (*model.Agent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.agents[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &a, nil
+}
+
+// UpdateAgent 更新Agent
+func (s *Store) UpdateAgent(agent *model.Agent) error {
+	return s.updateAgent(agent.ID, func(a *model.Agent) {
+		created := a.CreatedAt
+		*a = *agent
+		a.CreatedAt = created
+	})
+}
+
+// DeleteAgent 删除Agent
+func (s *Store) DeleteAgent(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.agents, id)
+	return nil
+}
+
+// UpdateAgentStatus 更新Agent状态，在线时同时记录最后巡检时间
+func (s *Store) UpdateAgentStatus(id uint64, status model.AgentStatus) error {
+	return s.updateAgent(id, func(a *model.Agent) {
+		a.Status = status
+		if status == model.AgentOnline {
+			now := time.Now()
+			a.LastCheckAt = &now
+		}
+	})
+}
+
+// UpdateCheckInterval 更新巡检间隔
+func (s *Store) UpdateCheckInterval(id uint64, seconds int) error {
+	return s.updateAgent(id, func(a *model.Agent) { a.CheckInterval = seconds })
+}
+
+// UpdateAgentCert 记录节点证书过期时间
+func (s *Store) UpdateAgentCert(id uint64, notAfter time.Time) error {
+	return s.updateAgent(id, func(a *model.Agent) { a.CertNotAfter = &notAfter })
+}
+
+// UpdateAgentProtocol 记录节点协议版本，agentVersion 与 capabilities 为空时保持不变
+func (s *Store) UpdateAgentProtocol(id uint64, protocolVersion int, agentVersion, capabilities string) error {
+	return s.updateAgent(id, func(a *model.Agent) {
+		a.ProtocolVersion = protocolVersion
+		if agentVersion != "" {
+			a.Version = agentVersion
+		}
+		if capabilities != "" {
+			a.Capabilities = capabilities
+		}
+	})
+}
+
+// UpdateAgentConfigVersion 记录节点当前生效的远程配置版本
+func (s *Store) UpdateAgentConfigVersion(id uint64, version string) error {
+	return s.updateAgent(id, func(a *model.Agent) { a.ConfigVersion = version })
+}
+
+// updateAgent 修改Agent，记录不存在时不报错
+func (s *Store) updateAgent(id uint64, fn func(a *model.Agent)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.agents[id]; ok {
+		fn(&a)
+		a.UpdatedAt = time.Now()
+		s.agents[id] = a
+	}
+	return nil
+}
+
+// SaveAgentToken 保存Agent令牌比对摘要，并将加密后的签名密钥同步到 Agent.APIKey
+func (s *Store) SaveAgentToken(agentID uint64, digest, sealedKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	token, ok := s.tokens[agentID]
+	if !ok {
+		token = model.AgentToken{ID: s.id(), AgentID: agentID, CreatedAt: now}
+	}
+	token.Token = digest
+	token.Enabled = true
+	token.UpdatedAt = now
+	s.tokens[agentID] = token
+
+	if a, ok := s.agents[agentID]; ok {
+		a.APIKey = sealedKey
+		s.agents[agentID] = a
+	}
+	return nil
+}
+
+// GetAgentToken 获取Agent令牌
+func (s *Store) GetAgentToken(agentID uint64) (*model.AgentToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[agentID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// RevokeAgentToken 吊销Agent令牌
+func (s *Store) RevokeAgentToken(agentID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, ok := s.tokens[agentID]; ok {
+		token.Enabled = false
+		token.UpdatedAt = time.Now()
+		s.tokens[agentID] = token
+	}
+	if a, ok := s.agents[agentID]; ok {
+		a.APIKey = ""
+		s.agents[agentID] = a
+	}
+	return nil
+}
+
+// CreateEnrollmentToken 创建注册令牌
+func (s *Store) CreateEnrollmentToken(token *model.EnrollmentToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.enrollments {
+		if t.Token == token.Token {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	token.ID = s.id()
+	token.CreatedAt = time.Now()
+	token.UpdatedAt = token.CreatedAt
+	s.enrollments[token.ID] = *token
+	return nil
+}
+
+// ListEnrollmentTokens 获取注册令牌列表
+func (s *Store) ListEnrollmentTokens() ([]model.EnrollmentToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]model.EnrollmentToken, 0, len(s.enrollments))
+	for _, t := range s.enrollments {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+	return tokens, nil
+}
+
+// GetEnrollmentToken 根据摘要获取注册令牌
+func (s *Store) GetEnrollmentToken(hash string) (*model.EnrollmentToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.enrollments {
+		if t.Token == hash {
+			return &t, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// DeleteEnrollmentToken 删除注册令牌
+func (s *Store) DeleteEnrollmentToken(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.enrollments, id)
+	return nil
+}
+
+// EnrollAgent 消耗注册令牌并创建节点
+func (s *Store) EnrollAgent(tokenID uint64, agent *model.Agent, digest, sealedKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	token, ok := s.enrollments[tokenID]
+	if !ok || !token.Usable(now) {
+		return repository.ErrEnrollmentTokenUsed
+	}
+
+	agent.APIKey = sealedKey
+	if err := s.createAgent(agent); err != nil {
+		return err
+	}
+	s.tokens[agent.ID] = model.AgentToken{
+		ID:        s.id(),
+		AgentID:   agent.ID,
+		Token:     digest,
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	token.UsedCount++
+	token.UpdatedAt = now
+	s.enrollments[tokenID] = token
+	return nil
+}
+
+// SaveInspection 保存巡检记录
+func (s *Store) SaveInspection(inspection *model.Inspection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inspection.ID = s.id()
+	// 与 GORM 一致，只在未设置时填充，推送补发的结果保留采集时间
+	if inspection.CreatedAt.IsZero() {
+		inspection.CreatedAt = time.Now()
+	}
+	for i := range inspection.Mounts {
+		inspection.Mounts[i].ID = s.id()
+		inspection.Mounts[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Services {
+		inspection.Services[i].ID = s.id()
+		inspection.Services[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Processes {
+		inspection.Processes[i].ID = s.id()
+		inspection.Processes[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.ProcessWatches {
+		inspection.ProcessWatches[i].ID = s.id()
+		inspection.ProcessWatches[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Interfaces {
+		inspection.Interfaces[i].ID = s.id()
+		inspection.Interfaces[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Probes {
+		inspection.Probes[i].ID = s.id()
+		inspection.Probes[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Certificates {
+		inspection.Certificates[i].ID = s.id()
+		inspection.Certificates[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.LogMatches {
+		inspection.LogMatches[i].ID = s.id()
+		inspection.LogMatches[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Findings {
+		inspection.Findings[i].ID = s.id()
+		inspection.Findings[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Packages {
+		inspection.Packages[i].ID = s.id()
+		inspection.Packages[i].InspectionID = inspection.ID
+	}
+	for i := range inspection.Vulnerabilities {
+		inspection.Vulnerabilities[i].ID = s.id()
+		inspection.Vulnerabilities[i].InspectionID = inspection.ID
+	}
+	stored := *inspection
+	stored.InstalledPackages = nil // 与数据库一致，不保存全部已安装的软件包
+	s.inspections = append(s.inspections, stored)
+	return nil
+}
+
+// LatestInspections 获取每个Agent的最新巡检记录
+func (s *Store) LatestInspections() ([]model.Inspection, error) {
+	return s.latestInspections(func(model.Inspection) bool { return true }), nil
+}
+
+// LatestAuditedInspections 获取每个Agent最近一次含合规得分的巡检记录
+func (s *Store) LatestAuditedInspections() ([]model.Inspection, error) {
+	return s.latestInspections(func(ins model.Inspection) bool { return ins.ComplianceScore != nil }), nil
+}
+
+// latestInspections 各节点满足条件的最新巡检记录，按 ID 排序
+func (s *Store) latestInspections(match func(model.Inspection) bool) []model.Inspection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	latest := make(map[uint64]model.Inspection)
+	for _, ins := range s.inspections {
+		if !match(ins) {
+			continue
+		}
+		if cur, ok := latest[ins.AgentID]; !ok || ins.ID > cur.ID {
+			latest[ins.AgentID] = ins
+		}
+	}
+
+	inspections := make([]model.Inspection, 0, len(latest))
+	for _, ins := range latest {
+		inspections = append(inspections, ins)
+	}
+	sort.Slice(inspections, func(i, j int) bool { return inspections[i].ID < inspections[j].ID })
+	return inspections
+}
+
+// ExpiringCertificates 获取各节点最近一次含证书的巡检中即将到期的证书
+func (s *Store) ExpiringCertificates(before time.Time) ([]model.InspectionCertificate, error) {
+	inspections := s.latestInspections(func(ins model.Inspection) bool { return len(ins.Certificates) > 0 })
+
+	var certs []model.InspectionCertificate
+	for _, ins := range inspections {
+		for _, c := range ins.Certificates {
+			if c.Error == "" && !c.NotAfter.After(before) {
+				certs = append(certs, c)
+			}
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].NotAfter.Before(certs[j].NotAfter) })
+	return certs, nil
+}
+
+// GetInspectionsByAgentID 获取指定Agent的巡检记录
+func (s *Store) GetInspectionsByAgentID(agentID uint64, limit int) ([]model.Inspection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var inspections []model.Inspection
+	for i := len(s.inspections) - 1; i >= 0; i-- {
+		if s.inspections[i].AgentID != agentID {
+			continue
+		}
+		inspections = append(inspections, s.inspections[i])
+		if limit > 0 && len(inspections) >= limit {
+			break
+		}
+	}
+	return inspections, nil
+}
+
+// CreateAlert 创建告警记录
+func (s *Store) CreateAlert(alert *model.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if alert.Status == "" {
+		alert.Status = model.AlertPending
+	}
+	alert.ID = s.id()
+	alert.CreatedAt = time.Now()
+	alert.UpdatedAt = alert.CreatedAt
+	s.alerts[alert.ID] = *alert
+	return nil
+}
+
+// GetAlerts 获取告警记录
+func (s *Store) GetAlerts(limit, offset int) ([]model.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alerts := make([]model.Alert, 0, len(s.alerts))
+	for _, a := range s.alerts {
+		a.Agent = s.agents[a.AgentID]
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID > alerts[j].ID })
+
+	if offset >= len(alerts) {
+		return []model.Alert{}, nil
+	}
+	alerts = alerts[offset:]
+	if limit > 0 && limit < len(alerts) {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+// GetAlertStats 获取告警统计
+func (s *Store) GetAlertStats(days int) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total, pending, critical, warning int64
+	for _, a := range s.alerts {
+		total++
+		if a.Status == model.AlertPending {
+			pending++
+		}
+		switch a.Level {
+		case model.LevelCritical:
+			critical++
+		case model.LevelWarning:
+			warning++
+		}
+	}
+
+	return map[string]interface{}{
+		"total":    total,
+		"pending":  pending,
+		"critical": critical,
+		"warning":  warning,
+	}, nil
+}
+
+// UpdateAlertStatus 更新告警状态
+func (s *Store) UpdateAlertStatus(id uint64, status model.AlertStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.alerts[id]; ok {
+		a.Status = status
+		a.UpdatedAt = time.Now()
+		s.alerts[id] = a
+	}
+	return nil
+}
+
+// ListAlertRules 获取所有阈值规则
+func (s *Store) ListAlertRules() ([]model.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]model.AlertRule, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// GetAlertRuleByID 根据ID获取阈值规则
+func (s *Store) GetAlertRuleByID(id uint64) (*model.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &r, nil
+}
+
+// CreateAlertRule 创建阈值规则
+func (s *Store) CreateAlertRule(rule *model.AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.ID = s.id()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	s.rules[rule.ID] = *rule
+	return nil
+}
+
+// UpdateAlertRule 更新阈值规则
+func (s *Store) UpdateAlertRule(rule *model.AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.UpdatedAt = time.Now()
+	s.rules[rule.ID] = *rule
+	return nil
+}
+
+// DeleteAlertRule 删除阈值规则
+func (s *Store) DeleteAlertRule(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rules, id)
+	return nil
+}
+
+// ListConfigProfiles 获取所有远程配置
+func (s *Store) ListConfigProfiles() ([]model.ConfigProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profiles := make([]model.ConfigProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+	return profiles, nil
+}
+
+// GetConfigProfileByID 根据ID获取远程配置
+func (s *Store) GetConfigProfileByID(id uint64) (*model.ConfigProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.profiles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+// CreateConfigProfile 创建远程配置
+func (s *Store) CreateConfigProfile(profile *model.ConfigProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile.ID = s.id()
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = profile.CreatedAt
+	s.profiles[profile.ID] = *profile
+	return nil
+}
+
+// UpdateConfigProfile 更新远程配置
+func (s *Store) UpdateConfigProfile(profile *model.ConfigProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile.UpdatedAt = time.Now()
+	s.profiles[profile.ID] = *profile
+	return nil
+}
+
+// DeleteConfigProfile 删除远程配置
+func (s *Store) DeleteConfigProfile(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.profiles, id)
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"cyber-inspector/internal/model"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strings"
+	"time"
 )
 
 // Repository 数据仓库
@@ -66,7 +68,7 @@ func (r *Repository) UpdateUserStatus(id uint64, enabled bool) error {
 
 // UpdateUserLastLogin 更新最后登录时间
 func (r *Repository) UpdateUserLastLogin(id uint64) error {
-	return r.db.Model(&model.User{}).Where("id = ?", id).Update("last_login", "NOW()").Error
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("last_login", time.Now()).Error
 }
 
 // DeleteUser 删除用户
@@ -104,7 +106,7 @@ func (r *Repository) ListAgents() ([]model.Agent, error) {
 // GetActiveAgents 获取活跃的Agent
 func (r *Repository) GetActiveAgents() ([]model.Agent, error) {
 	var agents []model.Agent
-	err := r.db.Where("enabled = ?", true).Find(&agents).Error
+	err := r.db.Where("enabled = ? AND status <> ?", true, model.AgentPending).Find(&agents).Error
 	return agents, err
 }
 
@@ -128,9 +130,13 @@ func (r *Repository) DeleteAgent(id uint64) error {
 	return r.db.Delete(&model.Agent{}, id).Error
 }
 
-// UpdateAgentStatus 更新Agent状态
+// UpdateAgentStatus 更新Agent状态，在线时同时记录最后巡检时间
 func (r *Repository) UpdateAgentStatus(id uint64, status model.AgentStatus) error {
-	return r.db.Model(&model.Agent{}).Where("id = ?", id).Update("status", status).Error
+	updates := map[string]interface{}{"status": status}
+	if status == model.AgentOnline {
+		updates["last_check_at"] = time.Now()
+	}
+	return r.db.Model(&model.Agent{}).Where("id = ?", id).Updates(updates).Error
 }
 
 // UpdateCheckInterval 更新巡检间隔
@@ -138,35 +144,168 @@ func (r *Repository) UpdateCheckInterval(id uint64, seconds int) error {
 	return r.db.Model(&model.Agent{}).Where("id = ?", id).Update("check_interval", seconds).Error
 }
 
+// UpdateAgentProtocol 记录节点协议版本，agentVersion 与 capabilities 为空时保持不变
+func (r *Repository) UpdateAgentProtocol(id uint64, protocolVersion int, agentVersion, capabilities string) error {
+	updates := map[string]interface{}{"protocol_version": protocolVersion}
+	if agentVersion != "" {
+		updates["version"] = agentVersion
+	}
+	if capabilities != "" {
+		updates["capabilities"] = capabilities
+	}
+	return r.db.Model(&model.Agent{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// UpdateAgentConfigVersion 记录节点当前生效的远程配置版本
+func (r *Repository) UpdateAgentConfigVersion(id uint64, version string) error {
+	return r.db.Model(&model.Agent{}).Where("id = ?", id).Update("config_version", version).Error
+}
+
+// CreateEnrollmentToken 创建注册令牌
+func (r *Repository) CreateEnrollmentToken(token *model.EnrollmentToken) error {
+	return r.db.Create(token).Error
+}
+
+// ListEnrollmentTokens 获取注册令牌列表
+func (r *Repository) ListEnrollmentTokens() ([]model.EnrollmentToken, error) {
+	var tokens []model.EnrollmentToken
+	err := r.db.Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// GetEnrollmentToken 根据摘要获取注册令牌
+func (r *Repository) GetEnrollmentToken(hash string) (*model.EnrollmentToken, error) {
+	var token model.EnrollmentToken
+	if err := r.db.Where("token = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteEnrollmentToken 删除注册令牌
+func (r *Repository) DeleteEnrollmentToken(id uint64) error {
+	return r.db.Delete(&model.EnrollmentToken{}, id).Error
+}
+
+// EnrollAgent 消耗注册令牌并创建节点，一次性令牌通过条件更新保证只能使用一次
+func (r *Repository) EnrollAgent(tokenID uint64, agent *model.Agent, digest, sealedKey string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.EnrollmentToken{}).
+			Where("id = ? AND enabled = ? AND (reusable = ? OR used_count = 0)", tokenID, true, true).
+			Where("(expires_at IS NULL OR expires_at > ?)", time.Now()).
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrEnrollmentTokenUsed
+		}
+
+		agent.APIKey = sealedKey
+		if err := tx.Create(agent).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.AgentToken{AgentID: agent.ID, Token: digest, Enabled: true}).Error
+	})
+}
+
+// UpdateAgentCert 记录节点证书过期时间
+func (r *Repository) UpdateAgentCert(id uint64, notAfter time.Time) error {
+	return r.db.Model(&model.Agent{}).Where("id = ?", id).Update("cert_not_after", notAfter).Error
+}
+
+// SaveAgentToken 保存Agent令牌比对摘要（轮换时覆盖旧令牌），并将加密后的签名密钥同步到 Agent.APIKey
+func (r *Repository) SaveAgentToken(agentID uint64, digest, sealedKey string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		token := &model.AgentToken{AgentID: agentID, Token: digest, Enabled: true}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "agent_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"token", "enabled", "updated_at"}),
+		}).Create(token).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Agent{}).Where("id = ?", agentID).Update("api_key", sealedKey).Error
+	})
+}
+
+// GetAgentToken 获取Agent令牌
+func (r *Repository) GetAgentToken(agentID uint64) (*model.AgentToken, error) {
+	var token model.AgentToken
+	err := r.db.Where("agent_id = ?", agentID).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeAgentToken 吊销Agent令牌
+func (r *Repository) RevokeAgentToken(agentID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.AgentToken{}).Where("agent_id = ?", agentID).Update("enabled", false).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Agent{}).Where("id = ?", agentID).Update("api_key", "").Error
+	})
+}
+
 // SaveInspection 保存巡检记录
 func (r *Repository) SaveInspection(inspection *model.Inspection) error {
 	return r.db.Create(inspection).Error
 }
 
-// LatestInspections 获取最新巡检记录
+// withInspectionDetails 预加载巡检记录的明细表
+func (r *Repository) withInspectionDetails() *gorm.DB {
+	return r.db.Preload("Mounts").Preload("Services").Preload("Processes").Preload("ProcessWatches").Preload("Interfaces").Preload("Probes").Preload("Certificates").Preload("LogMatches").Preload("Findings").Preload("Packages").Preload("Vulnerabilities")
+}
+
+// LatestInspections 获取每个Agent的最新巡检记录
+// 以自增ID取最新一条，避免同一时刻多条记录重复，且兼容各数据库方言
 func (r *Repository) LatestInspections() ([]model.Inspection, error) {
 	var inspections []model.Inspection
-	err := r.db.Raw(`
-		SELECT i.* FROM inspections i
-		INNER JOIN (
-			SELECT agent_id, MAX(created_at) AS max_time 
-			FROM inspections 
-			GROUP BY agent_id
-		) t ON i.agent_id = t.agent_id AND i.created_at = t.max_time
-	`).Scan(&inspections).Error
+	latest := r.db.Model(&model.Inspection{}).
+		Select("MAX(id)").
+		Group("agent_id")
+	err := r.withInspectionDetails().Where("id IN (?)", latest).Find(&inspections).Error
+	return inspections, err
+}
+
+// LatestAuditedInspections 获取每个Agent最近一次含合规得分的巡检记录
+// 节点不可达或本次采集未执行审计时最新巡检没有合规得分，不能按最新巡检取
+func (r *Repository) LatestAuditedInspections() ([]model.Inspection, error) {
+	var inspections []model.Inspection
+	latest := r.db.Model(&model.Inspection{}).
+		Select("MAX(id)").
+		Where("compliance_score IS NOT NULL").
+		Group("agent_id")
+	err := r.withInspectionDetails().Where("id IN (?)", latest).Find(&inspections).Error
 	return inspections, err
 }
 
 // GetInspectionsByAgentID 获取指定Agent的巡检记录
 func (r *Repository) GetInspectionsByAgentID(agentID uint64, limit int) ([]model.Inspection, error) {
 	var inspections []model.Inspection
-	err := r.db.Where("agent_id = ?", agentID).
+	err := r.withInspectionDetails().Where("agent_id = ?", agentID).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&inspections).Error
 	return inspections, err
 }
 
+// ExpiringCertificates 获取各节点最近一次含证书的巡检中即将到期的证书
+// 节点不可达时记录的巡检没有证书，不能按最新巡检取
+func (r *Repository) ExpiringCertificates(before time.Time) ([]model.InspectionCertificate, error) {
+	var certs []model.InspectionCertificate
+	latest := r.db.Model(&model.InspectionCertificate{}).
+		Select("MAX(inspection_id)").
+		Group("agent_id")
+	err := r.db.Where("inspection_id IN (?) AND error = ? AND not_after <= ?", latest, "", before).
+		Order("not_after").
+		Find(&certs).Error
+	return certs, err
+}
+
 // CreateAlert 创建告警记录
 func (r *Repository) CreateAlert(alert *model.Alert) error {
 	return r.db.Create(alert).Error
@@ -225,6 +364,70 @@ func (r *Repository) UpdateAlertStatus(id uint64, status model.AlertStatus) erro
 	return r.db.Model(&model.Alert{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// ListAlertRules 获取所有阈值规则
+func (r *Repository) ListAlertRules() ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	err := r.db.Order("id asc").Find(&rules).Error
+	return rules, err
+}
+
+// GetAlertRuleByID 根据ID获取阈值规则
+func (r *Repository) GetAlertRuleByID(id uint64) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	err := r.db.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateAlertRule 创建阈值规则
+func (r *Repository) CreateAlertRule(rule *model.AlertRule) error {
+	return r.db.Create(rule).Error
+}
+
+// UpdateAlertRule 更新阈值规则
+func (r *Repository) UpdateAlertRule(rule *model.AlertRule) error {
+	return r.db.Save(rule).Error
+}
+
+// DeleteAlertRule 删除阈值规则
+func (r *Repository) DeleteAlertRule(id uint64) error {
+	return r.db.Delete(&model.AlertRule{}, id).Error
+}
+
+// ListConfigProfiles 获取所有远程配置
+func (r *Repository) ListConfigProfiles() ([]model.ConfigProfile, error) {
+	var profiles []model.ConfigProfile
+	err := r.db.Order("id asc").Find(&profiles).Error
+	return profiles, err
+}
+
+// GetConfigProfileByID 根据ID获取远程配置
+func (r *Repository) GetConfigProfileByID(id uint64) (*model.ConfigProfile, error) {
+	var profile model.ConfigProfile
+	err := r.db.First(&profile, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CreateConfigProfile 创建远程配置
+func (r *Repository) CreateConfigProfile(profile *model.ConfigProfile) error {
+	return r.db.Create(profile).Error
+}
+
+// UpdateConfigProfile 更新远程配置
+func (r *Repository) UpdateConfigProfile(profile *model.ConfigProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// DeleteConfigProfile 删除远程配置
+func (r *Repository) DeleteConfigProfile(id uint64) error {
+	return r.db.Delete(&model.ConfigProfile{}, id).Error
+}
+
 // InitAdminUser 初始化管理员用户
 func (r *Repository) InitAdminUser(username, password string) error {
 	// 检查是否已存在管理员
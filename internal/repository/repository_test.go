@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/database"
+	"cyber-inspector/internal/migrate"
+	"cyber-inspector/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRepository 使用临时 SQLite 文件创建仓库
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	db, err := database.Open(config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		DSN:    filepath.Join(t.TempDir(), "test.db"),
+	}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+
+	if _, err := migrate.Up(db); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	return New(db)
+}
+
+func TestLatestInspections(t *testing.T) {
+	repo := newTestRepository(t)
+
+	a1 := &model.Agent{Name: "a1", IP: "10.0.0.1", URL: "http://10.0.0.1:8083", Enabled: true}
+	a2 := &model.Agent{Name: "a2", IP: "10.0.0.2", URL: "http://10.0.0.2:8083", Enabled: true}
+	for _, a := range []*model.Agent{a1, a2} {
+		if err := repo.CreateAgent(a); err != nil {
+			t.Fatalf("创建节点失败: %v", err)
+		}
+	}
+
+	score := 87.5
+	for _, ins := range []*model.Inspection{
+		{AgentID: a1.ID, Hostname: "a1", IP: a1.IP, Level: model.LevelOK},
+		{AgentID: a1.ID, Hostname: "a1", IP: a1.IP, Level: model.LevelCritical, DiskUsed: 95, Mounts: []model.InspectionMount{
+			{AgentID: a1.ID, Device: "/dev/sda2", Path: "/", FSType: "ext4", UsedPercent: 95, InodesPercent: 10},
+			{AgentID: a1.ID, Device: "/dev/sdb1", Path: "/data", FSType: "xfs", UsedPercent: 30, InodesPercent: 1},
+		}},
+		{AgentID: a2.ID, Hostname: "a2", IP: a2.IP, Level: model.LevelWarning, Certificates: []model.InspectionCertificate{
+			{AgentID: a2.ID, Source: "a2:443", SANs: []string{"a2"}, NotAfter: time.Now().AddDate(0, 0, 5), DaysLeft: 5},
+			{AgentID: a2.ID, Source: "/etc/ssl/a2.pem", NotAfter: time.Now().AddDate(1, 0, 0), DaysLeft: 365},
+		}, ComplianceScore: &score, Findings: []model.InspectionFinding{
+			{AgentID: a2.ID, FindingID: "account.uid0:toor", Check: "account.uid0", Severity: model.LevelCritical},
+		}},
+	} {
+		if err := repo.SaveInspection(ins); err != nil {
+			t.Fatalf("保存巡检记录失败: %v", err)
+		}
+	}
+
+	latest, err := repo.LatestInspections()
+	if err != nil {
+		t.Fatalf("查询最新巡检失败: %v", err)
+	}
+	if len(latest) != 2 {
+		t.Fatalf("期望 2 条记录，实际 %d", len(latest))
+	}
+
+	levels := make(map[uint64]model.InspectionLevel)
+	for _, ins := range latest {
+		levels[ins.AgentID] = ins.Level
+		if ins.AgentID == a1.ID && (len(ins.Mounts) != 2 || ins.Mounts[0].Path != "/") {
+			t.Fatalf("最新记录应包含挂载点: %+v", ins.Mounts)
+		}
+		if ins.AgentID == a1.ID && ins.ComplianceScore != nil {
+			t.Fatalf("未审计的记录合规得分应为空: %v", *ins.ComplianceScore)
+		}
+		if ins.AgentID == a2.ID && (ins.ComplianceScore == nil || *ins.ComplianceScore != score || len(ins.Findings) != 1) {
+			t.Fatalf("最新记录应包含合规得分与审计问题: %v %+v", ins.ComplianceScore, ins.Findings)
+		}
+	}
+	if levels[a1.ID] != model.LevelCritical || levels[a2.ID] != model.LevelWarning {
+		t.Fatalf("最新记录不正确: %v", levels)
+	}
+
+	certs, err := repo.ExpiringCertificates(time.Now().AddDate(0, 0, 30))
+	if err != nil {
+		t.Fatalf("查询即将到期的证书失败: %v", err)
+	}
+	if len(certs) != 1 || certs[0].Source != "a2:443" || len(certs[0].SANs) != 1 {
+		t.Fatalf("即将到期的证书不正确: %+v", certs)
+	}
+
+	// 节点不可达时的巡检没有证书，仍取最近一次含证书的巡检；恰好在截止时间到期的证书也包含在内
+	if err := repo.SaveInspection(&model.Inspection{AgentID: a2.ID, Hostname: "a2", IP: a2.IP, Level: model.LevelCritical}); err != nil {
+		t.Fatal(err)
+	}
+	certs, _ = repo.ExpiringCertificates(certs[0].NotAfter)
+	if len(certs) != 1 || certs[0].Source != "a2:443" {
+		t.Fatalf("不可达后即将到期的证书不正确: %+v", certs)
+	}
+
+	// 合规得分同样取最近一次审计的巡检
+	audited, err := repo.LatestAuditedInspections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audited) != 1 || audited[0].AgentID != a2.ID || audited[0].ComplianceScore == nil || len(audited[0].Findings) != 1 {
+		t.Fatalf("不可达后应取最近一次审计的巡检: %+v", audited)
+	}
+}
+
+func TestInitAdminUser(t *testing.T) {
+	repo := newTestRepository(t)
+
+	if err := repo.InitAdminUser("admin", "admin123"); err != nil {
+		t.Fatalf("初始化管理员失败: %v", err)
+	}
+	// 重复初始化不应创建新用户
+	if err := repo.InitAdminUser("admin2", "admin123"); err != nil {
+		t.Fatalf("重复初始化失败: %v", err)
+	}
+
+	users, err := repo.ListUsers()
+	if err != nil {
+		t.Fatalf("查询用户失败: %v", err)
+	}
+	if len(users) != 1 || !CheckPassword("admin123", users[0].Password) {
+		t.Fatalf("管理员账户不正确: %+v", users)
+	}
+
+	if err := repo.UpdateUserLastLogin(users[0].ID); err != nil {
+		t.Fatalf("更新登录时间失败: %v", err)
+	}
+}
+
+func TestAgentTokenRotateAndRevoke(t *testing.T) {
+	repo := newTestRepository(t)
+
+	a := &model.Agent{Name: "a1", IP: "10.0.0.1", URL: "http://10.0.0.1:8083", Enabled: true}
+	if err := repo.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []string{"1", "2"} {
+		if err := repo.SaveAgentToken(a.ID, "digest-"+n, "sealed-"+n); err != nil {
+			t.Fatalf("保存令牌失败: %v", err)
+		}
+	}
+
+	token, err := repo.GetAgentToken(a.ID)
+	if err != nil || token.Token != "digest-2" || !token.Enabled {
+		t.Fatalf("轮换后应只保留最新令牌: %+v %v", token, err)
+	}
+	if got, _ := repo.GetAgentByID(a.ID); got.APIKey != "sealed-2" {
+		t.Fatalf("签名密钥未同步: %q", got.APIKey)
+	}
+
+	if err := repo.RevokeAgentToken(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	token, _ = repo.GetAgentToken(a.ID)
+	if got, _ := repo.GetAgentByID(a.ID); token.Enabled || got.APIKey != "" {
+		t.Fatalf("吊销后令牌应失效: %+v %q", token, got.APIKey)
+	}
+}
+
+func TestEnrollAgentOneTimeToken(t *testing.T) {
+	repo := newTestRepository(t)
+
+	token := &model.EnrollmentToken{Name: "once", Token: "hash-once", Enabled: true}
+	if err := repo.CreateEnrollmentToken(token); err != nil {
+		t.Fatalf("创建注册令牌失败: %v", err)
+	}
+
+	first := &model.Agent{Name: "n1", IP: "10.0.0.1", URL: "http://10.0.0.1:8083", Enabled: true, Status: model.AgentPending}
+	if err := repo.EnrollAgent(token.ID, first, "digest-1", "sealed-1"); err != nil {
+		t.Fatalf("首次注册失败: %v", err)
+	}
+	if got, _ := repo.GetAgentToken(first.ID); got == nil || got.Token != "digest-1" || first.APIKey != "sealed-1" {
+		t.Fatal("注册后应签发节点令牌")
+	}
+
+	second := &model.Agent{Name: "n2", IP: "10.0.0.2", URL: "http://10.0.0.2:8083", Enabled: true, Status: model.AgentPending}
+	if err := repo.EnrollAgent(token.ID, second, "digest-2", "sealed-2"); err != ErrEnrollmentTokenUsed {
+		t.Fatalf("一次性令牌不应被重复使用，实际 %v", err)
+	}
+
+	// 待审批节点不参与巡检
+	active, _ := repo.GetActiveAgents()
+	if len(active) != 0 {
+		t.Fatalf("待审批节点不应被巡检，实际 %d 个", len(active))
+	}
+}
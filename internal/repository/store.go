@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"cyber-inspector/internal/model"
+)
+
+// UserStore 用户数据访问
+type UserStore interface {
+	CreateUser(user *model.User) error
+	GetUserByUsername(username string) (*model.User, error)
+	GetUserByID(id uint64) (*model.User, error)
+	ListUsers() ([]model.User, error)
+	UpdateUser(user *model.User) error
+	UpdateUserPassword(id uint64, password string) error
+	UpdateUserStatus(id uint64, enabled bool) error
+	UpdateUserLastLogin(id uint64) error
+	DeleteUser(id uint64) error
+	CreateLoginLog(log *model.LoginLog) error
+	InitAdminUser(username, password string) error
+}
+
+// AgentStore 节点数据访问
+type AgentStore interface {
+	CreateAgent(agent *model.Agent) error
+	ListAgents() ([]model.Agent, error)
+	GetActiveAgents() ([]model.Agent, error)
+	GetAgentByID(id uint64) (*model.Agent, error)
+	UpdateAgent(agent *model.Agent) error
+	DeleteAgent(id uint64) error
+	UpdateAgentStatus(id uint64, status model.AgentStatus) error
+	UpdateCheckInterval(id uint64, seconds int) error
+	UpdateAgentCert(id uint64, notAfter time.Time) error
+	UpdateAgentProtocol(id uint64, protocolVersion int, agentVersion, capabilities string) error
+	UpdateAgentConfigVersion(id uint64, version string) error
+
+	// SaveAgentToken 保存令牌比对摘要，加密后的签名密钥同步到 Agent.APIKey
+	SaveAgentToken(agentID uint64, digest, sealedKey string) error
+	GetAgentToken(agentID uint64) (*model.AgentToken, error)
+	RevokeAgentToken(agentID uint64) error
+}
+
+// EnrollmentStore 自注册令牌数据访问
+type EnrollmentStore interface {
+	CreateEnrollmentToken(token *model.EnrollmentToken) error
+	ListEnrollmentTokens() ([]model.EnrollmentToken, error)
+	GetEnrollmentToken(hash string) (*model.EnrollmentToken, error)
+	DeleteEnrollmentToken(id uint64) error
+
+	// EnrollAgent 消耗一次注册令牌并创建节点及其令牌，三者在同一事务中完成
+	EnrollAgent(tokenID uint64, agent *model.Agent, digest, sealedKey string) error
+}
+
+// ErrEnrollmentTokenUsed 注册令牌已失效（被使用、禁用或过期）
+var ErrEnrollmentTokenUsed = errors.New("注册令牌已失效")
+
+// InspectionStore 巡检记录数据访问
+type InspectionStore interface {
+	SaveInspection(inspection *model.Inspection) error
+	LatestInspections() ([]model.Inspection, error)
+	// LatestAuditedInspections 各节点最近一次含安全基线审计结果的巡检记录
+	LatestAuditedInspections() ([]model.Inspection, error)
+	GetInspectionsByAgentID(agentID uint64, limit int) ([]model.Inspection, error)
+	// ExpiringCertificates 各节点最近一次含证书的巡检中不晚于 before 到期的证书，按到期时间排序，不含检查失败的证书
+	ExpiringCertificates(before time.Time) ([]model.InspectionCertificate, error)
+}
+
+// AlertStore 告警记录与阈值规则数据访问
+type AlertStore interface {
+	CreateAlert(alert *model.Alert) error
+	GetAlerts(limit, offset int) ([]model.Alert, error)
+	GetAlertStats(days int) (map[string]interface{}, error)
+	UpdateAlertStatus(id uint64, status model.AlertStatus) error
+
+	ListAlertRules() ([]model.AlertRule, error)
+	GetAlertRuleByID(id uint64) (*model.AlertRule, error)
+	CreateAlertRule(rule *model.AlertRule) error
+	UpdateAlertRule(rule *model.AlertRule) error
+	DeleteAlertRule(id uint64) error
+}
+
+// ProfileStore Agent 远程配置数据访问
+type ProfileStore interface {
+	ListConfigProfiles() ([]model.ConfigProfile, error)
+	GetConfigProfileByID(id uint64) (*model.ConfigProfile, error)
+	CreateConfigProfile(profile *model.ConfigProfile) error
+	UpdateConfigProfile(profile *model.ConfigProfile) error
+	DeleteConfigProfile(id uint64) error
+}
+
+// Store 全部数据访问接口
+type Store interface {
+	UserStore
+	AgentStore
+	EnrollmentStore
+	InspectionStore
+	AlertStore
+	ProfileStore
+}
+
+// 确保 GORM 实现满足接口
+var _ Store = (*Repository)(nil)
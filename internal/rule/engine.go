@@ -0,0 +1,213 @@
+// file: internal/rule/engine.go
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/model"
+)
+
+// 内置指标名称
+const (
+	MetricCPU            = "cpu_used"
+	MetricMemory         = "memory_used"
+	MetricDisk           = "disk_used"
+	MetricInodes         = "inodes_used"
+	MetricLoadAvg        = "load_avg"
+	MetricPingLoss       = "ping_loss"
+	MetricJournalErr     = "journal_err_1h"
+	MetricProcessCount   = "process_count"
+	MetricTCPConnections = "tcp_connections"
+	MetricFailedUnits    = "failed_units"
+	MetricThreadCount    = "thread_count"
+	MetricZombieCount    = "zombie_count"
+	MetricConntrack      = "conntrack_used"
+	MetricListenPorts    = "listen_ports"
+	MetricCertDaysLeft   = "cert_days_left" // 检查成功的证书中最少的剩余天数
+	MetricCompliance     = "compliance_score"
+	MetricAuditFindings  = "audit_findings"   // 安全基线审计发现的问题数
+	MetricPackageUpdates = "package_updates"  // 有可用更新的软件包数
+	MetricSecUpdates     = "security_updates" // 有安全更新的软件包数
+	MetricVulns          = "vulnerabilities"  // 与漏洞库匹配的漏洞数
+)
+
+// 按 systemd 单元展开的指标前缀，如 service_active:nginx
+const (
+	MetricServiceActive   = "service_active:"   // 单元运行中为 1，否则为 0
+	MetricServiceRestarts = "service_restarts:" // 单元自动重启次数
+	MetricProcessWatch    = "process_count:"    // 进程守护匹配的进程数，如 process_count:nginx-worker
+	MetricTCPState        = "tcp_state:"        // 指定状态的 TCP 连接数，如 tcp_state:TIME_WAIT
+	MetricListenPort      = "listen_port:"      // 端口处于监听状态为 1，否则为 0，如 listen_port:443
+	MetricNetRxRate       = "net_rx_rate:"      // 网卡接收速率（字节/秒），如 net_rx_rate:eth0
+	MetricNetTxRate       = "net_tx_rate:"      // 网卡发送速率（字节/秒）
+	MetricNetErrors       = "net_errors:"       // 网卡收发错误累计数
+	MetricNetDropped      = "net_dropped:"      // 网卡收发丢包累计数
+	MetricProbeSuccess    = "probe_success:"    // 探测成功为 1，否则为 0，如 probe_success:db
+	MetricProbeLatency    = "probe_latency:"    // 探测耗时（毫秒）
+	MetricLogMatches      = "log_matches:"      // 日志规则自上次采集以来的匹配行数，如 log_matches:oom
+)
+
+// Metrics 从巡检记录中提取可评估的指标
+func Metrics(ins *model.Inspection) map[string]float64 {
+	metrics := map[string]float64{
+		MetricCPU:            ins.CPUUsed,
+		MetricMemory:         ins.MemoryUsed,
+		MetricDisk:           ins.DiskUsed,
+		MetricInodes:         ins.InodesUsed,
+		MetricLoadAvg:        ins.LoadAvg,
+		MetricPingLoss:       ins.PingLoss,
+		MetricJournalErr:     float64(ins.JournalErr1h),
+		MetricProcessCount:   float64(ins.ProcessCount),
+		MetricTCPConnections: float64(ins.TCPConnections),
+		MetricFailedUnits:    float64(ins.FailedUnits),
+		MetricThreadCount:    float64(ins.ThreadCount),
+		MetricZombieCount:    float64(ins.ZombieCount),
+	}
+	for _, s := range ins.Services {
+		active := 0.0
+		if s.ActiveState == "active" {
+			active = 1
+		}
+		metrics[MetricServiceActive+s.Name] = active
+		metrics[MetricServiceRestarts+s.Name] = float64(s.Restarts)
+	}
+	for _, w := range ins.ProcessWatches {
+		metrics[MetricProcessWatch+w.Name] = float64(w.Count)
+	}
+	// 未启用网络采集时不产生网络指标，避免端口监听规则误报
+	if ins.TCPStates != nil {
+		metrics[MetricConntrack] = ins.ConntrackUsed
+		metrics[MetricListenPorts] = float64(len(ins.ListenPorts))
+		for state, n := range ins.TCPStates {
+			metrics[MetricTCPState+state] = float64(n)
+		}
+		for _, port := range ins.ListenPorts {
+			metrics[fmt.Sprintf("%s%d", MetricListenPort, port)] = 1
+		}
+	}
+	for _, i := range ins.Interfaces {
+		metrics[MetricNetRxRate+i.Name] = i.RxRate
+		metrics[MetricNetTxRate+i.Name] = i.TxRate
+		metrics[MetricNetErrors+i.Name] = float64(i.RxErrors + i.TxErrors)
+		metrics[MetricNetDropped+i.Name] = float64(i.RxDropped + i.TxDropped)
+	}
+	for _, c := range ins.Certificates {
+		if c.Error != "" {
+			continue
+		}
+		if v, ok := metrics[MetricCertDaysLeft]; !ok || float64(c.DaysLeft) < v {
+			metrics[MetricCertDaysLeft] = float64(c.DaysLeft)
+		}
+	}
+	// 未启用审计时不产生审计指标
+	if ins.ComplianceScore != nil {
+		metrics[MetricCompliance] = *ins.ComplianceScore
+		metrics[MetricAuditFindings] = float64(len(ins.Findings))
+	}
+	// 未启用软件包采集时不产生软件包指标
+	if ins.PackageManager != "" {
+		metrics[MetricPackageUpdates] = float64(ins.PackageUpdates)
+		metrics[MetricSecUpdates] = float64(ins.SecurityUpdates)
+		metrics[MetricVulns] = float64(len(ins.Vulnerabilities))
+	}
+	for _, lm := range ins.LogMatches {
+		metrics[MetricLogMatches+lm.Rule] = float64(lm.Count)
+	}
+	for _, p := range ins.Probes {
+		success := 0.0
+		if p.Success {
+			success = 1
+		}
+		metrics[MetricProbeSuccess+p.Name] = success
+		metrics[MetricProbeLatency+p.Name] = p.Latency
+	}
+	return metrics
+}
+
+// lookup 取规则对应的指标值；上报了网络数据但端口未监听时 listen_port 指标为 0
+func lookup(metrics map[string]float64, metric string) (float64, bool) {
+	if v, ok := metrics[metric]; ok {
+		return v, true
+	}
+	if _, ok := metrics[MetricListenPorts]; ok && strings.HasPrefix(metric, MetricListenPort) {
+		return 0, true
+	}
+	return 0, false
+}
+
+// Violation 规则触发结果
+type Violation struct {
+	Rule  Rule
+	Value float64
+	Since time.Time
+}
+
+// String 触发描述
+func (v Violation) String() string {
+	name := v.Rule.Name
+	if name == "" {
+		name = v.Rule.Metric
+	}
+	return fmt.Sprintf("[%s] %s: 当前值 %g (%s)", v.Rule.Severity, name, v.Value, v.Rule)
+}
+
+// Engine 规则评估引擎，记录带持续时间条件的规则首次满足时间
+type Engine struct {
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+// NewEngine 创建规则引擎
+func NewEngine() *Engine {
+	return &Engine{pending: make(map[string]time.Time)}
+}
+
+// Evaluate 评估节点指标，返回已触发的规则
+func (e *Engine) Evaluate(agentID uint64, rules []Rule, metrics map[string]float64, now time.Time) []Violation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var violations []Violation
+	active := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		key := fmt.Sprintf("%d|%s", agentID, r.Key())
+		active[key] = true
+
+		v, ok := lookup(metrics, r.Metric)
+		if !ok || !r.Match(v) {
+			delete(e.pending, key)
+			continue
+		}
+
+		since, ok := e.pending[key]
+		if !ok {
+			since = now
+			e.pending[key] = now
+		}
+		if now.Sub(since) < r.For {
+			continue
+		}
+		violations = append(violations, Violation{Rule: r, Value: v, Since: since})
+	}
+
+	// 清理已失效规则的状态
+	prefix := fmt.Sprintf("%d|", agentID)
+	for key := range e.pending {
+		if strings.HasPrefix(key, prefix) && !active[key] {
+			delete(e.pending, key)
+		}
+	}
+	return violations
+}
+
+// Level 返回触发规则中的最高级别
+func Level(violations []Violation) model.InspectionLevel {
+	level := model.LevelOK
+	for _, v := range violations {
+		level = MaxLevel(level, v.Rule.Severity)
+	}
+	return level
+}
@@ -0,0 +1,65 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/model"
+)
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEvaluate(t *testing.T) {
+	rules := []Rule{
+		{Metric: MetricCPU, Operator: model.OpGreater, Value: 85, Severity: model.LevelCritical},
+		{Metric: MetricMemory, Operator: model.OpGreaterEqual, Value: 80, Severity: model.LevelWarning},
+		{Metric: MetricListenPort + "443", Operator: model.OpEqual, Value: 0, Severity: model.LevelCritical},
+		{Metric: MetricServiceActive + "nginx", Operator: model.OpEqual, Value: 0, Severity: model.LevelCritical},
+	}
+	ins := &model.Inspection{CPUUsed: 50, MemoryUsed: 80, TCPStates: map[string]int{"ESTABLISHED": 3}, ListenPorts: []int{22}}
+
+	violations := NewEngine().Evaluate(1, rules, Metrics(ins), testNow)
+	if len(violations) != 2 {
+		t.Fatalf("violations = %+v", violations)
+	}
+	// 未上报的服务不触发，上报了网络数据时未监听的端口按 0 计算
+	if violations[0].Rule.Metric != MetricMemory || violations[1].Rule.Metric != MetricListenPort+"443" {
+		t.Errorf("violations = %+v", violations)
+	}
+	if Level(violations) != model.LevelCritical || Level(nil) != model.LevelOK {
+		t.Errorf("级别计算错误")
+	}
+}
+
+func TestEvaluateFor(t *testing.T) {
+	e := NewEngine()
+	rules := []Rule{{Metric: MetricCPU, Operator: model.OpGreater, Value: 85, For: 10 * time.Minute, Severity: model.LevelCritical}}
+	high := map[string]float64{MetricCPU: 95}
+
+	if v := e.Evaluate(1, rules, high, testNow); len(v) != 0 {
+		t.Fatalf("未满持续时间不应触发: %+v", v)
+	}
+	if v := e.Evaluate(1, rules, high, testNow.Add(5*time.Minute)); len(v) != 0 {
+		t.Fatalf("未满持续时间不应触发: %+v", v)
+	}
+	// 其它节点的状态互不影响
+	if v := e.Evaluate(2, rules, high, testNow.Add(10*time.Minute)); len(v) != 0 {
+		t.Fatalf("节点 2 刚开始满足条件: %+v", v)
+	}
+	v := e.Evaluate(1, rules, high, testNow.Add(10*time.Minute))
+	if len(v) != 1 || !v[0].Since.Equal(testNow) {
+		t.Fatalf("满持续时间应触发并记录首次满足时间: %+v", v)
+	}
+
+	// 条件中断后重新计时
+	e.Evaluate(1, rules, map[string]float64{MetricCPU: 50}, testNow.Add(11*time.Minute))
+	if v := e.Evaluate(1, rules, high, testNow.Add(12*time.Minute)); len(v) != 0 {
+		t.Errorf("中断后应重新计时: %+v", v)
+	}
+
+	// 规则移除后清理状态
+	e.Evaluate(1, nil, high, testNow.Add(13*time.Minute))
+	if v := e.Evaluate(1, rules, high, testNow.Add(30*time.Minute)); len(v) != 0 {
+		t.Errorf("规则移除后应清理状态: %+v", v)
+	}
+}
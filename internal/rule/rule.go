@@ -0,0 +1,205 @@
+// file: internal/rule/rule.go
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+)
+
+// Rule 生效中的阈值规则
+type Rule struct {
+	Name     string                `json:"name"`
+	Metric   string                `json:"metric"`
+	Operator model.RuleOperator    `json:"operator"`
+	Value    float64               `json:"value"`
+	For      time.Duration         `json:"for"`
+	Severity model.InspectionLevel `json:"severity"`
+	Source   string                `json:"source"` // 规则来源：config / global / tag:xxx / agent
+}
+
+// Key 规则标识，同一指标同一级别的规则由更具体的范围覆盖
+func (r Rule) Key() string {
+	return r.Metric + "|" + string(r.Severity)
+}
+
+// Match 判断指标值是否触发规则
+func (r Rule) Match(v float64) bool {
+	switch r.Operator {
+	case model.OpGreater:
+		return v > r.Value
+	case model.OpGreaterEqual:
+		return v >= r.Value
+	case model.OpLess:
+		return v < r.Value
+	case model.OpLessEqual:
+		return v <= r.Value
+	case model.OpEqual:
+		return v == r.Value
+	case model.OpNotEqual:
+		return v != r.Value
+	}
+	return false
+}
+
+// String 规则描述
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s %s %g", r.Metric, r.Operator, r.Value)
+	if r.For > 0 {
+		s += fmt.Sprintf(" for %v", r.For)
+	}
+	return s
+}
+
+// Defaults 根据配置生成全局默认规则，alert.rules 已在加载配置时校验
+func Defaults(cfg config.AlertConfig) []Rule {
+	if len(cfg.Rules) > 0 {
+		rules := make([]Rule, 0, len(cfg.Rules))
+		for _, rc := range cfg.Rules {
+			rules = append(rules, Rule{
+				Name:     rc.Name,
+				Metric:   rc.Metric,
+				Operator: model.RuleOperator(rc.Operator),
+				Value:    rc.Value,
+				For:      rc.For,
+				Severity: model.InspectionLevel(strings.ToUpper(rc.Severity)),
+				Source:   "config",
+			})
+		}
+		return rules
+	}
+
+	var rules []Rule
+	add := func(name, metric string, value float64) {
+		if value <= 0 {
+			return
+		}
+		rules = append(rules, Rule{
+			Name:     name,
+			Metric:   metric,
+			Operator: model.OpGreater,
+			Value:    value,
+			Severity: model.LevelCritical,
+			Source:   "config",
+		})
+	}
+	add("CPU 使用率过高", MetricCPU, cfg.Threshold.CPU)
+	add("内存使用率过高", MetricMemory, cfg.Threshold.Memory)
+	add("磁盘使用率过高", MetricDisk, cfg.Threshold.Disk)
+	add("inode 使用率过高", MetricInodes, cfg.Threshold.Inodes)
+	add("系统负载过高", MetricLoadAvg, cfg.Threshold.LoadAvg)
+
+	// 证书按剩余天数由低到高依次为 CRITICAL、WARNING，剩余天数等于阈值时即告警
+	for _, r := range []struct {
+		days     int
+		severity model.InspectionLevel
+	}{{cfg.Threshold.CertWarningDays, model.LevelWarning}, {cfg.Threshold.CertCriticalDays, model.LevelCritical}} {
+		if r.days <= 0 {
+			continue
+		}
+		rules = append(rules, Rule{
+			Name:     "证书即将过期",
+			Metric:   MetricCertDaysLeft,
+			Operator: model.OpLessEqual,
+			Value:    float64(r.days),
+			Severity: r.severity,
+			Source:   "config",
+		})
+	}
+	return rules
+}
+
+// FromModel 将数据库规则转换为生效规则
+func FromModel(m model.AlertRule) Rule {
+	source := "global"
+	switch {
+	case m.AgentID != 0:
+		source = "agent"
+	case m.Tag != "":
+		source = "tag:" + m.Tag
+	}
+	return Rule{
+		Name:     m.Name,
+		Metric:   m.Metric,
+		Operator: m.Operator,
+		Value:    m.Value,
+		For:      time.Duration(m.For) * time.Second,
+		Severity: m.Severity,
+		Source:   source,
+	}
+}
+
+// Resolve 计算节点的生效规则
+// 覆盖顺序：配置默认 < 全局规则 < 标签规则 < 节点规则；被禁用的规则会屏蔽更低层级的同名规则
+func Resolve(defaults []Rule, stored []model.AlertRule, agent model.Agent) []Rule {
+	effective := make(map[string]*Rule)
+	var order []string
+
+	apply := func(r Rule, enabled bool) {
+		key := r.Key()
+		if _, ok := effective[key]; !ok {
+			order = append(order, key)
+		}
+		if !enabled {
+			effective[key] = nil
+			return
+		}
+		rr := r
+		effective[key] = &rr
+	}
+
+	for _, r := range defaults {
+		apply(r, true)
+	}
+
+	tags := make(map[string]bool)
+	for _, t := range agent.TagList() {
+		tags[t] = true
+	}
+
+	// 按范围由宽到窄依次覆盖
+	for _, scope := range []func(model.AlertRule) bool{
+		func(m model.AlertRule) bool { return m.AgentID == 0 && m.Tag == "" },
+		func(m model.AlertRule) bool { return m.AgentID == 0 && m.Tag != "" && tags[m.Tag] },
+		func(m model.AlertRule) bool { return m.AgentID != 0 && m.AgentID == agent.ID },
+	} {
+		for _, m := range stored {
+			if scope(m) {
+				apply(FromModel(m), m.Enabled)
+			}
+		}
+	}
+
+	rules := make([]Rule, 0, len(order))
+	for _, key := range order {
+		if r := effective[key]; r != nil {
+			rules = append(rules, *r)
+		}
+	}
+	return rules
+}
+
+// levelRank 级别权重
+func levelRank(l model.InspectionLevel) int {
+	switch l {
+	case model.LevelCritical:
+		return 2
+	case model.LevelWarning:
+		return 1
+	}
+	return 0
+}
+
+// MaxLevel 返回较严重的级别
+func MaxLevel(a, b model.InspectionLevel) model.InspectionLevel {
+	if levelRank(b) > levelRank(a) {
+		return b
+	}
+	if a == "" {
+		return model.LevelOK
+	}
+	return a
+}
@@ -0,0 +1,81 @@
+package rule
+
+import (
+	"testing"
+
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+)
+
+func TestResolve(t *testing.T) {
+	var cfg config.AlertConfig
+	cfg.Threshold.CPU, cfg.Threshold.Memory = 85, 90
+	defaults := Defaults(cfg)
+	stored := []model.AlertRule{
+		// 节点规则写在最前面，仍然最后覆盖
+		{ID: 1, Metric: MetricCPU, Operator: model.OpGreater, Value: 95, Severity: model.LevelCritical, AgentID: 7, Enabled: true},
+		{ID: 2, Metric: MetricCPU, Operator: model.OpGreater, Value: 80, Severity: model.LevelCritical, Enabled: true},
+		{ID: 3, Metric: MetricCPU, Operator: model.OpGreater, Value: 90, Severity: model.LevelCritical, Tag: "db", Enabled: true},
+		{ID: 4, Metric: MetricCPU, Operator: model.OpGreater, Value: 70, Severity: model.LevelCritical, Tag: "web", Enabled: true},
+		// 禁用的标签规则屏蔽配置默认规则
+		{ID: 5, Metric: MetricMemory, Operator: model.OpGreater, Value: 90, Severity: model.LevelCritical, Tag: "db", Enabled: false},
+		{ID: 6, Metric: MetricDisk, Operator: model.OpGreater, Value: 80, Severity: model.LevelWarning, AgentID: 8, Enabled: true},
+	}
+
+	byKey := func(rules []Rule) map[string]Rule {
+		m := make(map[string]Rule)
+		for _, r := range rules {
+			m[r.Key()] = r
+		}
+		return m
+	}
+	cpu := MetricCPU + "|" + string(model.LevelCritical)
+	memory := MetricMemory + "|" + string(model.LevelCritical)
+
+	rules := byKey(Resolve(defaults, stored, model.Agent{ID: 7, Tags: "db, linux"}))
+	if r := rules[cpu]; r.Value != 95 || r.Source != "agent" {
+		t.Errorf("节点规则应覆盖标签与全局规则: %+v", r)
+	}
+	if _, ok := rules[memory]; ok {
+		t.Errorf("禁用的标签规则应屏蔽配置默认规则: %+v", rules[memory])
+	}
+	if len(rules) != 1 {
+		t.Errorf("其它节点的规则不应生效: %+v", rules)
+	}
+
+	rules = byKey(Resolve(defaults, stored, model.Agent{ID: 9, Tags: "db"}))
+	if r := rules[cpu]; r.Value != 90 || r.Source != "tag:db" {
+		t.Errorf("标签规则应覆盖全局规则: %+v", r)
+	}
+
+	rules = byKey(Resolve(defaults, stored, model.Agent{ID: 10}))
+	if r := rules[cpu]; r.Value != 80 || r.Source != "global" {
+		t.Errorf("全局规则应覆盖配置默认规则: %+v", r)
+	}
+	if r := rules[memory]; r.Value != 90 || r.Source != "config" {
+		t.Errorf("未覆盖的配置默认规则应保留: %+v", r)
+	}
+}
+
+func TestDefaultsCertificates(t *testing.T) {
+	var cfg config.AlertConfig
+	cfg.Threshold.CertWarningDays, cfg.Threshold.CertCriticalDays = 30, 7
+
+	// 剩余天数等于阈值时即告警
+	severity := func(days float64) model.InspectionLevel {
+		level := model.LevelOK
+		for _, r := range Defaults(cfg) {
+			if r.Metric == MetricCertDaysLeft && r.Match(days) && (level == model.LevelOK || r.Severity == model.LevelCritical) {
+				level = r.Severity
+			}
+		}
+		return level
+	}
+	for days, want := range map[float64]model.InspectionLevel{
+		6: model.LevelCritical, 7: model.LevelCritical, 8: model.LevelWarning, 30: model.LevelWarning, 31: model.LevelOK,
+	} {
+		if got := severity(days); got != want {
+			t.Errorf("剩余 %v 天 = %s, want %s", days, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// file: internal/service/breaker.go
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BreakerStatus 熔断器状态
+type BreakerStatus string
+
+const (
+	BreakerClosed   BreakerStatus = "closed"    // 正常拉取
+	BreakerOpen     BreakerStatus = "open"      // 熔断中，冷却结束前不拉取
+	BreakerHalfOpen BreakerStatus = "half_open" // 冷却结束，正在单次探测
+)
+
+// BreakerState 熔断器状态快照
+type BreakerState struct {
+	AgentID   uint64        `json:"agent_id"`
+	Status    BreakerStatus `json:"status"`
+	Failures  int           `json:"failures"`   // 连续失败次数
+	OpenedAt  time.Time     `json:"opened_at"`  // 最近一次熔断时间
+	NextProbe time.Time     `json:"next_probe"` // 下次允许探测的时间
+}
+
+// breakerEntry 单个节点的熔断状态
+type breakerEntry struct {
+	status    BreakerStatus
+	failures  int
+	cooldown  time.Duration
+	openedAt  time.Time
+	nextProbe time.Time
+}
+
+// Breaker 节点熔断器
+// 连续失败达到阈值后熔断，冷却期内跳过拉取；冷却结束后放行一次探测，
+// 探测成功恢复正常，失败则冷却时间翻倍（不超过上限）后再次熔断
+type Breaker struct {
+	mu          sync.Mutex
+	threshold   int
+	cooldown    time.Duration
+	maxCooldown time.Duration
+	entries     map[uint64]*breakerEntry
+}
+
+// NewBreaker 创建熔断器，threshold 小于等于 0 时不熔断
+func NewBreaker(threshold int, cooldown, maxCooldown time.Duration) *Breaker {
+	if maxCooldown < cooldown {
+		maxCooldown = cooldown
+	}
+	return &Breaker{
+		threshold:   threshold,
+		cooldown:    cooldown,
+		maxCooldown: maxCooldown,
+		entries:     make(map[uint64]*breakerEntry),
+	}
+}
+
+// Allow 判断本轮是否拉取该节点，冷却结束时转为探测状态
+func (b *Breaker) Allow(agentID uint64, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[agentID]
+	if !ok {
+		return true
+	}
+	switch e.status {
+	case BreakerOpen:
+		if now.Before(e.nextProbe) {
+			return false
+		}
+		e.status = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		// 上一次探测尚未结束
+		return false
+	default:
+		return true
+	}
+}
+
+// Status 返回节点的熔断状态
+func (b *Breaker) Status(agentID uint64) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[agentID]; ok {
+		return e.status
+	}
+	return BreakerClosed
+}
+
+// Success 记录拉取成功，返回节点是否从熔断中恢复
+func (b *Breaker) Success(agentID uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[agentID]
+	if !ok {
+		return false
+	}
+	delete(b.entries, agentID)
+	return e.status != BreakerClosed
+}
+
+// Failure 记录拉取失败，返回节点是否因此进入熔断
+func (b *Breaker) Failure(agentID uint64, now time.Time) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[agentID]
+	if !ok {
+		e = &breakerEntry{status: BreakerClosed}
+		b.entries[agentID] = e
+	}
+	e.failures++
+
+	switch {
+	case e.status == BreakerHalfOpen:
+		// 探测失败，延长冷却时间
+		e.cooldown *= 2
+		if e.cooldown > b.maxCooldown {
+			e.cooldown = b.maxCooldown
+		}
+	case e.status == BreakerClosed && e.failures >= b.threshold:
+		e.cooldown = b.cooldown
+	default:
+		return false
+	}
+
+	opened := e.status == BreakerClosed
+	e.status = BreakerOpen
+	e.openedAt = now
+	e.nextProbe = now.Add(e.cooldown)
+	return opened
+}
+
+// Release 探测被取消（如服务停止）时恢复熔断状态，下一轮重新探测
+func (b *Breaker) Release(agentID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[agentID]; ok && e.status == BreakerHalfOpen {
+		e.status = BreakerOpen
+	}
+}
+
+// States 返回熔断中或连续失败的节点状态
+func (b *Breaker) States() []BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make([]BreakerState, 0, len(b.entries))
+	for id, e := range b.entries {
+		s := BreakerState{AgentID: id, Status: e.status, Failures: e.failures, OpenedAt: e.openedAt}
+		if e.status != BreakerClosed {
+			s.NextProbe = e.nextProbe
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].AgentID < states[j].AgentID })
+	return states
+}
@@ -4,8 +4,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,24 +15,70 @@ import (
 	"cyber-inspector/internal/config"
 	"cyber-inspector/internal/mailer"
 	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
 	"cyber-inspector/internal/repository"
+	"cyber-inspector/internal/rule"
+	"cyber-inspector/internal/vuln"
 )
 
 // Checker 巡检服务
 type Checker struct {
-	repo   *repository.Repository
-	client *agent.Client
-	cancel context.CancelFunc
-	mu     sync.Mutex
-	wg     sync.WaitGroup
+	repo     repository.Store
+	client   *agent.Client
+	rules    *rule.Engine
+	flaps    *FlapDetector
+	stream   *StreamHub
+	breaker  *Breaker
+	vulns    *vuln.Database
+	cooldown map[string]time.Time // 告警冷却缓存
+	seen     sync.Map             // 节点最近一次巡检结果的采集时间，用于识别 Agent 缓存的重复结果
+	ctx      context.Context      // 服务运行期间有效，Stop 时取消进行中的拉取
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	wg       sync.WaitGroup
 }
 
 // NewChecker 创建巡检服务
-func NewChecker(repo *repository.Repository, client *agent.Client) *Checker {
-	return &Checker{
-		repo:   repo,
-		client: client,
+func NewChecker(repo repository.Store, client *agent.Client) *Checker {
+	c := &Checker{
+		repo:     repo,
+		client:   client,
+		rules:    rule.NewEngine(),
+		vulns:    vuln.NewDatabase(),
+		cooldown: make(map[string]time.Time),
+		ctx:      context.Background(),
+		flaps: NewFlapDetector(
+			config.Conf.Alert.Flapping.Window,
+			config.Conf.Alert.Flapping.HighThreshold,
+			config.Conf.Alert.Flapping.LowThreshold,
+		),
 	}
+	c.stream = NewStreamHub(repo, c.Ingest)
+
+	threshold := 0
+	if config.Conf.Check.Breaker.Enabled {
+		threshold = config.Conf.Check.Breaker.Threshold
+	}
+	c.breaker = NewBreaker(threshold, config.Conf.Check.Breaker.Cooldown, config.Conf.Check.Breaker.MaxCooldown)
+
+	if feed := config.Conf.Vuln.Feed; feed != "" {
+		if err := c.vulns.Load(feed); err != nil {
+			log.Printf("【漏洞库】加载 %s 失败: %v", feed, err)
+		} else {
+			log.Printf("【漏洞库】已加载 %d 条漏洞", c.vulns.Status().Vulnerabilities)
+		}
+	}
+	return c
+}
+
+// Vulnerabilities 返回本地漏洞库
+func (c *Checker) Vulnerabilities() *vuln.Database {
+	return c.vulns
+}
+
+// Stream 返回长连接管理器
+func (c *Checker) Stream() *StreamHub {
+	return c.stream
 }
 
 // Start 启动巡检服务
@@ -39,12 +87,12 @@ func (c *Checker) Start() {
 	defer c.mu.Unlock()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	c.cancel = cancel
+	c.ctx, c.cancel = ctx, cancel
 
 	log.Println("【巡检服务】已启动")
 
 	// 立即执行一次巡检
-	go c.batchCheck()
+	go c.batchCheck(ctx)
 
 	// 启动定时巡检
 	ticker := time.NewTicker(config.Conf.Check.Interval)
@@ -55,7 +103,7 @@ func (c *Checker) Start() {
 				ticker.Stop()
 				return
 			case <-ticker.C:
-				go c.batchCheck()
+				go c.batchCheck(ctx)
 			}
 		}
 	}()
@@ -75,8 +123,8 @@ func (c *Checker) Stop() {
 	log.Println("【巡检服务】已停止")
 }
 
-// batchCheck 批量巡检
-func (c *Checker) batchCheck() {
+// batchCheck 批量巡检，ctx 取消时中止进行中的拉取与重试
+func (c *Checker) batchCheck(ctx context.Context) {
 	start := time.Now()
 	log.Println("【批量巡检】开始...")
 
@@ -86,6 +134,15 @@ func (c *Checker) batchCheck() {
 		return
 	}
 
+	// 推送模式的节点不拉取，只检查是否按时上报
+	agents = c.checkPushAgents(agents, start)
+
+	// 已建立长连接的节点通过命令触发巡检，结果经长连接上报
+	agents = c.dispatchStreamed(agents)
+
+	// 熔断中的节点跳过拉取，不占用并发名额
+	agents = c.filterBroken(agents, start)
+
 	if len(agents) == 0 {
 		log.Println("【批量巡检】没有活跃的节点需要巡检")
 		return
@@ -96,14 +153,19 @@ func (c *Checker) batchCheck() {
 	results := make(chan *InspectionResult, len(agents))
 
 	// 启动巡检任务
+	var batch sync.WaitGroup
 	for _, agent := range agents {
 		c.wg.Add(1)
-		go c.checkAgent(agent, semaphore, results)
+		batch.Add(1)
+		go func(agent model.Agent) {
+			defer batch.Done()
+			c.checkAgent(ctx, agent, semaphore, results)
+		}(agent)
 	}
 
-	// 等待所有任务完成
+	// 等待本轮任务完成
 	go func() {
-		c.wg.Wait()
+		batch.Wait()
 		close(results)
 	}()
 
@@ -111,6 +173,44 @@ func (c *Checker) batchCheck() {
 	c.processResults(results, start)
 }
 
+// pushStaleFactor 推送节点超过该倍数的巡检间隔未上报时视为离线
+const pushStaleFactor = 3
+
+// checkPushAgents 将长时间未上报的推送节点标记为离线，返回需要拉取的节点
+func (c *Checker) checkPushAgents(agents []model.Agent, now time.Time) []model.Agent {
+	pull := agents[:0:0]
+	for _, a := range agents {
+		if a.Mode != model.AgentModePush {
+			pull = append(pull, a)
+			continue
+		}
+
+		interval := time.Duration(a.CheckInterval) * time.Second
+		if interval <= 0 {
+			interval = config.Conf.Check.Interval
+		}
+		stale := a.LastCheckAt == nil || now.Sub(*a.LastCheckAt) > pushStaleFactor*interval
+		if stale && a.Status != model.AgentOffline {
+			log.Printf("【推送超时】节点: %s 超过 %v 未上报", a.Name, pushStaleFactor*interval)
+			c.repo.UpdateAgentStatus(a.ID, model.AgentOffline)
+		}
+	}
+	return pull
+}
+
+// dispatchStreamed 向已建立长连接的节点下发巡检命令，返回仍需拉取的节点
+func (c *Checker) dispatchStreamed(agents []model.Agent) []model.Agent {
+	pull := agents[:0:0]
+	for _, a := range agents {
+		if _, err := c.stream.Send(a.ID, protocol.CommandInspect); err != nil {
+			pull = append(pull, a)
+			continue
+		}
+		log.Printf("【长连接巡检】已向节点 %s 下发巡检命令", a.Name)
+	}
+	return pull
+}
+
 // InspectionResult 巡检结果
 type InspectionResult struct {
 	Agent      *model.Agent
@@ -119,74 +219,302 @@ type InspectionResult struct {
 	Duration   time.Duration
 }
 
-// checkAgent 巡检单个节点
-func (c *Checker) checkAgent(agent model.Agent, semaphore chan struct{}, results chan<- *InspectionResult) {
+// filterBroken 过滤熔断冷却中的节点
+func (c *Checker) filterBroken(agents []model.Agent, now time.Time) []model.Agent {
+	pull := agents[:0:0]
+	for _, a := range agents {
+		if c.breaker.Allow(a.ID, now) {
+			pull = append(pull, a)
+		}
+	}
+	if skipped := len(agents) - len(pull); skipped > 0 {
+		log.Printf("【熔断】本轮跳过 %d 个节点", skipped)
+	}
+	return pull
+}
+
+// checkAgent 巡检单个节点，失败时按指数退避加随机抖动重试
+func (c *Checker) checkAgent(ctx context.Context, node model.Agent, semaphore chan struct{}, results chan<- *InspectionResult) {
 	defer c.wg.Done()
 
 	// 获取信号量
-	semaphore <- struct{}{}
+	select {
+	case semaphore <- struct{}{}:
+	case <-ctx.Done():
+		c.breaker.Release(node.ID)
+		return
+	}
 	defer func() { <-semaphore }()
 
 	start := time.Now()
 	result := &InspectionResult{
-		Agent:    &agent,
+		Agent:    &node,
 		Duration: 0,
 	}
 
+	timeout := node.PullTimeout(config.Conf.Check.Timeout)
+	backoff := &agent.Backoff{Min: config.Conf.Check.RetryBackoff, Max: timeout}
+
 	// 重试机制
 	for i := 0; i < config.Conf.Check.RetryTimes; i++ {
-		inspection, err := c.client.Pull(agent)
+		inspection, err := c.pullLimited(ctx, node, timeout)
 		if err == nil {
 			result.Inspection = inspection
-			result.Duration = time.Since(start)
+			result.Error = nil
 			break
 		}
 
+		// 等待后仍被限流时不再重试，本轮跳过该节点
 		result.Error = err
-		if i < config.Conf.Check.RetryTimes-1 {
-			time.Sleep(time.Second * time.Duration(i+1)) // 指数退避
+		if i == config.Conf.Check.RetryTimes-1 || isRateLimited(err) {
+			break
+		}
+
+		timer := time.NewTimer(backoff.Next())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
 		}
 	}
+	result.Duration = time.Since(start)
+
+	// 服务停止导致的中断不计入失败，也不记录结果
+	if ctx.Err() != nil {
+		c.breaker.Release(node.ID)
+		return
+	}
 
 	results <- result
 }
 
+// pull 在节点超时时间内拉取一次
+func (c *Checker) pull(ctx context.Context, agent model.Agent, timeout time.Duration) (*model.Inspection, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return c.client.Pull(ctx, agent)
+}
+
+// pullLimited 拉取一次，Agent 限流时按 Retry-After 等待后再拉取一次
+// Retry-After 超过拉取超时时间时不等待，直接返回限流错误
+func (c *Checker) pullLimited(ctx context.Context, node model.Agent, timeout time.Duration) (*model.Inspection, error) {
+	inspection, err := c.pull(ctx, node, timeout)
+	var limited *agent.RateLimitError
+	if !errors.As(err, &limited) || limited.RetryAfter > timeout {
+		return inspection, err
+	}
+
+	wait := limited.RetryAfter
+	if wait <= 0 {
+		wait = config.Conf.Check.RetryBackoff
+	}
+	log.Printf("【限流】节点: %s, 等待 %v 后重新拉取", node.Name, wait)
+	timer := time.NewTimer(wait)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+	return c.pull(ctx, node, timeout)
+}
+
+// isRateLimited 是否为 Agent 限流错误
+func isRateLimited(err error) bool {
+	var limited *agent.RateLimitError
+	return errors.As(err, &limited)
+}
+
 // processResults 处理巡检结果
 func (c *Checker) processResults(results <-chan *InspectionResult, startTime time.Time) {
-	var successCount, failedCount int
+	var successCount, failedCount, skippedCount int
+
+	// 加载数据库中的规则，本轮巡检共用
+	stored, err := c.repo.ListAlertRules()
+	if err != nil {
+		log.Printf("【规则加载失败】错误: %v", err)
+	}
+	defaults := rule.Defaults(config.Conf.Alert)
 
 	for result := range results {
+		// 限流说明节点在线，本轮跳过，不计入熔断失败也不记录不可达
+		if isRateLimited(result.Error) {
+			skippedCount++
+			c.breaker.Release(result.Agent.ID)
+			log.Printf("【限流】节点: %s 仍被限流，跳过本轮巡检", result.Agent.Name)
+			continue
+		}
 		if result.Error != nil {
 			failedCount++
 			log.Printf("【巡检失败】节点: %s, 错误: %v", result.Agent.Name, result.Error)
+			c.processFailure(result, defaults, stored)
 			continue
 		}
 
 		successCount++
-
-		// 保存巡检结果
-		if err := c.repo.SaveInspection(result.Inspection); err != nil {
-			log.Printf("【保存失败】节点: %s, 错误: %v", result.Agent.Name, err)
-			continue
+		if c.breaker.Success(result.Agent.ID) {
+			log.Printf("【熔断恢复】节点: %s 探测成功，恢复正常拉取", result.Agent.Name)
 		}
+		c.processResult(result, defaults, stored)
+	}
 
-		// 处理告警
-		c.processAlert(result.Inspection, result.Agent)
+	elapsed := time.Since(startTime)
+	log.Printf("【批量巡检】完成 %d 个节点, 成功: %d, 失败: %d, 限流跳过: %d, 耗时: %v",
+		successCount+failedCount+skippedCount, successCount, failedCount, skippedCount, elapsed)
+}
+
+// processFailure 记录拉取失败：保存 CRITICAL 巡检记录并标记节点离线，
+// 熔断后的探测失败只更新熔断状态，避免冷却期间重复记录
+func (c *Checker) processFailure(result *InspectionResult, defaults []rule.Rule, stored []model.AlertRule) {
+	node := result.Agent
+	probing := c.breaker.Status(node.ID) == BreakerHalfOpen
+	if c.breaker.Failure(node.ID, time.Now()) {
+		log.Printf("【熔断】节点: %s 连续拉取失败，暂停拉取 %v", node.Name, config.Conf.Check.Breaker.Cooldown)
+	}
+	if probing {
+		log.Printf("【熔断】节点: %s 探测失败，继续熔断", node.Name)
+		return
+	}
 
-		// 更新Agent最后巡检时间
+	result.Inspection = agent.UnreachableInspection(*result.Agent, result.Error)
+	c.processResult(result, defaults, stored)
+}
+
+// processResult 评估规则、保存巡检结果并处理告警
+func (c *Checker) processResult(result *InspectionResult, defaults []rule.Rule, stored []model.AlertRule) error {
+	// Agent 在缓存有效期内返回同一份结果时只刷新节点状态，不重复记录与告警
+	if c.duplicate(result) {
+		log.Printf("【缓存结果】节点: %s, 采集于 %s，跳过重复记录",
+			result.Agent.Name, result.Inspection.CreatedAt.Format(time.RFC3339))
 		c.repo.UpdateAgentStatus(result.Agent.ID, model.AgentOnline)
+		return nil
+	}
 
-		log.Printf("【巡检成功】节点: %s, 级别: %s, 耗时: %v",
-			result.Agent.Name, result.Inspection.Level, result.Duration)
+	// 已安装的软件包与本地漏洞库匹配
+	c.vulns.Match(result.Inspection)
+
+	// 规则窗口与抖动检测使用同一时间基准，推送补发的结果按采集时间计算
+	now := time.Now()
+	if !result.Inspection.CreatedAt.IsZero() {
+		now = result.Inspection.CreatedAt
 	}
 
-	elapsed := time.Since(startTime)
-	log.Printf("【批量巡检】完成 %d 个节点, 成功: %d, 失败: %d, 耗时: %v",
-		successCount+failedCount, successCount, failedCount, elapsed)
+	// 评估阈值规则
+	rules := rule.Resolve(defaults, stored, *result.Agent)
+	violations, flaps := c.evaluateRules(result.Inspection, result.Agent, rules, now)
+
+	// 保存巡检结果
+	if err := c.repo.SaveInspection(result.Inspection); err != nil {
+		log.Printf("【保存失败】节点: %s, 错误: %v", result.Agent.Name, err)
+		return err
+	}
+
+	// 处理告警
+	c.processAlert(result.Inspection, result.Agent, violations, flaps, now)
+
+	// 更新Agent状态与最后巡检时间
+	if result.Error != nil {
+		c.repo.UpdateAgentStatus(result.Agent.ID, model.AgentOffline)
+	} else {
+		c.repo.UpdateAgentStatus(result.Agent.ID, model.AgentOnline)
+	}
+
+	// 记录节点当前生效的远程配置版本
+	if v := result.Inspection.ConfigVersion; result.Error == nil && v != result.Agent.ConfigVersion {
+		if err := c.repo.UpdateAgentConfigVersion(result.Agent.ID, v); err != nil {
+			log.Printf("【更新配置版本失败】节点: %s, 错误: %v", result.Agent.Name, err)
+		}
+	}
+
+	// 滚动升级期间记录节点协议版本变化
+	if v := result.Inspection.ProtocolVersion; v != 0 && v != result.Agent.ProtocolVersion {
+		log.Printf("【协议版本】节点: %s, v%d -> v%d", result.Agent.Name, result.Agent.ProtocolVersion, v)
+		if err := c.repo.UpdateAgentProtocol(result.Agent.ID, v, "", ""); err != nil {
+			log.Printf("【更新协议版本失败】节点: %s, 错误: %v", result.Agent.Name, err)
+		}
+	}
+
+	log.Printf("【巡检成功】节点: %s, 级别: %s, 耗时: %v",
+		result.Agent.Name, result.Inspection.Level, result.Duration)
+	return nil
+}
+
+// duplicate 巡检结果的采集时间与该节点上一次结果相同，说明是 Agent 缓存的同一份数据
+func (c *Checker) duplicate(result *InspectionResult) bool {
+	collectedAt := result.Inspection.CreatedAt
+	if result.Error != nil || collectedAt.IsZero() {
+		return false
+	}
+	last, loaded := c.seen.Swap(result.Agent.ID, collectedAt)
+	return loaded && last.(time.Time).Equal(collectedAt)
+}
+
+// Ingest 处理 Agent 主动推送的巡检结果，与拉取结果走相同的规则、存储与告警流程
+func (c *Checker) Ingest(agent *model.Agent, inspection *model.Inspection) error {
+	stored, err := c.repo.ListAlertRules()
+	if err != nil {
+		log.Printf("【规则加载失败】错误: %v", err)
+	}
+	return c.processResult(&InspectionResult{Agent: agent, Inspection: inspection}, rule.Defaults(config.Conf.Alert), stored)
+}
+
+// flapEvent 条件抖动状态变化
+type flapEvent struct {
+	condition  string
+	transition FlapTransition
+}
+
+// 抖动检测的条件名称
+const (
+	conditionLevel      = "level"
+	conditionRulePrefix = "rule:"
+)
+
+// evaluateRules 评估阈值规则，触发时提升巡检级别
+// 处于抖动状态的规则不参与级别计算，其状态变化通过 flapEvent 返回
+func (c *Checker) evaluateRules(inspection *model.Inspection, agent *model.Agent, rules []rule.Rule, now time.Time) ([]rule.Violation, []flapEvent) {
+	fired := c.rules.Evaluate(agent.ID, rules, rule.Metrics(inspection), now)
+
+	var events []flapEvent
+	violations := fired
+	if config.Conf.Alert.Flapping.Enabled {
+		firedKeys := make(map[string]bool, len(fired))
+		for _, v := range fired {
+			firedKeys[v.Rule.Key()] = true
+		}
+		for _, r := range rules {
+			condition := conditionRulePrefix + r.Key()
+			if t := c.flaps.Record(agent.ID, condition, firedKeys[r.Key()], now); t != FlapNone {
+				events = append(events, flapEvent{condition: condition, transition: t})
+			}
+		}
+
+		violations = violations[:0:0]
+		for _, v := range fired {
+			if !c.flaps.IsFlapping(agent.ID, conditionRulePrefix+v.Rule.Key()) {
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil, events
+	}
+
+	level := rule.Level(violations)
+	if rule.MaxLevel(inspection.Level, level) != inspection.Level {
+		log.Printf("【规则触发】节点: %s, 级别: %s -> %s", agent.Name, inspection.Level, level)
+		inspection.Level = level
+	}
+	inspection.Alert = true
+	return violations, events
 }
 
-// processAlert 处理告警
-func (c *Checker) processAlert(inspection *model.Inspection, agent *model.Agent) {
+// processAlert 处理告警，now 为规则评估使用的时间
+func (c *Checker) processAlert(inspection *model.Inspection, agent *model.Agent, violations []rule.Violation, flaps []flapEvent, now time.Time) {
 	log.Printf("[AlertDebug] 进入processAlert: agent=%s, level=%s, alertEnabled=%v",
 		agent.Name, inspection.Level, config.Conf.Alert.Enabled)
 
@@ -195,6 +523,19 @@ func (c *Checker) processAlert(inspection *model.Inspection, agent *model.Agent)
 		return
 	}
 
+	// 抖动检测：抖动期间只发送一次抖动通知，不再单独告警
+	if config.Conf.Alert.Flapping.Enabled {
+		problem := inspection.Level == model.LevelCritical
+		if t := c.flaps.Record(agent.ID, conditionLevel, problem, now); t != FlapNone {
+			flaps = append(flaps, flapEvent{condition: conditionLevel, transition: t})
+		}
+		c.processFlapping(inspection, agent, flaps)
+		if c.flaps.IsFlapping(agent.ID, conditionLevel) {
+			log.Printf("【告警抑制】节点: %s, 状态抖动中，跳过告警", agent.Name)
+			return
+		}
+	}
+
 	if inspection.Level != model.LevelCritical {
 		log.Printf("[AlertDebug] 非CRITICAL，不告警")
 		return
@@ -229,6 +570,33 @@ func (c *Checker) processAlert(inspection *model.Inspection, agent *model.Agent)
 		}
 	}
 
+	// 规则触发时附带触发明细
+	if len(violations) > 0 {
+		lines := make([]string, 0, len(violations))
+		for _, v := range violations {
+			lines = append(lines, v.String())
+			if top := topProcesses(inspection, v.Rule.Metric); top != "" {
+				lines = append(lines, "  "+top)
+			}
+			for _, cert := range expiringCertificates(inspection, v.Rule) {
+				lines = append(lines, "  "+cert)
+			}
+			for _, sample := range logSamples(inspection, v.Rule.Metric) {
+				lines = append(lines, "  "+sample)
+			}
+			for _, f := range auditFindings(inspection, v.Rule.Metric) {
+				lines = append(lines, "  "+f)
+			}
+			for _, p := range packageDetails(inspection, v.Rule.Metric) {
+				lines = append(lines, "  "+p)
+			}
+		}
+		alert.Details = strings.Join(lines, "\n")
+		if alert.Summary == "" {
+			alert.Summary = fmt.Sprintf("节点 %s 触发 %d 条阈值规则", agent.Name, len(violations))
+		}
+	}
+
 	if err := c.repo.CreateAlert(alert); err != nil {
 		log.Printf("【告警创建失败】节点: %s, 错误: %v", agent.Name, err)
 		return
@@ -242,8 +610,162 @@ func (c *Checker) processAlert(inspection *model.Inspection, agent *model.Agent)
 	}
 }
 
-// 告警冷却缓存
-var alertCooldown = make(map[string]time.Time)
+// topProcesses CPU 或内存规则触发时，说明占用最高的进程
+func topProcesses(inspection *model.Inspection, metric string) string {
+	var top string
+	switch metric {
+	case rule.MetricCPU, rule.MetricLoadAvg:
+		top = model.ProcessTopCPU
+	case rule.MetricMemory:
+		top = model.ProcessTopMemory
+	default:
+		return ""
+	}
+
+	var items []string
+	for _, p := range inspection.Processes {
+		if p.Top != top {
+			continue
+		}
+		if top == model.ProcessTopCPU {
+			items = append(items, fmt.Sprintf("%s(%d) %.1f%%", p.Name, p.PID, p.CPU))
+		} else {
+			items = append(items, fmt.Sprintf("%s(%d) %.1fMB", p.Name, p.PID, float64(p.RSS)/1024/1024))
+		}
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return "占用最高的进程: " + strings.Join(items, ", ")
+}
+
+// expiringCertificates 证书规则触发时，列出满足规则的证书
+func expiringCertificates(inspection *model.Inspection, r rule.Rule) []string {
+	if r.Metric != rule.MetricCertDaysLeft {
+		return nil
+	}
+	var items []string
+	for _, c := range inspection.Certificates {
+		if c.Error == "" && r.Match(float64(c.DaysLeft)) {
+			items = append(items, fmt.Sprintf("%s（%s）%s 到期，剩余 %d 天", c.Source, c.Subject, c.NotAfter.Format("2006-01-02"), c.DaysLeft))
+		}
+	}
+	return items
+}
+
+// logSamples 日志规则触发时，附带匹配的日志行作为证据
+func logSamples(inspection *model.Inspection, metric string) []string {
+	name, ok := strings.CutPrefix(metric, rule.MetricLogMatches)
+	if !ok {
+		return nil
+	}
+	for _, lm := range inspection.LogMatches {
+		if lm.Rule == name {
+			return lm.Samples
+		}
+	}
+	return nil
+}
+
+// auditFindings 审计规则触发时，列出审计发现的问题
+func auditFindings(inspection *model.Inspection, metric string) []string {
+	if metric != rule.MetricCompliance && metric != rule.MetricAuditFindings {
+		return nil
+	}
+	items := make([]string, 0, len(inspection.Findings))
+	for _, f := range inspection.Findings {
+		items = append(items, fmt.Sprintf("[%s] %s", f.Severity, f.Title))
+	}
+	return items
+}
+
+// packageDetails 软件包规则触发时，列出有安全更新的软件包或匹配到的漏洞
+func packageDetails(inspection *model.Inspection, metric string) []string {
+	var items []string
+	switch metric {
+	case rule.MetricSecUpdates:
+		for _, p := range inspection.Packages {
+			if p.Security {
+				items = append(items, fmt.Sprintf("%s %s → %s", p.Name, p.Version, p.Update))
+			}
+		}
+	case rule.MetricVulns:
+		for _, v := range inspection.Vulnerabilities {
+			item := fmt.Sprintf("[%s] %s %s %s", v.Severity, v.VulnID, v.Package, v.Version)
+			if v.Fixed != "" {
+				item += "，修复版本 " + v.Fixed
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// processFlapping 处理抖动状态变化：节点开始抖动时发送一次抖动通知，同一节点的多个条件共用该通知，
+// 全部条件退出抖动后关闭
+func (c *Checker) processFlapping(inspection *model.Inspection, agent *model.Agent, events []flapEvent) {
+	var started []string
+	for _, e := range events {
+		switch e.transition {
+		case FlapStarted:
+			log.Printf("【状态抖动】节点: %s, 条件: %s", agent.Name, e.condition)
+			started = append(started, e.condition)
+		case FlapStopped:
+			log.Printf("【抖动结束】节点: %s, 条件: %s", agent.Name, e.condition)
+		}
+	}
+
+	id := c.flaps.AlertID(agent.ID)
+	switch {
+	case len(started) > 0 && id == 0:
+		conditions := strings.Join(started, ", ")
+		alert := &model.Alert{
+			AgentID:      agent.ID,
+			InspectionID: inspection.ID,
+			Level:        model.LevelWarning,
+			Title:        fmt.Sprintf("%s - FLAPPING", agent.Name),
+			Summary:      fmt.Sprintf("节点 %s 的条件 %s 状态频繁翻转，抖动期间暂停单独通知", agent.Name, conditions),
+			Details:      conditions,
+		}
+		if err := c.repo.CreateAlert(alert); err != nil {
+			log.Printf("【告警创建失败】节点: %s, 错误: %v", agent.Name, err)
+			return
+		}
+		c.flaps.SetAlertID(agent.ID, alert.ID)
+
+		if config.Conf.Mail.Enabled && config.Conf.Mail.Host != "" {
+			c.sendAlertMail(alert, agent)
+		}
+
+	case id != 0 && !c.flaps.AgentFlapping(agent.ID):
+		if err := c.repo.UpdateAlertStatus(id, model.AlertResolved); err != nil {
+			log.Printf("【更新告警状态失败】节点: %s, 错误: %v", agent.Name, err)
+		}
+		c.flaps.SetAlertID(agent.ID, 0)
+	}
+}
+
+// Breakers 获取连续失败或熔断中的节点
+func (c *Checker) Breakers() []BreakerState {
+	return c.breaker.States()
+}
+
+// AgentInfo 查询节点的协议版本与能力，并更新节点记录
+func (c *Checker) AgentInfo(ctx context.Context, agent model.Agent) (*protocol.Info, error) {
+	info, err := c.client.Info(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.repo.UpdateAgentProtocol(agent.ID, info.ProtocolVersion, info.AgentVersion, strings.Join(info.Capabilities, ",")); err != nil {
+		log.Printf("【更新协议版本失败】节点: %s, 错误: %v", agent.Name, err)
+	}
+	return info, nil
+}
+
+// Flapping 返回当前处于抖动状态的条件
+func (c *Checker) Flapping() []FlapState {
+	return c.flaps.Flapping()
+}
 
 // canSendAlert 检查是否可以发送告警
 func (c *Checker) canSendAlert(agentID uint64, level model.InspectionLevel) bool {
@@ -252,13 +774,13 @@ func (c *Checker) canSendAlert(agentID uint64, level model.InspectionLevel) bool
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if lastAlert, ok := alertCooldown[key]; ok {
+	if lastAlert, ok := c.cooldown[key]; ok {
 		if time.Since(lastAlert) < config.Conf.Alert.Cooldown {
 			return false
 		}
 	}
 
-	alertCooldown[key] = time.Now()
+	c.cooldown[key] = time.Now()
 	return true
 }
 
@@ -311,6 +833,10 @@ IP地址：%s
 
 // BatchCheck 手动触发巡检
 func (c *Checker) BatchCheck() {
-	go c.batchCheck()
+	c.mu.Lock()
+	ctx := c.ctx
+	c.mu.Unlock()
+
+	go c.batchCheck(ctx)
 	log.Println("【手动巡检】已触发")
 }
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/config"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/repository/memory"
+)
+
+// setupConfig 设置测试用全局配置
+func setupConfig(t *testing.T) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Check.Interval = time.Hour
+	cfg.Check.MaxConcurrent = 2
+	cfg.Check.RetryTimes = 1
+	cfg.Check.Timeout = 5 * time.Second
+	cfg.Check.RetryBackoff = 10 * time.Millisecond
+	cfg.Check.Breaker = config.BreakerConfig{Enabled: true, Threshold: 2, Cooldown: time.Hour, MaxCooldown: time.Hour}
+	cfg.Alert.Enabled = true
+	cfg.Alert.Cooldown = time.Minute
+	cfg.Alert.Threshold.CPU = 85
+	cfg.Alert.Threshold.Memory = 90
+	cfg.Alert.Flapping = config.FlappingConfig{Enabled: true, Window: 20, HighThreshold: 50, LowThreshold: 25}
+
+	old := config.Conf
+	config.Conf = cfg
+	t.Cleanup(func() { config.Conf = old })
+}
+
+// newAgentServer 模拟 Agent 的 /inspect 接口
+func newAgentServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inspect" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBatchCheckRuleEscalation(t *testing.T) {
+	setupConfig(t)
+
+	srv := newAgentServer(t, `{
+		"hostname": "web-01",
+		"raw_data": {"cpu_used": "95.5%", "mem_used": "40.00%", "cpu_load": "0.5", "ping_loss": "0"},
+		"analysis": {"alert": false, "level": "OK", "summary": "一切正常"}
+	}`)
+
+	store := memory.New()
+	a := &model.Agent{Name: "web-01", IP: "10.0.0.1", URL: srv.URL, Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := NewChecker(store, agent.NewClient())
+	checker.batchCheck(context.Background())
+
+	inspections, _ := store.GetInspectionsByAgentID(a.ID, 10)
+	if len(inspections) != 1 {
+		t.Fatalf("期望 1 条巡检记录，实际 %d", len(inspections))
+	}
+	ins := inspections[0]
+	if ins.Level != model.LevelCritical || !ins.Alert {
+		t.Fatalf("CPU 超过阈值应提升为 CRITICAL，实际 %s", ins.Level)
+	}
+	if ins.CPUUsed != 95.5 {
+		t.Fatalf("CPU 解析错误: %v", ins.CPUUsed)
+	}
+
+	alerts, _ := store.GetAlerts(10, 0)
+	if len(alerts) != 1 {
+		t.Fatalf("期望 1 条告警，实际 %d", len(alerts))
+	}
+	if !strings.Contains(alerts[0].Details, "cpu_used") {
+		t.Fatalf("告警应包含触发的规则: %q", alerts[0].Details)
+	}
+
+	got, _ := store.GetAgentByID(a.ID)
+	if got.Status != model.AgentOnline {
+		t.Fatalf("节点状态应为 online，实际 %s", got.Status)
+	}
+
+	// 冷却期内不重复告警
+	checker.batchCheck(context.Background())
+	alerts, _ = store.GetAlerts(10, 0)
+	if len(alerts) != 1 {
+		t.Fatalf("冷却期内不应重复告警，实际 %d 条", len(alerts))
+	}
+}
+
+func TestBatchCheckAgentOverrideDisablesRule(t *testing.T) {
+	setupConfig(t)
+
+	srv := newAgentServer(t, `{
+		"hostname": "batch-01",
+		"raw_data": {"cpu_used": "99%", "mem_used": "10%"},
+		"analysis": {"alert": false, "level": "OK"}
+	}`)
+
+	store := memory.New()
+	a := &model.Agent{Name: "batch-01", IP: "10.0.0.2", URL: srv.URL, Enabled: true, Tags: "batch"}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+	// 批处理节点 CPU 长期满载属正常，按标签禁用 CPU 规则
+	if err := store.CreateAlertRule(&model.AlertRule{
+		Metric: "cpu_used", Operator: model.OpGreater, Value: 85,
+		Severity: model.LevelCritical, Tag: "batch", Enabled: false,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	NewChecker(store, agent.NewClient()).batchCheck(context.Background())
+
+	inspections, _ := store.GetInspectionsByAgentID(a.ID, 10)
+	if len(inspections) != 1 {
+		t.Fatalf("标签覆盖规则应屏蔽 CPU 告警: %+v", inspections)
+	}
+	if alerts, _ := store.GetAlerts(10, 0); len(alerts) != 0 {
+		t.Fatalf("不应产生告警，实际 %d 条", len(alerts))
+	}
+}
+
+func TestBatchCheckUnreachableAgent(t *testing.T) {
+	setupConfig(t)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	store := memory.New()
+	a := &model.Agent{Name: "down-01", IP: "10.0.0.3", URL: url, Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := NewChecker(store, agent.NewClient())
+	checker.batchCheck(context.Background())
+
+	inspections, _ := store.GetInspectionsByAgentID(a.ID, 10)
+	if len(inspections) != 1 || inspections[0].Level != model.LevelCritical {
+		t.Fatalf("不可达节点应记录 CRITICAL: %+v", inspections)
+	}
+	if alerts, _ := store.GetAlerts(10, 0); len(alerts) != 1 {
+		t.Fatalf("不可达节点应产生告警，实际 %d 条", len(alerts))
+	}
+	if got, _ := store.GetAgentByID(a.ID); got.Status != model.AgentOffline {
+		t.Fatalf("不可达节点应标记为 offline，实际 %s", got.Status)
+	}
+
+	// 连续失败达到阈值后熔断，冷却期内不再拉取
+	checker.batchCheck(context.Background())
+	if states := checker.Breakers(); len(states) != 1 || states[0].Status != BreakerOpen {
+		t.Fatalf("连续失败后应熔断: %+v", states)
+	}
+	checker.batchCheck(context.Background())
+	if inspections, _ = store.GetInspectionsByAgentID(a.ID, 10); len(inspections) != 2 {
+		t.Fatalf("熔断期间不应拉取，实际 %d 条记录", len(inspections))
+	}
+}
+
+func TestBatchCheckRateLimited(t *testing.T) {
+	setupConfig(t)
+
+	var requests, limit int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= atomic.LoadInt32(&limit) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hostname": "web-01", "raw_data": {"cpu_used": "10%"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	store := memory.New()
+	a := &model.Agent{Name: "web-01", IP: "10.0.0.1", URL: srv.URL, Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker(store, agent.NewClient())
+
+	// 一直被限流：跳过本轮，不记录不可达、不计入熔断
+	atomic.StoreInt32(&limit, 100)
+	for i := 0; i < 3; i++ {
+		checker.batchCheck(context.Background())
+	}
+	if inspections, _ := store.GetInspectionsByAgentID(a.ID, 10); len(inspections) != 0 {
+		t.Fatalf("限流时不应记录巡检结果: %+v", inspections)
+	}
+	if states := checker.Breakers(); len(states) != 0 {
+		t.Fatalf("限流不应计入熔断: %+v", states)
+	}
+	if got, _ := store.GetAgentByID(a.ID); got.Status == model.AgentOffline {
+		t.Fatal("限流的节点不应标记为 offline")
+	}
+	if n := atomic.LoadInt32(&requests); n != 6 {
+		t.Fatalf("每轮应等待后只重新拉取一次，实际请求 %d 次", n)
+	}
+
+	// 等待后不再限流：正常记录
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&limit, 1)
+	checker.batchCheck(context.Background())
+	if inspections, _ := store.GetInspectionsByAgentID(a.ID, 10); len(inspections) != 1 {
+		t.Fatalf("等待后应拉取成功，实际 %d 条记录", len(inspections))
+	}
+}
+
+func TestBatchCheckSkipsCachedResult(t *testing.T) {
+	setupConfig(t)
+
+	// Agent 缓存期内返回同一份结果，采集时间不变
+	srv := newAgentServer(t, `{
+		"protocol_version": 2,
+		"hostname": "cache-01",
+		"collected_at": "2024-01-01T00:00:00Z",
+		"metrics": {"cpu_used": 10, "memory_used": 10},
+		"raw_data": {},
+		"analysis": {"alert": false, "level": "OK"}
+	}`)
+
+	store := memory.New()
+	a := &model.Agent{Name: "cache-01", IP: "10.0.0.5", URL: srv.URL, Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := NewChecker(store, agent.NewClient())
+	checker.batchCheck(context.Background())
+	checker.batchCheck(context.Background())
+
+	if inspections, _ := store.GetInspectionsByAgentID(a.ID, 10); len(inspections) != 1 {
+		t.Fatalf("重复的缓存结果不应再次记录，实际 %d 条", len(inspections))
+	}
+}
+
+func TestBatchCheckCancel(t *testing.T) {
+	setupConfig(t)
+
+	// 模拟卡住的 Agent，直到请求被取消
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	store := memory.New()
+	a := &model.Agent{Name: "hang-01", IP: "10.0.0.4", URL: srv.URL, Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	NewChecker(store, agent.NewClient()).batchCheck(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("取消后应立即返回，实际耗时 %v", elapsed)
+	}
+	if inspections, _ := store.GetInspectionsByAgentID(a.ID, 10); len(inspections) != 0 {
+		t.Fatalf("取消的巡检不应记录结果: %+v", inspections)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	b := NewBreaker(2, time.Minute, 3*time.Minute)
+	now := time.Now()
+
+	b.Failure(1, now)
+	if !b.Failure(1, now) || b.Allow(1, now) {
+		t.Fatal("连续失败 2 次应熔断")
+	}
+
+	// 冷却结束放行一次探测，探测期间不重复放行
+	now = now.Add(time.Minute)
+	if !b.Allow(1, now) || b.Allow(1, now) {
+		t.Fatal("冷却结束后应只放行一次探测")
+	}
+
+	// 探测失败，冷却时间翻倍
+	b.Failure(1, now)
+	if b.Allow(1, now.Add(time.Minute)) || !b.Allow(1, now.Add(2*time.Minute)) {
+		t.Fatal("探测失败后冷却时间应翻倍")
+	}
+
+	if !b.Success(1) || b.Status(1) != BreakerClosed {
+		t.Fatal("探测成功应恢复")
+	}
+}
+
+func TestFlapDetector(t *testing.T) {
+	d := NewFlapDetector(10, 50, 25)
+	now := time.Now()
+
+	var started, stopped int
+	for i := 0; i < 10; i++ {
+		switch d.Record(1, conditionLevel, i%2 == 0, now) {
+		case FlapStarted:
+			started++
+		case FlapStopped:
+			stopped++
+		}
+	}
+	if started != 1 || !d.IsFlapping(1, conditionLevel) {
+		t.Fatalf("交替变化的状态应进入抖动: started=%d", started)
+	}
+	if len(d.Flapping()) != 1 {
+		t.Fatalf("抖动列表应包含 1 个条件")
+	}
+
+	for i := 0; i < 10; i++ {
+		if d.Record(1, conditionLevel, false, now) == FlapStopped {
+			stopped++
+		}
+	}
+	if stopped != 1 || d.IsFlapping(1, conditionLevel) {
+		t.Fatalf("状态稳定后应退出抖动: stopped=%d", stopped)
+	}
+}
+
+func TestFlapDetectorHysteresis(t *testing.T) {
+	d := NewFlapDetector(10, 50, 25)
+	now := time.Now()
+
+	// 分数介于低阈值与高阈值之间时不进入抖动
+	for i := 0; i < 30; i++ {
+		if d.Record(1, conditionLevel, i/3%2 == 0, now) != FlapNone {
+			t.Fatalf("第 %d 次: 分数未超过高阈值不应进入抖动", i)
+		}
+	}
+
+	// 进入抖动后分数介于两个阈值之间时保持抖动，低于低阈值才退出
+	seq := []bool{true, false, true, false, true, false, true, false, true, false,
+		true, true, false, false, false, true, true, true, false, false, false, false}
+	transitions := make(map[int]FlapTransition)
+	for i, problem := range seq {
+		if tr := d.Record(2, conditionLevel, problem, now); tr != FlapNone {
+			transitions[i] = tr
+		}
+		if i == 20 && !d.IsFlapping(2, conditionLevel) {
+			t.Fatalf("分数高于低阈值时应保持抖动: %+v", d.Flapping())
+		}
+	}
+	if len(transitions) != 2 || transitions[4] != FlapStarted || transitions[21] != FlapStopped {
+		t.Fatalf("transitions = %v", transitions)
+	}
+}
+
+func TestFlappingSingleNotice(t *testing.T) {
+	setupConfig(t)
+
+	store := memory.New()
+	a := &model.Agent{Name: "flap-01", IP: "10.0.0.7", Enabled: true}
+	if err := store.CreateAgent(a); err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker(store, agent.NewClient())
+
+	flapping := func() []model.Alert {
+		alerts, _ := store.GetAlerts(100, 0)
+		var notices []model.Alert
+		for _, al := range alerts {
+			if strings.HasSuffix(al.Title, "FLAPPING") {
+				notices = append(notices, al)
+			}
+		}
+		return notices
+	}
+
+	// 同一次翻转同时触发级别抖动与 CPU 规则抖动，只发送一条抖动通知
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 20; i++ {
+		ins := &model.Inspection{AgentID: a.ID, Level: model.LevelOK, CPUUsed: 10, CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		if i%2 == 0 {
+			ins.Level, ins.CPUUsed = model.LevelCritical, 95
+		}
+		if err := checker.Ingest(a, ins); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(checker.Flapping()) != 2 {
+		t.Fatalf("级别与 CPU 规则都应处于抖动: %+v", checker.Flapping())
+	}
+	notices := flapping()
+	if len(notices) != 1 || notices[0].Status == model.AlertResolved {
+		t.Fatalf("一个节点应只有一条抖动通知: %+v", notices)
+	}
+
+	for i := 20; i < 40; i++ {
+		ins := &model.Inspection{AgentID: a.ID, Level: model.LevelOK, CPUUsed: 10, CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		if err := checker.Ingest(a, ins); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notices = flapping()
+	if len(checker.Flapping()) != 0 || len(notices) != 1 || notices[0].Status != model.AlertResolved {
+		t.Fatalf("全部条件退出抖动后应关闭通知: %+v", notices)
+	}
+}
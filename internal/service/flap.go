@@ -0,0 +1,188 @@
+// file: internal/service/flap.go
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FlapTransition 抖动状态变化
+type FlapTransition int
+
+const (
+	FlapNone    FlapTransition = iota // 状态未变化
+	FlapStarted                       // 开始抖动
+	FlapStopped                       // 抖动结束
+)
+
+// FlapState 抖动状态快照
+type FlapState struct {
+	AgentID   uint64    `json:"agent_id"`
+	Condition string    `json:"condition"`
+	Score     float64   `json:"score"`
+	Flapping  bool      `json:"flapping"`
+	Since     time.Time `json:"since"`
+}
+
+// flapEntry 单个条件的滑动窗口
+type flapEntry struct {
+	history  []bool
+	score    float64
+	flapping bool
+	since    time.Time
+}
+
+// FlapDetector 抖动检测器
+// 在滑动窗口内统计状态翻转次数（越新的翻转权重越高），
+// 分数超过高阈值进入抖动，低于低阈值退出抖动
+type FlapDetector struct {
+	mu      sync.Mutex
+	window  int
+	high    float64
+	low     float64
+	entries map[flapKey]*flapEntry
+	notices map[uint64]uint64 // 节点的抖动通知，同一节点多个条件同时抖动时共用一条
+}
+
+type flapKey struct {
+	agentID   uint64
+	condition string
+}
+
+// NewFlapDetector 创建抖动检测器
+func NewFlapDetector(window int, high, low float64) *FlapDetector {
+	if window < 3 {
+		window = 3
+	}
+	return &FlapDetector{
+		window:  window,
+		high:    high,
+		low:     low,
+		entries: make(map[flapKey]*flapEntry),
+		notices: make(map[uint64]uint64),
+	}
+}
+
+// Record 记录一次条件状态，problem 表示条件处于异常
+func (d *FlapDetector) Record(agentID uint64, condition string, problem bool, now time.Time) FlapTransition {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := flapKey{agentID, condition}
+	e, ok := d.entries[key]
+	if !ok {
+		e = &flapEntry{}
+		d.entries[key] = e
+	}
+
+	e.history = append(e.history, problem)
+	if len(e.history) > d.window {
+		e.history = e.history[len(e.history)-d.window:]
+	}
+	e.score = flapScore(e.history)
+
+	switch {
+	case !e.flapping && len(e.history) >= d.window/2 && e.score >= d.high:
+		e.flapping = true
+		e.since = now
+		return FlapStarted
+	case e.flapping && e.score < d.low:
+		e.flapping = false
+		e.since = now
+		return FlapStopped
+	}
+
+	// 长期稳定在正常状态的条件无需保留
+	if !e.flapping && !problem && e.score == 0 && len(e.history) == d.window {
+		delete(d.entries, key)
+	}
+	return FlapNone
+}
+
+// IsFlapping 条件是否处于抖动状态
+func (d *FlapDetector) IsFlapping(agentID uint64, condition string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[flapKey{agentID, condition}]
+	return ok && e.flapping
+}
+
+// AgentFlapping 节点是否有条件处于抖动状态
+func (d *FlapDetector) AgentFlapping(agentID uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, e := range d.entries {
+		if key.agentID == agentID && e.flapping {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAlertID 记录节点抖动通知对应的告警，0 表示通知已关闭
+func (d *FlapDetector) SetAlertID(agentID uint64, alertID uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if alertID == 0 {
+		delete(d.notices, agentID)
+		return
+	}
+	d.notices[agentID] = alertID
+}
+
+// AlertID 获取节点抖动通知对应的告警
+func (d *FlapDetector) AlertID(agentID uint64) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.notices[agentID]
+}
+
+// Flapping 返回所有处于抖动状态的条件
+func (d *FlapDetector) Flapping() []FlapState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states := make([]FlapState, 0)
+	for key, e := range d.entries {
+		if !e.flapping {
+			continue
+		}
+		states = append(states, FlapState{
+			AgentID:   key.agentID,
+			Condition: key.condition,
+			Score:     e.score,
+			Flapping:  true,
+			Since:     e.since,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].AgentID != states[j].AgentID {
+			return states[i].AgentID < states[j].AgentID
+		}
+		return states[i].Condition < states[j].Condition
+	})
+	return states
+}
+
+// flapScore 计算抖动分数（0-100），权重从最旧的 0.8 线性增加到最新的 1.2
+func flapScore(history []bool) float64 {
+	n := len(history)
+	if n < 2 {
+		return 0
+	}
+
+	var changed, total float64
+	for i := 1; i < n; i++ {
+		weight := 0.8 + 0.4*float64(i-1)/float64(max(n-2, 1))
+		total += weight
+		if history[i] != history[i-1] {
+			changed += weight
+		}
+	}
+	return changed / total * 100
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/repository"
+	"github.com/gorilla/websocket"
+)
+
+// ErrAgentNotConnected 节点未建立长连接
+var ErrAgentNotConnected = errors.New("节点未建立长连接")
+
+// StreamState 节点长连接状态
+type StreamState struct {
+	Connected      bool       `json:"connected"`
+	Remote         string     `json:"remote"`
+	ConnectedAt    time.Time  `json:"connected_at"`
+	LastSeen       time.Time  `json:"last_seen"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+}
+
+// StreamHub Master 侧长连接管理：接收心跳与巡检结果，向节点下发命令
+type StreamHub struct {
+	repo   repository.AgentStore
+	ingest func(*model.Agent, *model.Inspection) error
+
+	mu     sync.RWMutex
+	conns  map[uint64]*streamConn
+	states map[uint64]StreamState
+	seq    uint64
+}
+
+// streamConn 单个节点的长连接
+type streamConn struct {
+	ws   *websocket.Conn
+	send chan protocol.Message
+	done chan struct{}
+	once sync.Once
+}
+
+// close 关闭连接，可重复调用
+func (c *streamConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+// NewStreamHub 创建长连接管理器，ingest 处理节点上报的巡检结果
+func NewStreamHub(repo repository.AgentStore, ingest func(*model.Agent, *model.Inspection) error) *StreamHub {
+	return &StreamHub{
+		repo:   repo,
+		ingest: ingest,
+		conns:  make(map[uint64]*streamConn),
+		states: make(map[uint64]StreamState),
+	}
+}
+
+// Serve 处理节点长连接直到断开，同一节点的新连接会替换旧连接
+func (h *StreamHub) Serve(node *model.Agent, ws *websocket.Conn, remote string) {
+	conn := &streamConn{
+		ws:   ws,
+		send: make(chan protocol.Message, 16),
+		done: make(chan struct{}),
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	if old, ok := h.conns[node.ID]; ok {
+		old.close()
+	}
+	h.conns[node.ID] = conn
+	h.states[node.ID] = StreamState{Connected: true, Remote: remote, ConnectedAt: now, LastSeen: now}
+	h.mu.Unlock()
+	log.Printf("【长连接】节点 %s 已连接: %s", node.Name, remote)
+
+	go h.writeLoop(conn)
+	err := h.readLoop(node, conn)
+
+	conn.close()
+	h.mu.Lock()
+	if h.conns[node.ID] == conn {
+		delete(h.conns, node.ID)
+		state := h.states[node.ID]
+		state.Connected = false
+		disconnected := time.Now()
+		state.DisconnectedAt = &disconnected
+		h.states[node.ID] = state
+	}
+	h.mu.Unlock()
+	log.Printf("【长连接】节点 %s 已断开: %v", node.Name, err)
+}
+
+// readLoop 读取节点消息
+func (h *StreamHub) readLoop(node *model.Agent, conn *streamConn) error {
+	conn.ws.SetReadLimit(protocol.StreamMaxMessage)
+	conn.ws.SetPongHandler(func(string) error {
+		conn.ws.SetReadDeadline(time.Now().Add(protocol.StreamPongWait))
+		return nil
+	})
+	for {
+		conn.ws.SetReadDeadline(time.Now().Add(protocol.StreamPongWait))
+		var m protocol.Message
+		if err := conn.ws.ReadJSON(&m); err != nil {
+			return err
+		}
+		h.touch(node.ID)
+
+		switch m.Type {
+		case protocol.MsgHeartbeat:
+			h.handleHeartbeat(node, m.Payload)
+		case protocol.MsgReport:
+			code, msg := h.handleReport(node.ID, m.Payload)
+			h.enqueue(conn, protocol.Message{Type: protocol.MsgAck, ID: m.ID, Code: code, Error: msg})
+		case protocol.MsgResult:
+			if m.Error != "" {
+				log.Printf("【长连接】节点 %s 执行命令 %s 失败: %s", node.Name, m.Command, m.Error)
+			} else {
+				log.Printf("【长连接】节点 %s 已执行命令 %s", node.Name, m.Command)
+			}
+		}
+	}
+}
+
+// handleHeartbeat 心跳携带 Agent 信息，协议版本或能力变化时更新节点记录
+func (h *StreamHub) handleHeartbeat(node *model.Agent, payload []byte) {
+	var info protocol.Info
+	if len(payload) == 0 || json.Unmarshal(payload, &info) != nil || info.ProtocolVersion == 0 {
+		return
+	}
+
+	if info.ConfigVersion != node.ConfigVersion {
+		if err := h.repo.UpdateAgentConfigVersion(node.ID, info.ConfigVersion); err != nil {
+			log.Printf("【长连接】更新节点 %s 配置版本失败: %v", node.Name, err)
+		} else {
+			node.ConfigVersion = info.ConfigVersion
+		}
+	}
+
+	capabilities := strings.Join(info.Capabilities, ",")
+	if info.ProtocolVersion == node.ProtocolVersion && info.AgentVersion == node.Version && capabilities == node.Capabilities {
+		return
+	}
+	if err := h.repo.UpdateAgentProtocol(node.ID, info.ProtocolVersion, info.AgentVersion, capabilities); err != nil {
+		log.Printf("【长连接】更新节点 %s 信息失败: %v", node.Name, err)
+		return
+	}
+	node.ProtocolVersion, node.Version, node.Capabilities = info.ProtocolVersion, info.AgentVersion, capabilities
+}
+
+// handleReport 处理巡检结果，返回确认状态码与错误信息
+func (h *StreamHub) handleReport(agentID uint64, payload []byte) (int, string) {
+	// 每次重新加载节点，使标签与规则变更立即生效
+	node, err := h.repo.GetAgentByID(agentID)
+	if err != nil || !node.Enabled {
+		return http.StatusForbidden, "节点不存在或已禁用"
+	}
+
+	if _, err := protocol.DecodeReport(payload); err != nil {
+		return http.StatusBadRequest, "巡检数据格式错误: " + err.Error()
+	}
+
+	inspection := agent.ParseInspection(*node, payload)
+	if now := time.Now(); inspection.CreatedAt.After(now) {
+		inspection.CreatedAt = now
+	}
+	if err := h.ingest(node, inspection); err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	return http.StatusOK, ""
+}
+
+// writeLoop 发送队列中的消息，并定时 ping 保活
+func (h *StreamHub) writeLoop(conn *streamConn) {
+	ticker := time.NewTicker(protocol.HeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.done:
+			return
+		case m := <-conn.send:
+			m.Time = time.Now()
+			conn.ws.SetWriteDeadline(time.Now().Add(protocol.StreamWriteWait))
+			if err := conn.ws.WriteJSON(m); err != nil {
+				conn.close()
+				return
+			}
+		case <-ticker.C:
+			if err := conn.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(protocol.StreamWriteWait)); err != nil {
+				conn.close()
+				return
+			}
+		}
+	}
+}
+
+// enqueue 将消息放入发送队列，队列已满时断开连接由节点重连
+func (h *StreamHub) enqueue(conn *streamConn, m protocol.Message) bool {
+	select {
+	case conn.send <- m:
+		return true
+	case <-conn.done:
+		return false
+	default:
+		conn.close()
+		return false
+	}
+}
+
+// touch 更新节点最后活跃时间
+func (h *StreamHub) touch(agentID uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if state, ok := h.states[agentID]; ok {
+		state.LastSeen = time.Now()
+		h.states[agentID] = state
+	}
+}
+
+// Send 向节点下发命令，返回命令ID
+func (h *StreamHub) Send(agentID uint64, command string) (string, error) {
+	h.mu.Lock()
+	conn, ok := h.conns[agentID]
+	h.seq++
+	id := "cmd-" + strconv.FormatUint(h.seq, 10)
+	h.mu.Unlock()
+
+	if !ok {
+		return "", ErrAgentNotConnected
+	}
+	if !h.enqueue(conn, protocol.Message{Type: protocol.MsgCommand, ID: id, Command: command}) {
+		return "", ErrAgentNotConnected
+	}
+	return id, nil
+}
+
+// Connected 节点是否已建立长连接
+func (h *StreamHub) Connected(agentID uint64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, ok := h.conns[agentID]
+	return ok
+}
+
+// Broadcast 向所有已连接的节点下发命令，返回下发成功的节点数
+func (h *StreamHub) Broadcast(command string) int {
+	h.mu.RLock()
+	ids := make([]uint64, 0, len(h.conns))
+	for id := range h.conns {
+		ids = append(ids, id)
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, id := range ids {
+		if _, err := h.Send(id, command); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// States 返回所有节点的长连接状态
+func (h *StreamHub) States() map[uint64]StreamState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	states := make(map[uint64]StreamState, len(h.states))
+	for id, s := range h.states {
+		states[id] = s
+	}
+	return states
+}
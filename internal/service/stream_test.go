@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-inspector/internal/agent"
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+	"cyber-inspector/internal/repository/memory"
+	"github.com/gorilla/websocket"
+)
+
+func TestStreamCommandAndReport(t *testing.T) {
+	store := memory.New()
+	node := &model.Agent{Name: "nat-01", IP: "10.0.0.9", URL: "http://10.0.0.9:8083", Enabled: true}
+	if err := store.CreateAgent(node); err != nil {
+		t.Fatal(err)
+	}
+
+	ingested := make(chan *model.Inspection, 1)
+	hub := NewStreamHub(store, func(a *model.Agent, ins *model.Inspection) error {
+		ingested <- ins
+		return nil
+	})
+
+	upgrader := websocket.Upgrader{}
+	master := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(node, ws, r.RemoteAddr)
+	}))
+	defer master.Close()
+
+	client := agent.NewStreamClient(master.URL, node.ID, "token")
+	client.Handle(protocol.CommandInspect, func() error {
+		data, _ := json.Marshal(protocol.Report{
+			Hostname: "nat-01",
+			RawData:  json.RawMessage(`{"cpu_used":"12%"}`),
+			Analysis: json.RawMessage(`{"alert":false,"level":"OK"}`),
+		})
+		return client.SendReport(data)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go client.Run(ctx)
+
+	waitFor(t, func() bool { return hub.Connected(node.ID) && client.Connected() })
+
+	if _, err := hub.Send(node.ID, protocol.CommandInspect); err != nil {
+		t.Fatalf("下发命令失败: %v", err)
+	}
+	select {
+	case ins := <-ingested:
+		if ins.Hostname != "nat-01" || ins.CPUUsed != 12 {
+			t.Fatalf("巡检结果解析错误: %+v", ins)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到长连接上报的巡检结果")
+	}
+
+	cancel()
+	waitFor(t, func() bool { return !hub.Connected(node.ID) })
+	if state := hub.States()[node.ID]; state.Connected || state.DisconnectedAt == nil {
+		t.Fatalf("断开后状态应为未连接: %+v", state)
+	}
+	if _, err := hub.Send(node.ID, protocol.CommandInspect); err != ErrAgentNotConnected {
+		t.Fatalf("未连接时下发命令应失败，实际 %v", err)
+	}
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,142 @@
+package vuln
+
+import (
+	"sort"
+	"strings"
+
+	"cyber-inspector/internal/model"
+)
+
+// ecosystemDistros OSV 生态名称（去掉空格后小写）与 /etc/os-release ID 不同的发行版
+var ecosystemDistros = map[string]string{
+	"rockylinux": "rocky",
+	"redhat":     "rhel",
+}
+
+// Match 将巡检记录中已安装的软件包与漏洞库匹配，结果写入 Vulnerabilities；未采集软件包时不处理
+func (d *Database) Match(ins *model.Inspection) {
+	ins.Vulnerabilities = nil
+	if ins.PackageManager == "" {
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	seen := make(map[string]bool)
+	for _, p := range ins.InstalledPackages {
+		// Debian 系的漏洞按源码包记录
+		names := []string{p.Name}
+		if p.Source != "" {
+			names = append(names, p.Source)
+		}
+		for _, name := range names {
+			for _, e := range d.index[strings.ToLower(name)] {
+				if !EcosystemMatches(e.affected.Package.Ecosystem, ins.Distro, ins.DistroRelease) {
+					continue
+				}
+				fixed, ok := Affects(ins.PackageManager, e.affected, p.Version)
+				key := e.vuln.ID + "/" + p.Name + "/" + p.Arch
+				if !ok || seen[key] {
+					continue
+				}
+				seen[key] = true
+				ins.Vulnerabilities = append(ins.Vulnerabilities, model.InspectionVuln{
+					AgentID:  ins.AgentID,
+					VulnID:   e.vuln.ID,
+					Aliases:  e.vuln.Aliases,
+					Package:  p.Name,
+					Version:  p.Version,
+					Fixed:    fixed,
+					Severity: model.InspectionLevel(e.vuln.severity(e.affected)),
+					Summary:  truncate(e.vuln.summary(), 255),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(ins.Vulnerabilities, func(i, j int) bool {
+		a, b := ins.Vulnerabilities[i], ins.Vulnerabilities[j]
+		if a.Severity != b.Severity {
+			return a.Severity == model.LevelCritical
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.VulnID < b.VulnID
+	})
+}
+
+// EcosystemMatches OSV 生态是否对应节点的发行版，如 Debian:12 对应 debian 12，Rocky Linux:9 对应 rocky 9.3；
+// 生态不带版本号时匹配该发行版的全部版本
+func EcosystemMatches(ecosystem, distro, release string) bool {
+	parts := strings.Split(ecosystem, ":")
+	name := strings.ToLower(strings.ReplaceAll(parts[0], " ", ""))
+	if d, ok := ecosystemDistros[name]; ok {
+		name = d
+	}
+	if name != strings.ToLower(distro) {
+		return false
+	}
+	if len(parts) == 1 || release == "" {
+		return true
+	}
+	for _, p := range parts[1:] {
+		p = strings.TrimPrefix(strings.ToLower(p), "v")
+		if p != "" && (p == release || strings.HasPrefix(release, p+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Affects 判断版本是否受影响，受影响时返回修复版本（没有修复版本时为空）
+func Affects(manager string, a *Affected, version string) (string, bool) {
+	affected := false
+	for _, v := range a.Versions {
+		if CompareVersions(manager, version, v) == 0 {
+			affected = true
+			break
+		}
+	}
+
+	fixed := ""
+	for _, r := range a.Ranges {
+		if r.Type != "ECOSYSTEM" {
+			continue
+		}
+		inRange, rangeFixed := false, ""
+		for _, e := range sortedEvents(manager, r.Events) {
+			switch {
+			case e.Introduced != "":
+				if e.Introduced == "0" || CompareVersions(manager, version, e.Introduced) >= 0 {
+					inRange, rangeFixed = true, ""
+				}
+			case e.Fixed != "":
+				if CompareVersions(manager, version, e.Fixed) >= 0 {
+					inRange = false
+				} else if inRange && rangeFixed == "" {
+					rangeFixed = e.Fixed
+				}
+			case e.LastAffected != "":
+				if CompareVersions(manager, version, e.LastAffected) > 0 {
+					inRange = false
+				}
+			}
+		}
+		if inRange {
+			affected = true
+			if fixed == "" {
+				fixed = rangeFixed
+			}
+		}
+	}
+	return fixed, affected
+}
+
+// truncate 按字符截断
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
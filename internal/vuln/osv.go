@@ -0,0 +1,265 @@
+// Package vuln 加载本地 OSV 格式的漏洞库，将节点上报的已安装软件包与漏洞库匹配，不访问网络
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFeedEntry 单个漏洞文件的最大字节数
+const maxFeedEntry = 16 << 20
+
+// OSV 漏洞记录，只解析匹配需要的字段，格式见 https://ossf.github.io/osv-schema/
+type OSV struct {
+	ID               string          `json:"id"`
+	Aliases          []string        `json:"aliases"`
+	Summary          string          `json:"summary"`
+	Details          string          `json:"details"`
+	Withdrawn        string          `json:"withdrawn"`
+	Severity         []osvSeverity   `json:"severity"`
+	Affected         []Affected      `json:"affected"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+// osvSeverity 严重程度，type 为 CVSS_V3 时 score 为向量，发行版自定义类型（如 Ubuntu）时为等级名称
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// Affected 受影响的软件包与版本
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"` // 如 Debian:12、Rocky Linux:9
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges            []Range         `json:"ranges"`
+	Versions          []string        `json:"versions"`
+	EcosystemSpecific json.RawMessage `json:"ecosystem_specific"`
+}
+
+// Range 受影响的版本区间
+type Range struct {
+	Type   string  `json:"type"` // 只处理 ECOSYSTEM
+	Events []Event `json:"events"`
+}
+
+// Event 版本区间事件，每个事件只有一个字段有值
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// Status 漏洞库状态
+type Status struct {
+	Source          string    `json:"source"`
+	Vulnerabilities int       `json:"vulnerabilities"`
+	Packages        int       `json:"packages"` // 涉及的软件包数
+	LoadedAt        time.Time `json:"loaded_at"`
+}
+
+// entry 按软件包索引的漏洞条目
+type entry struct {
+	vuln     *OSV
+	affected *Affected
+}
+
+// Database 内存中的漏洞库，按软件包名索引，可在运行中重新加载
+type Database struct {
+	mu     sync.RWMutex
+	index  map[string][]entry
+	status Status
+}
+
+// NewDatabase 创建空的漏洞库
+func NewDatabase() *Database {
+	return &Database{index: make(map[string][]entry)}
+}
+
+// Status 返回漏洞库状态
+func (d *Database) Status() Status {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+// Load 从 path 加载漏洞库并替换当前内容。path 可以是单个 JSON 文件（单条记录或记录数组）、
+// 包含 JSON 文件的目录，或 OSV 官方导出的 zip 包（如 Debian/all.zip）
+func (d *Database) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var vulns []*OSV
+	switch {
+	case info.IsDir():
+		err = filepath.WalkDir(path, func(p string, e fs.DirEntry, err error) error {
+			if err != nil || e.IsDir() {
+				return err
+			}
+			items, err := loadFile(p)
+			vulns = append(vulns, items...)
+			return err
+		})
+	default:
+		vulns, err = loadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string][]entry)
+	count := 0
+	for _, v := range vulns {
+		if v.Withdrawn != "" || len(v.Affected) == 0 {
+			continue
+		}
+		count++
+		for i := range v.Affected {
+			a := &v.Affected[i]
+			name := strings.ToLower(a.Package.Name)
+			index[name] = append(index[name], entry{vuln: v, affected: a})
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.index = index
+	d.status = Status{Source: path, Vulnerabilities: count, Packages: len(index), LoadedAt: time.Now()}
+	return nil
+}
+
+// loadFile 读取 .json 或 .zip 文件，其它扩展名忽略
+func loadFile(path string) ([]*OSV, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		vulns, err := ParseOSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return vulns, nil
+	case ".zip":
+		return loadZip(path)
+	}
+	return nil, nil
+}
+
+// loadZip 读取 zip 包中的全部 JSON 文件
+func loadZip(path string) ([]*OSV, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var vulns []*OSV
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(f.Name), ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxFeedEntry))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		items, err := ParseOSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", path, f.Name, err)
+		}
+		vulns = append(vulns, items...)
+	}
+	return vulns, nil
+}
+
+// ParseOSV 解析单条 OSV 记录或记录数组
+func ParseOSV(data []byte) ([]*OSV, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var vulns []*OSV
+		if err := json.Unmarshal(data, &vulns); err != nil {
+			return nil, err
+		}
+		return vulns, nil
+	}
+	var v OSV
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return []*OSV{&v}, nil
+}
+
+// severity 从发行版的严重程度或紧急程度推断告警级别：critical、high、important 为 CRITICAL，其余为 WARNING
+func (v *OSV) severity(a *Affected) string {
+	levels := []string{}
+	for _, s := range v.Severity {
+		if !strings.HasPrefix(s.Type, "CVSS") {
+			levels = append(levels, s.Score)
+		}
+	}
+	for _, raw := range []json.RawMessage{a.EcosystemSpecific, v.DatabaseSpecific} {
+		var specific struct {
+			Severity string `json:"severity"`
+			Urgency  string `json:"urgency"`
+		}
+		if len(raw) > 0 && json.Unmarshal(raw, &specific) == nil {
+			levels = append(levels, specific.Severity, specific.Urgency)
+		}
+	}
+	for _, l := range levels {
+		switch strings.ToLower(l) {
+		case "critical", "high", "important":
+			return "CRITICAL"
+		}
+	}
+	return "WARNING"
+}
+
+// summary 漏洞摘要，没有 summary 时取 details 的第一行
+func (v *OSV) summary() string {
+	if v.Summary != "" {
+		return v.Summary
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(v.Details), "\n")
+	return line
+}
+
+// sortedEvents 按版本排序区间事件，introduced 为 0 的事件排在最前
+func sortedEvents(manager string, events []Event) []Event {
+	sorted := append([]Event(nil), events...)
+	version := func(e Event) string {
+		switch {
+		case e.Introduced != "":
+			return e.Introduced
+		case e.Fixed != "":
+			return e.Fixed
+		}
+		return e.LastAffected
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, vj := version(sorted[i]), version(sorted[j])
+		if vi == "0" || vj == "0" {
+			return vi == "0" && vj != "0"
+		}
+		return CompareVersions(manager, vi, vj) < 0
+	})
+	return sorted
+}
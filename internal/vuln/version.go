@@ -0,0 +1,222 @@
+package vuln
+
+import (
+	"strconv"
+	"strings"
+
+	"cyber-inspector/internal/protocol"
+)
+
+// CompareVersions 按包管理器的规则比较版本，a 较新返回 1，相同返回 0，较旧返回 -1
+func CompareVersions(manager, a, b string) int {
+	if manager == protocol.PackageRPM {
+		return compareRPM(a, b)
+	}
+	return compareDebian(a, b)
+}
+
+// splitEpoch 拆分 epoch:version，没有 epoch 时为 0
+func splitEpoch(v string) (int, string) {
+	if i := strings.IndexByte(v, ':'); i > 0 {
+		if epoch, err := strconv.Atoi(v[:i]); err == nil {
+			return epoch, v[i+1:]
+		}
+	}
+	return 0, v
+}
+
+// compareInt 比较两个整数
+func compareInt(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+// compareDebian 按 dpkg 的规则比较 [epoch:]upstream[-revision]
+func compareDebian(a, b string) int {
+	ea, va := splitEpoch(a)
+	eb, vb := splitEpoch(b)
+	if c := compareInt(ea, eb); c != 0 {
+		return c
+	}
+	ua, ra := splitRevision(va)
+	ub, rb := splitRevision(vb)
+	if c := debianCompareFragment(ua, ub); c != 0 {
+		return c
+	}
+	return debianCompareFragment(ra, rb)
+}
+
+// splitRevision 以最后一个 - 拆分 upstream 与 revision
+func splitRevision(v string) (string, string) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// debianOrder 非数字字符的排序权重：~ 最小，其次为字符串结尾，字母小于其它符号
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// debianCompareFragment 交替比较非数字段与数字段
+func debianCompareFragment(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if c := compareInt(debianOrder(a, i), debianOrder(b, j)); c != 0 {
+				return c
+			}
+			i, j = i+1, j+1
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		first := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if first == 0 {
+				first = compareInt(int(a[i]), int(b[j]))
+			}
+			i, j = i+1, j+1
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if first != 0 {
+			return first
+		}
+	}
+	return 0
+}
+
+// compareRPM 按 rpm 的规则比较 [epoch:]version[-release]
+func compareRPM(a, b string) int {
+	ea, va := splitEpoch(a)
+	eb, vb := splitEpoch(b)
+	if c := compareInt(ea, eb); c != 0 {
+		return c
+	}
+	ua, ra := splitRevision(va)
+	ub, rb := splitRevision(vb)
+	if c := rpmvercmp(ua, ub); c != 0 {
+		return c
+	}
+	// 一方没有 release 时只比较 version
+	if ra == "" || rb == "" {
+		return 0
+	}
+	return rpmvercmp(ra, rb)
+}
+
+// rpmvercmp rpm 的版本段比较：数字段大于字母段，~ 早于任何内容，^ 晚于结尾但早于其它内容
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isAlnum(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isAlnum(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		if (i < len(a) && a[i] == '~') || (j < len(b) && b[j] == '~') {
+			if i >= len(a) || a[i] != '~' {
+				return 1
+			}
+			if j >= len(b) || b[j] != '~' {
+				return -1
+			}
+			i, j = i+1, j+1
+			continue
+		}
+		if (i < len(a) && a[i] == '^') || (j < len(b) && b[j] == '^') {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if a[i] != '^' {
+				return 1
+			}
+			if b[j] != '^' {
+				return -1
+			}
+			i, j = i+1, j+1
+			continue
+		}
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		numeric := isDigit(a[i])
+		match := isAlpha
+		if numeric {
+			match = isDigit
+		}
+		si, sj := i, j
+		for i < len(a) && match(a[i]) {
+			i++
+		}
+		for j < len(b) && match(b[j]) {
+			j++
+		}
+		segA, segB := a[si:i], b[sj:j]
+		if segB == "" {
+			// 数字段比字母段新
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if c := compareInt(len(segA), len(segB)); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case i >= len(a) && j >= len(b):
+		return 0
+	case i < len(a):
+		return 1
+	}
+	return -1
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+func isAlnum(c byte) bool { return isDigit(c) || isAlpha(c) }
@@ -0,0 +1,129 @@
+package vuln
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cyber-inspector/internal/model"
+	"cyber-inspector/internal/protocol"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		manager, a, b string
+		want          int
+	}{
+		{protocol.PackageDpkg, "3.0.11-1~deb12u2", "3.0.11-1", -1},
+		{protocol.PackageDpkg, "1.0~rc1", "1.0", -1},
+		{protocol.PackageDpkg, "1:1.0", "2.0", 1},
+		{protocol.PackageDpkg, "2.36-9+deb12u3", "2.36-9+deb12u4", -1},
+		{protocol.PackageDpkg, "1.10", "1.9", 1},
+		{protocol.PackageDpkg, "1.0a", "1.0+", -1},
+		{protocol.PackageDpkg, "001.2", "1.2", 0},
+		{protocol.PackageRPM, "3.0.7-24.el9", "1:3.0.7-25.el9_3", -1},
+		{protocol.PackageRPM, "1.0~rc1", "1.0", -1},
+		{protocol.PackageRPM, "1.0^git1", "1.0", 1},
+		{protocol.PackageRPM, "1.0^git1", "1.0.1", -1},
+		{protocol.PackageRPM, "2.0.1", "2.0a", 1},
+		{protocol.PackageRPM, "5.14.0-362.el9", "5.14.0", 0},
+	}
+	for _, c := range cases {
+		if got := CompareVersions(c.manager, c.a, c.b); got != c.want {
+			t.Errorf("%s %s vs %s = %d, want %d", c.manager, c.a, c.b, got, c.want)
+		}
+		if got := CompareVersions(c.manager, c.b, c.a); got != -c.want {
+			t.Errorf("%s %s vs %s = %d, want %d", c.manager, c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestEcosystemMatches(t *testing.T) {
+	cases := []struct {
+		ecosystem, distro, release string
+		want                       bool
+	}{
+		{"Debian:12", "debian", "12", true},
+		{"Debian:11", "debian", "12", false},
+		{"Debian", "debian", "12", true},
+		{"Rocky Linux:9", "rocky", "9.3", true},
+		{"Rocky Linux:9", "rocky", "93", false},
+		{"Ubuntu:22.04:LTS", "ubuntu", "22.04", true},
+		{"Debian:12", "ubuntu", "22.04", false},
+	}
+	for _, c := range cases {
+		if got := EcosystemMatches(c.ecosystem, c.distro, c.release); got != c.want {
+			t.Errorf("EcosystemMatches(%q, %q, %q) = %v", c.ecosystem, c.distro, c.release, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	db := NewDatabase()
+	if err := db.Load("testdata/feed"); err != nil {
+		t.Fatal(err)
+	}
+	// 撤回的记录不计入
+	if s := db.Status(); s.Vulnerabilities != 3 || s.Packages != 2 {
+		t.Fatalf("status = %+v", s)
+	}
+
+	ins := &model.Inspection{AgentID: 1, PackageManager: protocol.PackageDpkg, Distro: "debian", DistroRelease: "12",
+		InstalledPackages: []model.InspectionPackage{
+			{Name: "bash", Version: "5.2.15-2+b2", Arch: "amd64"},
+			{Name: "libssl3", Version: "3.0.9-1", Arch: "amd64", Source: "openssl"},
+			{Name: "openssl", Version: "3.0.11-1~deb12u2", Arch: "amd64", Source: "openssl"},
+			{Name: "libc6", Version: "2.36-9+deb12u3", Arch: "amd64", Source: "glibc"},
+		}}
+	db.Match(ins)
+	if len(ins.Vulnerabilities) != 2 {
+		t.Fatalf("应匹配到 2 个漏洞: %+v", ins.Vulnerabilities)
+	}
+	ssl, libc := ins.Vulnerabilities[0], ins.Vulnerabilities[1]
+	if ssl.VulnID != "DSA-5532-1" || ssl.Package != "libssl3" || ssl.Fixed != "3.0.11-1~deb12u2" ||
+		ssl.Severity != model.LevelCritical || len(ssl.Aliases) != 1 {
+		t.Errorf("按源码包匹配错误: %+v", ssl)
+	}
+	if libc.VulnID != "DLA-0001-1" || libc.Severity != model.LevelWarning || libc.Summary != "glibc - security update" {
+		t.Errorf("libc6 = %+v", libc)
+	}
+
+	// 发行版版本不同时不匹配
+	ins.DistroRelease = "13"
+	db.Match(ins)
+	if len(ins.Vulnerabilities) != 0 {
+		t.Errorf("Debian 13 = %+v", ins.Vulnerabilities)
+	}
+
+	rocky := &model.Inspection{PackageManager: protocol.PackageRPM, Distro: "rocky", DistroRelease: "9.3",
+		InstalledPackages: []model.InspectionPackage{{Name: "openssl", Version: "1:3.0.7-24.el9", Arch: "x86_64"}}}
+	db.Match(rocky)
+	if len(rocky.Vulnerabilities) != 1 || rocky.Vulnerabilities[0].Severity != model.LevelCritical {
+		t.Errorf("rocky = %+v", rocky.Vulnerabilities)
+	}
+}
+
+func TestLoadZip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "all.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	data, _ := os.ReadFile("testdata/feed/DSA-5532-1.json")
+	w, _ := zw.Create("DSA-5532-1.json")
+	_, _ = w.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db := NewDatabase()
+	if err := db.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Status(); s.Vulnerabilities != 1 || s.Packages != 1 || s.Source != path {
+		t.Errorf("status = %+v", s)
+	}
+}